@@ -0,0 +1,92 @@
+package buildcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stackoverflow/novah-go/compiler"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+var BuildCmd = &cobra.Command{
+	Use:   "build [novah sources]",
+	Short: "compile a novah program to an executable",
+	Long: `compile novah sources to a go program and build it with the go tool.
+The program starts at the main function of the entry module: the entry of the project (novah.json)
+when no sources are given, or else the first source that declares main, starting with the first given file.`,
+	Run: runBuild,
+}
+
+var output string
+var verbose *bool
+var noColor bool
+
+func init() {
+	BuildCmd.Flags().StringVarP(&output, "output", "o", "", "path of the executable (defaults to the name of the entry module)")
+	verbose = BuildCmd.Flags().BoolP("verbose", "v", false, "print more information about the compilation")
+	BuildCmd.Flags().BoolVar(&noColor, "no-color", false, data.NO_COLOR_USAGE)
+}
+
+func runBuild(cmd *cobra.Command, args []string) {
+	sources, project, err := compiler.ProgramSources(args, ".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	opts := compiler.Options{Verbose: *verbose}
+	if project != nil {
+		opts.SourceRoots = project.SourceRoots()
+		opts.GoModule = project.GoModule
+		// given sources take precedence over the entry of the project
+		if len(args) == 0 {
+			opts.Entry = project.Entry
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "novah-build")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	os.Exit(build(tmp, sources, opts))
+}
+
+// Returns the exit code of the build
+func build(tmp string, sources []string, opts compiler.Options) int {
+	defer os.RemoveAll(tmp)
+
+	comp := compiler.NewCompiler(sources, opts)
+	problems := comp.RunProgram(tmp)
+	if len(problems) > 0 {
+		if err := data.WriteProblems(os.Stderr, problems, data.FORMAT_TEXT, data.CommandConsoleOptions(noColor, comp.SourceText)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if errs, _ := data.CountProblems(problems); errs > 0 {
+		return 1
+	}
+
+	bin := output
+	if bin == "" {
+		entry := comp.Entry()
+		bin = entry[strings.LastIndex(entry, ".")+1:]
+	}
+	bin, err := filepath.Abs(bin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *verbose {
+		fmt.Printf("building %s...\n", bin)
+	}
+	if err := compiler.GoTool(tmp, "build", "-o", bin, comp.EntryPackage()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,56 @@
+package checkcmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stackoverflow/novah-go/compiler"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+var CheckCmd = &cobra.Command{
+	Use:   "check [novah sources or directories]",
+	Short: "typecheck novah source files without generating code",
+	Long:  `parse and typecheck novah sources, reporting every error and warning without writing anything to disk`,
+	Run:   runCheck,
+}
+
+var verbose *bool
+var format string
+var noColor bool
+
+func init() {
+	verbose = CheckCmd.Flags().BoolP("verbose", "v", false, "print more information about the compilation")
+	CheckCmd.Flags().StringVar(&format, "format", data.FORMAT_TEXT, "output format for errors and warnings: text, json or sarif")
+	CheckCmd.Flags().BoolVar(&noColor, "no-color", false, data.NO_COLOR_USAGE)
+}
+
+func runCheck(cmd *cobra.Command, args []string) {
+	if !data.IsProblemFormat(format) {
+		fmt.Fprintf(os.Stderr, "invalid format %s, expected one of: text, json, sarif\n", format)
+		os.Exit(2)
+	}
+
+	sources, project, err := compiler.ResolveSources(args, ".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	opts := compiler.Options{Verbose: *verbose}
+	if project != nil {
+		opts.SourceRoots = project.SourceRoots()
+	}
+	compiler := compiler.NewCompiler(sources, opts)
+	compiler.Compile()
+	problems := compiler.Errors()
+
+	if err := data.WriteProblems(os.Stdout, problems, format, data.CommandConsoleOptions(noColor, compiler.SourceText)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if errors, _ := data.CountProblems(problems); errors > 0 {
+		os.Exit(1)
+	}
+}
@@ -2,39 +2,82 @@ package compilecmd
 
 import (
 	"fmt"
-	"strings"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/stackoverflow/novah-go/compiler"
+	"github.com/stackoverflow/novah-go/data"
 )
 
 var CompileCmd = &cobra.Command{
-	Use:   "compile [novah sources]",
+	Use:   "compile [novah sources or directories]",
 	Short: "compile novah source files to go",
-	Long:  `compile novah sources to go and store it in the output folder`,
-	Run:   runCompile,
+	Long: `compile novah sources to go and store it in the output folder.
+Directories are searched recursively for sources.
+Without arguments the sources and output of the project (novah.json) are used.`,
+	Run: runCompile,
 }
 
 var output string
 var verbose *bool
+var format string
+var noColor bool
+var verifyReproducible bool
 
 func init() {
 	CompileCmd.Flags().StringVarP(&output, "output", "o", "output", "output directoy for generated files")
 	verbose = CompileCmd.Flags().BoolP("verbose", "v", false, "print more information about the compilation")
+	CompileCmd.Flags().StringVar(&format, "format", data.FORMAT_TEXT, "output format for errors and warnings: text, json or sarif")
+	CompileCmd.Flags().BoolVar(&noColor, "no-color", false, data.NO_COLOR_USAGE)
+	CompileCmd.Flags().BoolVar(&verifyReproducible, "verify-reproducible", false, "compile again and check that the generated code is the same as the output")
 }
 
 func runCompile(cmd *cobra.Command, args []string) {
+	if !data.IsProblemFormat(format) {
+		fmt.Fprintf(os.Stderr, "invalid format %s, expected one of: text, json, sarif\n", format)
+		os.Exit(2)
+	}
+
+	sources, project, err := compiler.ResolveSources(args, ".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if project != nil && !cmd.Flags().Changed("output") {
+		output = project.OutputDir()
+	}
+
 	if *verbose {
 		fmt.Printf("compiling to %s...\n", output)
 	}
 
-	sources := make([]string, 0, len(args))
-	for _, arg := range args {
-		if strings.HasSuffix(arg, ".novah") {
-			sources = append(sources, arg)
+	opts := compiler.Options{Verbose: *verbose}
+	if project != nil {
+		opts.SourceRoots = project.SourceRoots()
+		opts.GoModule = project.GoModule
+	}
+	comp := compiler.NewCompiler(sources, opts)
+	problems := comp.Run(output, false)
+
+	// tools always get a full report, even if it's empty
+	if len(problems) > 0 || format != data.FORMAT_TEXT {
+		if err := data.WriteProblems(os.Stdout, problems, format, data.CommandConsoleOptions(noColor, comp.SourceText)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
 		}
 	}
+	if errors, _ := data.CountProblems(problems); errors > 0 {
+		os.Exit(1)
+	}
 
-	compiler := compiler.NewCompiler(sources, compiler.Options{Verbose: *verbose})
-	compiler.Run(output, false)
+	if verifyReproducible {
+		hash, _, err := compiler.VerifyReproducible(sources, opts, output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if format == data.FORMAT_TEXT {
+			fmt.Printf("the output is reproducible: sha256 %s\n", hash)
+		}
+	}
 }
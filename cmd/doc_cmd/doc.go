@@ -0,0 +1,86 @@
+package doccmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/stackoverflow/novah-go/compiler"
+	"github.com/stackoverflow/novah-go/data"
+	"github.com/stackoverflow/novah-go/doc"
+)
+
+var DocCmd = &cobra.Command{
+	Use:   "doc [novah sources or directories]",
+	Short: "generate api documentation for novah source files",
+	Long:  `typecheck novah sources and write one page per module documenting its public declarations, plus an index page and a search index`,
+	Run:   runDoc,
+}
+
+var output string
+var format string
+var title string
+var verbose *bool
+var noColor bool
+
+func init() {
+	DocCmd.Flags().StringVarP(&output, "output", "o", "docs", "output directory for the documentation")
+	DocCmd.Flags().StringVar(&format, "format", doc.FORMAT_MARKDOWN, "format of the pages: markdown or html")
+	DocCmd.Flags().StringVar(&title, "title", "", "title of the index page")
+	verbose = DocCmd.Flags().BoolP("verbose", "v", false, "print more information about the compilation")
+	DocCmd.Flags().BoolVar(&noColor, "no-color", false, data.NO_COLOR_USAGE)
+}
+
+func runDoc(cmd *cobra.Command, args []string) {
+	if format != doc.FORMAT_MARKDOWN && format != doc.FORMAT_HTML {
+		fmt.Fprintf(os.Stderr, "invalid format %s, expected one of: markdown, html\n", format)
+		os.Exit(2)
+	}
+
+	sources, project, err := compiler.ResolveSources(args, ".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// the docs cover every module, not only the ones reachable from the entry
+	if len(args) == 0 && project != nil {
+		if sources, err = project.SourceFiles(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	opts := compiler.Options{Verbose: *verbose}
+	if project != nil {
+		opts.SourceRoots = project.SourceRoots()
+	}
+	compiler := compiler.NewCompiler(sources, opts)
+	modules, _ := compiler.Compile()
+	problems := compiler.Errors()
+	if errors, _ := data.CountProblems(problems); errors > 0 {
+		data.WriteProblems(os.Stderr, problems, data.FORMAT_TEXT, data.CommandConsoleOptions(noColor, compiler.SourceText))
+		os.Exit(1)
+	}
+
+	pages, err := doc.Generate(modules, doc.Options{Format: format, Title: title})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	for _, page := range pages {
+		path := filepath.Join(output, page.Path)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if err := os.WriteFile(path, []byte(page.Content), 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if *verbose {
+			fmt.Printf("wrote %s\n", path)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package explaincmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+var ExplainCmd = &cobra.Command{
+	Use:   "explain [code]",
+	Short: "explain an error or warning code",
+	Long:  `print a longer explanation of an error or warning code with an example of the problem and how to fix it. Without a code list all codes`,
+	Args:  cobra.MaximumNArgs(1),
+	Run:   runExplain,
+}
+
+func runExplain(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		for _, code := range data.ExplainedCodes() {
+			title, _ := data.ExplainTitle(code)
+			fmt.Printf("%s  %s\n", code, title)
+		}
+		return
+	}
+
+	code := normalizeCode(args[0])
+	text, has := data.Explain(code)
+	if !has {
+		fmt.Fprintf(os.Stderr, "unknown code %s\n", args[0])
+		os.Exit(1)
+	}
+	fmt.Print(text)
+}
+
+// accepts codes like `N0042`, `n0042` and `42`
+func normalizeCode(code string) string {
+	code = strings.TrimPrefix(strings.ToUpper(code), "N")
+	if len(code) < 4 {
+		code = strings.Repeat("0", 4-len(code)) + code
+	}
+	return "N" + code
+}
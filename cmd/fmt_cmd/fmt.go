@@ -0,0 +1,138 @@
+package fmtcmd
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stackoverflow/novah-go/compiler"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+var FmtCmd = &cobra.Command{
+	Use:   "fmt [novah sources or directories]",
+	Short: "format novah source files",
+	Long: `format novah sources in the canonical layout and print the result.
+Without arguments, or with -, the source is read from stdin.
+Files that fail to parse are reported and never rewritten`,
+	Run: runFmt,
+}
+
+var write *bool
+var check *bool
+var diff *bool
+
+func init() {
+	write = FmtCmd.Flags().BoolP("write", "w", false, "rewrite the files in place instead of printing them")
+	check = FmtCmd.Flags().BoolP("check", "c", false, "list the files that are not formatted and exit with an error if there are any")
+	diff = FmtCmd.Flags().BoolP("diff", "d", false, "print a unified diff of the changes instead of the formatted files")
+}
+
+func runFmt(cmd *cobra.Command, args []string) {
+	if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
+		text, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if !formatFile("<stdin>", string(text), false) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	files, err := collectSources(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	ok := true
+	for _, file := range files {
+		text, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			ok = false
+			continue
+		}
+		ok = formatFile(file, string(text), true) && ok
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// Formats one source and reports it according to the flags.
+// Returns false if the file failed to parse or is not formatted in check mode.
+func formatFile(path, text string, isFile bool) bool {
+	formatted, errs := compiler.FormatSource(path, text)
+	if len(errs) > 0 {
+		opts := data.ConsoleOptions{Color: false, Source: func(string) (string, bool) { return text, true }}
+		data.WriteProblems(os.Stderr, errs, data.FORMAT_TEXT, opts)
+		return false
+	}
+
+	changed := formatted != text
+	if *diff {
+		fmt.Print(data.UnifiedDiff(text, formatted, path, path))
+	}
+	if *check {
+		if changed {
+			fmt.Println(path)
+		}
+		return !changed
+	}
+	if *write && isFile {
+		if changed {
+			if err := writeFile(path, formatted); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return false
+			}
+		}
+		return true
+	}
+	if !*diff {
+		fmt.Print(formatted)
+	}
+	return true
+}
+
+// Expands directories to all the novah sources inside them
+func collectSources(args []string) ([]string, error) {
+	files := make([]string, 0, len(args))
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(path, ".novah") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Writes the file keeping its permissions
+func writeFile(path, text string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(text), info.Mode().Perm())
+}
@@ -0,0 +1,26 @@
+package lspcmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stackoverflow/novah-go/lsp"
+)
+
+var LspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "start the novah language server",
+	Long:  `start a language server that speaks the Language Server Protocol over stdin and stdout`,
+	Args:  cobra.NoArgs,
+	Run:   runLsp,
+}
+
+func runLsp(cmd *cobra.Command, args []string) {
+	server := lsp.NewServer(os.Stdin, os.Stdout)
+	server.Log = os.Stderr
+	if err := server.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,126 @@
+package runcmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stackoverflow/novah-go/compiler"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+var RunCmd = &cobra.Command{
+	Use:   "run [novah sources] [-- program arguments]",
+	Short: "compile and run a novah program",
+	Long: `compile novah sources to a go program in a temporary directory and run it.
+The program starts at the main function of the entry module: the entry of the project (novah.json)
+when no sources are given, or else the first source that declares main, starting with the first given file.
+Arguments after -- are passed to the program.`,
+	Run: runRun,
+}
+
+var verbose *bool
+var noColor bool
+
+func init() {
+	verbose = RunCmd.Flags().BoolP("verbose", "v", false, "print more information about the compilation")
+	RunCmd.Flags().BoolVar(&noColor, "no-color", false, data.NO_COLOR_USAGE)
+}
+
+func runRun(cmd *cobra.Command, args []string) {
+	progArgs := []string{}
+	if dash := cmd.ArgsLenAtDash(); dash != -1 {
+		args, progArgs = args[:dash], args[dash:]
+	}
+
+	sources, project, err := compiler.ProgramSources(args, ".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	opts := compiler.Options{Verbose: *verbose}
+	if project != nil {
+		opts.SourceRoots = project.SourceRoots()
+		opts.GoModule = project.GoModule
+		// given sources take precedence over the entry of the project
+		if len(args) == 0 {
+			opts.Entry = project.Entry
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "novah-run")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	os.Exit(run(tmp, sources, opts, progArgs))
+}
+
+// Returns the exit code of the program
+func run(tmp string, sources []string, opts compiler.Options, progArgs []string) int {
+	defer os.RemoveAll(tmp)
+
+	comp := compiler.NewCompiler(sources, opts)
+	problems := comp.RunProgram(tmp)
+	if len(problems) > 0 {
+		if err := data.WriteProblems(os.Stderr, problems, data.FORMAT_TEXT, data.CommandConsoleOptions(noColor, comp.SourceText)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if errs, _ := data.CountProblems(problems); errs > 0 {
+		return 1
+	}
+
+	// module directories never have dots
+	bin := filepath.Join(tmp, "a.out")
+	if err := compiler.GoTool(tmp, "build", "-o", bin, comp.EntryPackage()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	prog := exec.Command(bin, progArgs...)
+	prog.Stdin = os.Stdin
+	prog.Stdout = os.Stdout
+	stderr, err := prog.StderrPipe()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := prog.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	// go panics and stack traces are shown with novah names.
+	// Lines are read whole however long they are.
+	demangler := comp.Demangler()
+	reader := bufio.NewReader(stderr)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			end := ""
+			if strings.HasSuffix(line, "\n") {
+				line, end = strings.TrimSuffix(line, "\n"), "\n"
+			}
+			fmt.Fprint(os.Stderr, demangler.Line(line)+end)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err := prog.Wait(); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			return exit.ExitCode()
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package tracecmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stackoverflow/novah-go/compiler"
+)
+
+var TraceCmd = &cobra.Command{
+	Use:   "trace [stack trace file]",
+	Short: "rewrite a go stack trace with novah names and sources",
+	Long: `rewrite a go panic or stack trace of a compiled novah program to novah functions and file/line references.
+The trace is read from the file or from the standard input.
+Go files are mapped to novah with the source maps generated next to them.
+The go module and entry default to the ones of the project (novah.json).`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runTrace,
+}
+
+var goModule string
+var entry string
+
+func init() {
+	TraceCmd.Flags().StringVar(&goModule, "go-module", "", "the go module of the program")
+	TraceCmd.Flags().StringVar(&entry, "entry", "", "the entry module of the program")
+}
+
+func runTrace(cmd *cobra.Command, args []string) {
+	project, err := compiler.FindProject(".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if project != nil {
+		if !cmd.Flags().Changed("go-module") {
+			goModule = project.GoModule
+		}
+		if !cmd.Flags().Changed("entry") {
+			entry = project.Entry
+		}
+	}
+
+	var in io.Reader = os.Stdin
+	if len(args) > 0 {
+		file, err := os.Open(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	demangler := compiler.NewDemangler(goModule, entry)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fmt.Println(demangler.Line(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
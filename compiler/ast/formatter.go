@@ -2,6 +2,8 @@ package ast
 
 import (
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
 	"strings"
 
@@ -18,6 +20,12 @@ const (
 type Formatter struct {
 	builder strings.Builder
 	tab     string
+	// the comment of the code being shown
+	comment *lexer.Comment
+	// the comments not attached to the code that were not shown yet, in order
+	detached []lexer.Comment
+	// the comments inside the line being shown, to show before it
+	hoisted *[]lexer.Comment
 }
 
 func NewFormatter() *Formatter {
@@ -29,8 +37,20 @@ func Format(ast SModule) string {
 	return f.ShowModule(ast)
 }
 
+// Formats the module with all the comments scanned by the lexer.
+// The comments the lexer didn't attach to the code are shown where they were.
+func FormatWithComments(ast SModule, comments []lexer.Comment) string {
+	f := &Formatter{tab: "", detached: detachedComments(ast, comments)}
+	return f.ShowModule(ast)
+}
+
 func (f *Formatter) ShowModule(m SModule) string {
 	var build strings.Builder
+	start := m.Name.Span.Start
+	if m.Comment != nil {
+		start = m.Comment.Span.Start
+	}
+	build.WriteString(f.showDetachedDecls(start))
 	if m.Comment != nil {
 		build.WriteString(f.ShowComment(*m.Comment, true))
 	}
@@ -42,18 +62,120 @@ func (f *Formatter) ShowModule(m SModule) string {
 		imps := make([]Import, len(m.Imports))
 		copy(imps, m.Imports)
 		slices.SortStableFunc(imps, func(i, j Import) bool { return i.Module.Val < j.Module.Val })
-		build.WriteString(data.JoinToStringFunc(imps, "\n", f.ShowImport))
+		for i, imp := range imps {
+			if i > 0 {
+				build.WriteString("\n")
+			}
+			start := imp.Span.Start
+			if imp.Comment != nil {
+				start = imp.Comment.Span.Start
+			}
+			// detached comments are separated from the previous import
+			if detached := f.showDetachedDecls(start); detached != "" {
+				if i > 0 {
+					build.WriteString("\n")
+				}
+				build.WriteString(detached)
+			}
+			build.WriteString(f.ShowImport(imp))
+		}
 	}
 
 	if len(m.Decls) > 0 {
 		for _, d := range m.Decls {
 			build.WriteString("\n\n")
+			start := d.GetSpan().Start
+			if d.GetComment() != nil {
+				start = d.GetComment().Span.Start
+			}
+			build.WriteString(f.showDetachedDecls(start))
 			build.WriteString(f.ShowDecl(d))
 		}
 	}
+	if rest := f.showDetachedDecls(data.Pos{Line: math.MaxInt}); rest != "" {
+		build.WriteString("\n\n")
+		build.WriteString(strings.TrimRight(rest, "\n"))
+	}
 	return build.String()
 }
 
+// Shows the detached comments before pos between declarations.
+// They are followed by an empty line, unless they had none before pos,
+// so they are not attached to the next declaration.
+func (f *Formatter) showDetachedDecls(pos data.Pos) string {
+	comments := f.takeDetached(pos)
+	var build strings.Builder
+	for i, c := range comments {
+		build.WriteString(f.ShowComment(c, true))
+		next := pos.Line
+		if i < len(comments)-1 {
+			next = comments[i+1].Span.Start.Line
+		}
+		if c.Span.End.Line+1 != next {
+			build.WriteString("\n")
+		}
+	}
+	return build.String()
+}
+
+// Removes and returns the detached comments that end before pos
+func (f *Formatter) takeDetached(pos data.Pos) []lexer.Comment {
+	i := 0
+	for i < len(f.detached) && posBefore(f.detached[i].Span.End, pos) {
+		i++
+	}
+	comments := f.detached[:i]
+	f.detached = f.detached[i:]
+	return comments
+}
+
+// Returns the comments not attached to any node of the module.
+// Adjacent line comments are attached to the code together, as a single comment.
+func detachedComments(m SModule, comments []lexer.Comment) []lexer.Comment {
+	var attached []data.Span
+	collectComments(reflect.ValueOf(m), &attached)
+	return data.FilterSlice(comments, func(c lexer.Comment) bool {
+		for _, span := range attached {
+			if !posBefore(c.Span.Start, span.Start) && !posBefore(span.End, c.Span.End) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+var commentType = reflect.TypeOf(lexer.Comment{})
+
+func collectComments(v reflect.Value, spans *[]data.Span) {
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == commentType {
+			*spans = append(*spans, v.Interface().(lexer.Comment).Span)
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			collectComments(v.Field(i), spans)
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collectComments(v.Elem(), spans)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectComments(v.Index(i), spans)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectComments(iter.Value(), spans)
+		}
+	}
+}
+
+func posBefore(a, b data.Pos) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+}
+
 func (f *Formatter) ShowImport(imp Import) string {
 	cmt := ""
 	if imp.Comment != nil {
@@ -61,7 +183,7 @@ func (f *Formatter) ShowImport(imp Import) string {
 	}
 	exposes := ""
 	if len(imp.Defs) > 0 {
-		exposes = fmt.Sprintf("(%s)", data.JoinToStringFunc(imp.Defs, ", ", f.ShowDeclarationRef))
+		exposes = fmt.Sprintf(" (%s)", data.JoinToStringFunc(imp.Defs, ", ", f.ShowDeclarationRef))
 	}
 	alias := ""
 	if imp.Alias != "" {
@@ -117,7 +239,7 @@ func (f *Formatter) ShowDecl(d SDecl) string {
 	case STypeAliasDecl:
 		body = fmt.Sprintf("%s%s", vis, f.ShowTypealiasDecl(dd))
 	}
-	return fmt.Sprintf("%s %s", cmt, body)
+	return fmt.Sprintf("%s%s", cmt, body)
 }
 
 func (f *Formatter) ShowValDec(vd SValDecl) string {
@@ -126,16 +248,22 @@ func (f *Formatter) ShowValDec(vd SValDecl) string {
 		prefix.WriteString(f.showNameType(vd.Binder.Val, vd.Signature.Type, vd.IsOperator))
 		prefix.WriteString("\n")
 	}
-	prefix.WriteString(vd.Binder.Val)
-	prefix.WriteString(" ")
-	prefix.WriteString(data.JoinToStringFunc(vd.Pats, " ", f.ShowPattern))
+	if vd.IsOperator {
+		prefix.WriteString(fmt.Sprintf("(%s)", vd.Binder.Val))
+	} else {
+		prefix.WriteString(vd.Binder.Val)
+	}
+	if len(vd.Pats) > 0 {
+		prefix.WriteString(" ")
+		prefix.WriteString(data.JoinToStringFunc(vd.Pats, " ", f.ShowPattern))
+	}
 	prefix.WriteString(" =")
 
-	if isSimpleExpr(vd.Exp) {
+	if f.isSimpleExpr(vd.Exp) {
 		prefix.WriteString(" ")
 		prefix.WriteString(f.ShowExpr(vd.Exp))
 	} else {
-		prefix.WriteString(f.withIndentDef(func() string { return f.tab + f.ShowExpr(vd.Exp) }))
+		prefix.WriteString(f.withIndentDef(func() string { return f.tab + f.showLineExpr(vd.Exp) }))
 	}
 	return prefix.String()
 }
@@ -183,36 +311,41 @@ func (f *Formatter) showNameType(name string, ty SType, isOp bool) string {
 }
 
 func (f *Formatter) ShowDataCtor(dc SDataCtor) string {
+	if len(dc.Args) == 0 {
+		return dc.Name.Val
+	}
 	args := data.JoinToStringFunc(dc.Args, " ", f.ShowType)
 	return fmt.Sprintf("%s %s", dc.Name.Val, args)
 }
 
 func (f *Formatter) ShowExpr(exp SExpr) string {
-	cmt := ""
-	if exp.GetComment() != nil {
-		cmt = f.ShowComment(*exp.GetComment(), false)
-	}
+	comments, restore := f.commentsBefore(exp.GetComment(), exp.GetSpan())
+	defer restore()
+	cmt := f.showComments(comments, exp.GetSpan())
 	var estr string
 	switch e := exp.(type) {
 	case SDo:
-		estr = data.JoinToStringFunc(e.Exps, "\n"+f.tab, f.ShowExpr)
+		estr = data.JoinToStringFunc(e.Exps, "\n"+f.tab, f.showLineExpr)
 	case SMatch:
 		{
 			exps := data.JoinToStringFunc(e.Exprs, ", ", f.ShowExpr)
 			cases := f.withIndentDef(func() string {
-				return data.JoinToStringFunc(e.Cases, "\n"+f.tab, f.ShowCase)
+				return f.tab + data.JoinToStringFunc(e.Cases, "\n"+f.tab, func(c SCase) string {
+					return f.showLine(func() string { return f.ShowCase(c) })
+				})
 			})
-			estr = fmt.Sprintf("case %s of%s%s", exps, f.tab, cases)
+			estr = fmt.Sprintf("case %s of%s", exps, cases)
 		}
 	case SLet:
 		{
-			var str string
-			if isSimpleExpr(e.Body) {
-				str = fmt.Sprintf("in %s", f.ShowExpr(e.Body))
-			} else {
-				str = fmt.Sprintf("in %s", f.withIndentDef(func() string { return f.tab + f.ShowExpr(e.Body) }))
-			}
-			estr = fmt.Sprintf("let %s\n%s%s", f.ShowLetDef(e.Def, false), f.tab, str)
+			def := f.ShowLetDef(e.Def, false)
+			str := f.showLine(func() string {
+				if f.isSimpleExpr(e.Body) {
+					return fmt.Sprintf("in %s", f.ShowExpr(e.Body))
+				}
+				return fmt.Sprintf("in %s", f.withIndentDef(func() string { return f.tab + f.showLineExpr(e.Body) }))
+			})
+			estr = fmt.Sprintf("let %s\n%s%s", def, f.tab, str)
 		}
 	case SDoLet:
 		estr = fmt.Sprintf("let %s", f.ShowLetDef(e.Def, false))
@@ -223,21 +356,26 @@ func (f *Formatter) ShowExpr(exp SExpr) string {
 			estr = fmt.Sprintf("let! %s in %s", f.ShowLetDef(e.Def, false), f.ShowExpr(e.Body))
 		}
 	case SFor:
-		estr = fmt.Sprintf("for %s, do %s", f.ShowLetDef(e.Def, true), f.ShowExpr(e.Body))
+		estr = fmt.Sprintf("for %s do%s", f.ShowLetDef(e.Def, true), f.withIndentDef(func() string { return f.tab + f.showLineExpr(e.Body) }))
 	case SDoBang:
 		estr = fmt.Sprintf("do! %s", f.ShowExpr(e.Exp))
 	case SIf:
 		if e.Else != nil {
-			if isSimpleExpr(e.Then) && isSimpleExpr(e.Else) {
+			if f.isSimpleExpr(e.Then) && f.isSimpleExpr(e.Else) {
 				estr = fmt.Sprintf("if %s then %s else %s", f.ShowExpr(e.Cond), f.ShowExpr(e.Then), f.ShowExpr(e.Else))
 			} else {
-				estr = fmt.Sprintf("if %s\n%sthen %s\n%selse %s", f.ShowExpr(e.Cond), f.tab, f.ShowExpr(e.Then), f.tab, f.ShowExpr(e.Else))
+				cond := f.ShowExpr(e.Cond)
+				then := f.showLine(func() string { return "then " + f.ShowExpr(e.Then) })
+				els := f.showLine(func() string { return "else " + f.ShowExpr(e.Else) })
+				estr = fmt.Sprintf("if %s\n%s%s\n%s%s", cond, f.tab, then, f.tab, els)
 			}
 		} else {
-			if isSimpleExpr(e.Then) {
+			if f.isSimpleExpr(e.Then) {
 				estr = fmt.Sprintf("if %s then %s", f.ShowExpr(e.Cond), f.ShowExpr(e.Then))
 			} else {
-				estr = fmt.Sprintf("if %s\n%sthen %s", f.ShowExpr(e.Cond), f.tab, f.ShowExpr(e.Then))
+				cond := f.ShowExpr(e.Cond)
+				then := f.showLine(func() string { return "then " + f.ShowExpr(e.Then) })
+				estr = fmt.Sprintf("if %s\n%s%s", cond, f.tab, then)
 			}
 		}
 	case SReturn:
@@ -249,10 +387,10 @@ func (f *Formatter) ShowExpr(exp SExpr) string {
 	case SLambda:
 		{
 			var show string
-			if isSimpleExpr(e.Body) {
+			if f.isSimpleExpr(e.Body) {
 				show = fmt.Sprintf(" -> %s", f.ShowExpr(e.Body))
 			} else {
-				show = fmt.Sprintf(" ->%s", f.withIndentDef(func() string { return f.tab + f.ShowExpr(e.Body) }))
+				show = fmt.Sprintf(" ->%s", f.withIndentDef(func() string { return f.tab + f.showLineExpr(e.Body) }))
 			}
 			pats := data.JoinToStringFunc(e.Pats, " ", f.ShowPattern)
 			estr = fmt.Sprintf("\\%s%s", pats, show)
@@ -328,7 +466,11 @@ func (f *Formatter) ShowExpr(exp SExpr) string {
 	case SRecordUpdate:
 		{
 			labels := data.JoinToStringFunc(e.Labels, ".", func(l Spanned[string]) string { return data.ShowLabel(l.Val) })
-			estr = fmt.Sprintf("{ .%s = %s | %s }", labels, f.ShowExpr(e.Val), f.ShowExpr(e.Exp))
+			op := "="
+			if !e.IsSet {
+				op = "->"
+			}
+			estr = fmt.Sprintf("{ .%s %s %s | %s }", labels, op, f.ShowExpr(e.Val), f.ShowExpr(e.Exp))
 		}
 	case SRecordExtend:
 		{
@@ -345,7 +487,7 @@ func (f *Formatter) ShowExpr(exp SExpr) string {
 	case SListLiteral:
 		estr = fmt.Sprintf("[%s]", data.JoinToStringFunc(e.Exps, ", ", f.ShowExpr))
 	case SSetLiteral:
-		estr = fmt.Sprintf("[%s]", data.JoinToStringFunc(e.Exps, ", ", f.ShowExpr))
+		estr = fmt.Sprintf("#{%s}", data.JoinToStringFunc(e.Exps, ", ", f.ShowExpr))
 	case SIndex:
 		estr = fmt.Sprintf("%s.[%s]", f.ShowExpr(e.Exp), f.ShowExpr(e.Index))
 	case SBinApp:
@@ -353,19 +495,21 @@ func (f *Formatter) ShowExpr(exp SExpr) string {
 	case SWhile:
 		{
 			body := f.withIndentDef(func() string {
-				return data.JoinToStringFunc(e.Exps, "\n"+f.tab, f.ShowExpr)
+				return f.tab + data.JoinToStringFunc(e.Exps, "\n"+f.tab, f.showLineExpr)
 			})
 			estr = fmt.Sprintf("while %s do%s", f.ShowExpr(e.Cond), body)
 		}
 	case SComputation:
 		{
 			body := f.withIndentDef(func() string {
-				return data.JoinToStringFunc(e.Exps, "\n"+f.tab, f.ShowExpr)
+				return f.tab + data.JoinToStringFunc(e.Exps, "\n"+f.tab, f.showLineExpr)
 			})
 			estr = fmt.Sprintf("do.%s%s", e.Builder.Name, body)
 		}
 	case SNil:
 		estr = "nil"
+	case SAnn:
+		estr = fmt.Sprintf("%s : %s", f.ShowExpr(e.Exp), f.ShowType(e.Type))
 	case STypeCast:
 		estr = fmt.Sprintf("%s as %s", f.ShowExpr(e.Exp), f.ShowType(e.Cast))
 	}
@@ -385,36 +529,85 @@ func (f *Formatter) ShowLetDef(l SLetDef, isFor bool) string {
 			if def.Type != nil {
 				typ = fmt.Sprintf("%s : %s\n%s", def.Name.Name, f.ShowType(def.Type), f.tab)
 			}
-			pats := data.JoinToStringFunc(def.Pats, " ", func(p SPattern) string { return f.ShowPattern(p) })
-			prefix = fmt.Sprintf("%s%s %s %s", typ, def.Name.Name, pats, sep)
+			pats := data.JoinToStringFunc(def.Pats, "", func(p SPattern) string { return " " + f.ShowPattern(p) })
+			prefix = fmt.Sprintf("%s%s%s %s", typ, def.Name.Name, pats, sep)
 		}
 	case SLetPat:
 		prefix = fmt.Sprintf("%s %s", f.ShowPattern(def.Pat), sep)
 	}
-	if isSimpleExpr(l.GetExpr()) {
+	if f.isSimpleExpr(l.GetExpr()) {
 		return fmt.Sprintf("%s %s", prefix, f.ShowExpr(l.GetExpr()))
 	}
-	return prefix + f.withIndentDef(func() string { return fmt.Sprintf("%s%s", f.tab, f.ShowExpr(l.GetExpr())) })
+	return prefix + f.withIndentDef(func() string { return fmt.Sprintf("%s%s", f.tab, f.showLineExpr(l.GetExpr())) })
 }
 
 func (f *Formatter) ShowComment(c lexer.Comment, newline bool) string {
 	if !c.IsMulti {
-		return fmt.Sprintf("// %s\n", c.Text)
+		// the text of line comments keeps the space after the slashes
+		lines := strings.Split(c.Text, "\n")
+		return data.JoinToStringFunc(lines, "\n"+f.tab, func(l string) string { return "//" + l }) + "\n" + f.tab
 	}
-	end := ""
+	end := " "
 	if newline {
-		end = "\n"
+		end = "\n" + f.tab
 	}
 	return fmt.Sprintf("/*%s*/%s", c.Text, end)
 }
 
+// Returns the comments to show before the code at span:
+// the detached comments before it and its comment, if it's not already shown.
+// The parser attaches the same comment to an expression and to the first expression
+// inside it (a do and its first statement, an application and its function),
+// so the comment is not shown again until restore is called.
+func (f *Formatter) commentsBefore(c *lexer.Comment, span data.Span) ([]lexer.Comment, func()) {
+	comments := append([]lexer.Comment{}, f.takeDetached(span.Start)...)
+	if c == nil || c == f.comment {
+		return comments, func() {}
+	}
+	prev := f.comment
+	f.comment = c
+	return append(comments, *c), func() { f.comment = prev }
+}
+
+// Shows the comments before the code at span,
+// or moves them before the line being shown
+func (f *Formatter) showComments(comments []lexer.Comment, span data.Span) string {
+	if f.hoisted != nil {
+		*f.hoisted = append(*f.hoisted, comments...)
+		return ""
+	}
+	return data.JoinToStringFunc(comments, "", func(c lexer.Comment) string {
+		// block comments on their own line stay there
+		return f.ShowComment(c, c.Span.End.Line < span.Start.Line)
+	})
+}
+
+// Shows code that starts a new line.
+// The comments inside the code are shown before the line,
+// a line comment in the middle of it would end the code there.
+func (f *Formatter) showLine(show func() string) string {
+	prev := f.hoisted
+	var comments []lexer.Comment
+	f.hoisted = &comments
+	line := show()
+	f.hoisted = prev
+	return data.JoinToStringFunc(comments, "", func(c lexer.Comment) string { return f.ShowComment(c, true) }) + line
+}
+
+func (f *Formatter) showLineExpr(exp SExpr) string {
+	return f.showLine(func() string { return f.ShowExpr(exp) })
+}
+
 func (f *Formatter) ShowCase(cas SCase) string {
+	comments, restore := f.commentsBefore(cas.Comment, cas.Pats[0].GetSpan())
+	defer restore()
+	cmt := f.showComments(comments, cas.Pats[0].GetSpan())
 	guard := ""
 	if cas.Guard != nil {
 		guard = fmt.Sprintf(" if %s", f.ShowExpr(cas.Guard))
 	}
 	pats := data.JoinToStringFunc(cas.Pats, ", ", func(p SPattern) string { return f.ShowPattern(p) })
-	return fmt.Sprintf("%s%s -> %s", pats, guard, f.ShowExpr(cas.Exp))
+	return fmt.Sprintf("%s%s%s -> %s", cmt, pats, guard, f.ShowExpr(cas.Exp))
 }
 
 func (f *Formatter) ShowPattern(pat SPattern) string {
@@ -425,8 +618,8 @@ func (f *Formatter) ShowPattern(pat SPattern) string {
 		return f.ShowExpr(p.V)
 	case SCtorP:
 		{
-			fields := data.JoinToStringFunc(p.Fields, " ", func(pa SPattern) string { return f.ShowPattern(pa) })
-			return fmt.Sprintf("%s %s", f.ShowExpr(p.Ctor), fields)
+			fields := data.JoinToStringFunc(p.Fields, "", func(pa SPattern) string { return " " + f.ShowPattern(pa) })
+			return fmt.Sprintf("%s%s", f.ShowExpr(p.Ctor), fields)
 		}
 	case SLiteralP:
 		return f.ShowExpr(p.Lit)
@@ -465,7 +658,7 @@ func (f *Formatter) ShowPattern(pat SPattern) string {
 	case STupleP:
 		return fmt.Sprintf("%s ; %s", f.ShowPattern(p.P1), f.ShowPattern(p.P2))
 	case SRegexP:
-		return fmt.Sprintf(`#"%s"`, f.ShowExpr(p.Regex))
+		return f.ShowExpr(p.Regex)
 	default:
 		panic("received unknow pattern " + p.String())
 	}
@@ -541,7 +734,15 @@ func (f *Formatter) showLabelType(l string, ty SType) string {
 	return fmt.Sprintf("%s : %s", l, f.ShowType(ty))
 }
 
-func isSimpleExpr(exp SExpr) bool {
+// Simple expressions are shown in the same line,
+// unless they have a comment, which is shown in its own line
+func (f *Formatter) isSimpleExpr(exp SExpr) bool {
+	if c := exp.GetComment(); c != nil && c != f.comment {
+		return false
+	}
+	if len(f.detached) > 0 && posBefore(f.detached[0].Span.End, exp.GetSpan().Start) {
+		return false
+	}
 	switch exp.(type) {
 	case SInt, SFloat, SComplex, SString, SChar, SBool, SVar, SOperator, SPatternLiteral:
 		return true
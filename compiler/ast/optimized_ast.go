@@ -6,11 +6,15 @@ import (
 )
 
 type GoPackage struct {
-	Name       string
+	Name string
+	// the novah module of this package
+	Module     string
 	SourceName string
-	Decls      []GoDecl
-	Pos        data.Pos
-	Comment    *lexer.Comment
+	// the path of the go module of the program
+	GoModule string
+	Decls    []GoDecl
+	Pos      data.Pos
+	Comment  *lexer.Comment
 }
 
 ////////////////////////////////////
@@ -23,17 +27,19 @@ type GoDecl interface {
 }
 
 type GoInterface struct {
-	Name    string
-	Methods []InterMethod
-	Pos     data.Pos
-	Comment *lexer.Comment
+	Name       string
+	TypeParams []string
+	Methods    []InterMethod
+	Pos        data.Pos
+	Comment    *lexer.Comment
 }
 
 type GoStruct struct {
-	Name    string
-	Fields  map[string]GoType
-	Pos     data.Pos
-	Comment *lexer.Comment
+	Name       string
+	TypeParams []string
+	Fields     []GoTField
+	Pos        data.Pos
+	Comment    *lexer.Comment
 }
 
 type GoConstDecl struct {
@@ -51,12 +57,15 @@ type GoVarDecl struct {
 }
 
 type GoFuncDecl struct {
-	Name    string
-	Params  map[string]GoType
-	Returns []GoType
-	Body    *GoExpr
-	Pos     data.Pos
-	Comment *lexer.Comment
+	Name string
+	// the type of the receiver if this is a method
+	Receiver   GoType
+	TypeParams []string
+	Params     []GoParam
+	Returns    []GoType
+	Body       *GoExpr
+	Pos        data.Pos
+	Comment    *lexer.Comment
 }
 
 func (d GoStruct) GetPos() data.Pos {
@@ -90,6 +99,12 @@ func (d GoFuncDecl) GetComment() *lexer.Comment {
 	return d.Comment
 }
 
+// A parameter of a function
+type GoParam struct {
+	Name string
+	Type GoType
+}
+
 type InterMethod struct {
 	Name string
 	Args []GoType
@@ -114,12 +129,14 @@ type GoConst struct {
 type GoVar struct {
 	Name    string
 	Package string
-	Type    GoType
-	Pos     data.Pos
+	// type arguments for generic functions
+	TypeArgs []GoType
+	Type     GoType
+	Pos      data.Pos
 }
 
 type GoFunc struct {
-	Args    map[string]GoType
+	Args    []GoParam
 	Returns []GoType
 	Body    GoExpr
 	Type    GoType
@@ -176,6 +193,7 @@ type GoUnit struct {
 	Pos  data.Pos
 }
 
+// A loop. Loops without a condition only end by a break.
 type GoWhile struct {
 	Cond GoExpr
 	Exps []GoExpr
@@ -183,11 +201,68 @@ type GoWhile struct {
 	Pos  data.Pos
 }
 
+type GoBreak struct {
+	Pos data.Pos
+}
+
 type GoNil struct {
 	Type GoType
 	Pos  data.Pos
 }
 
+type GoBinOp struct {
+	Op    string
+	Left  GoExpr
+	Right GoExpr
+	Type  GoType
+	Pos   data.Pos
+}
+
+type GoField struct {
+	Exp  GoExpr
+	Name string
+	Type GoType
+	Pos  data.Pos
+}
+
+// A switch statement.
+// If IsType is true this is a type switch and
+// Bind is the (optional) name of the narrowed value.
+type GoSwitch struct {
+	Exp     GoExpr
+	Bind    string
+	IsType  bool
+	Cases   []GoCase
+	Default GoExpr
+	Type    GoType
+	Pos     data.Pos
+}
+
+type GoCase struct {
+	Values []GoExpr
+	Types  []GoType
+	Body   GoExpr
+}
+
+// A composite literal of a struct type: T{Name: exp, ...}
+type GoStructLit struct {
+	Fields []GoFieldVal
+	Type   GoType
+	Pos    data.Pos
+}
+
+type GoFieldVal struct {
+	Name string
+	Exp  GoExpr
+}
+
+// A type assertion: exp.(T)
+type GoTypeAssert struct {
+	Exp  GoExpr
+	Type GoType
+	Pos  data.Pos
+}
+
 func (e GoConst) GetType() GoType {
 	return e.Type
 }
@@ -224,9 +299,27 @@ func (e GoUnit) GetType() GoType {
 func (e GoWhile) GetType() GoType {
 	return e.Type
 }
+func (e GoBreak) GetType() GoType {
+	return nil
+}
 func (e GoNil) GetType() GoType {
 	return e.Type
 }
+func (e GoBinOp) GetType() GoType {
+	return e.Type
+}
+func (e GoField) GetType() GoType {
+	return e.Type
+}
+func (e GoSwitch) GetType() GoType {
+	return e.Type
+}
+func (e GoStructLit) GetType() GoType {
+	return e.Type
+}
+func (e GoTypeAssert) GetType() GoType {
+	return e.Type
+}
 
 func (e GoConst) GetPos() data.Pos {
 	return e.Pos
@@ -264,9 +357,27 @@ func (e GoUnit) GetPos() data.Pos {
 func (e GoWhile) GetPos() data.Pos {
 	return e.Pos
 }
+func (e GoBreak) GetPos() data.Pos {
+	return e.Pos
+}
 func (e GoNil) GetPos() data.Pos {
 	return e.Pos
 }
+func (e GoBinOp) GetPos() data.Pos {
+	return e.Pos
+}
+func (e GoField) GetPos() data.Pos {
+	return e.Pos
+}
+func (e GoSwitch) GetPos() data.Pos {
+	return e.Pos
+}
+func (e GoStructLit) GetPos() data.Pos {
+	return e.Pos
+}
+func (e GoTypeAssert) GetPos() data.Pos {
+	return e.Pos
+}
 
 ////////////////////////////////////
 // Type
@@ -286,5 +397,23 @@ type GoTFunc struct {
 	Ret GoType
 }
 
-func (_ GoTConst) goType() {}
-func (_ GoTFunc) goType()  {}
+// An anonymous struct type
+type GoTStruct struct {
+	Fields []GoTField
+}
+
+type GoTField struct {
+	Name string
+	Type GoType
+}
+
+// A generic type applied to its type arguments
+type GoTApp struct {
+	Type GoTConst
+	Args []GoType
+}
+
+func (_ GoTConst) goType()  {}
+func (_ GoTFunc) goType()   {}
+func (_ GoTStruct) goType() {}
+func (_ GoTApp) goType()    {}
@@ -980,9 +980,10 @@ func IsSimple(e *SExpr) bool {
 ///////////////////////////////////////////////
 
 type SCase struct {
-	Pats  []SPattern
-	Exp   SExpr
-	Guard SExpr
+	Pats    []SPattern
+	Exp     SExpr
+	Guard   SExpr
+	Comment *lexer.Comment
 }
 
 func (c *SCase) PatternSpan() data.Span {
@@ -11,38 +11,38 @@ import (
 )
 
 type Codegen struct {
-	pack   ast.GoPackage
-	sb     strings.Builder
-	tab    string
-	toInit []ast.GoVarDecl
+	pack ast.GoPackage
+	sb   strings.Builder
+	tab  string
+	// the names used to reference the imported packages
+	imports   map[string]string
+	declNames data.Set[string]
+	// the novah source as named by the line directives
+	file string
 }
 
 func NewCodegen(pack ast.GoPackage) *Codegen {
-	return &Codegen{pack: pack, toInit: make([]ast.GoVarDecl, 0, 2)}
+	return &Codegen{pack: pack, imports: make(map[string]string), declNames: data.NewSet(declNames(pack.Decls)...), file: pack.SourceName}
 }
 
 func (c *Codegen) Run() string {
-	c.write("//line ", c.pack.SourceName, ":", strconv.Itoa(c.pack.Pos.Line), "\n")
-	c.write("package ", c.pack.Name, "\n\n")
-
-	c.sb.WriteString(`func __if[T any](cond bool, then, els func() T) T {
-  if cond {
-	  return then()
-  } else {
-	  return els()
-  }
-}
-
-`)
-
 	for _, decl := range c.pack.Decls {
 		c.genDecl(decl)
 		c.sb.WriteString("\n\n")
 	}
-	return c.sb.String()
+
+	// the imports are only known after the code is generated
+	var header strings.Builder
+	header.WriteString("// Code generated by novah from the module " + c.pack.Module + ". DO NOT EDIT.\n\n")
+	header.WriteString(goDoc(c.pack.Comment))
+	header.WriteString("//line " + c.file + ":" + strconv.Itoa(c.pack.Pos.Line) + "\n")
+	header.WriteString("package " + c.pack.Name + "\n\n")
+	c.genImports(&header)
+	return header.String() + c.sb.String()
 }
 
 func (c *Codegen) genDecl(decl ast.GoDecl) {
+	c.sb.WriteString(goDoc(decl.GetComment()))
 	c.writePosLn(decl.GetPos())
 	switch d := decl.(type) {
 	case ast.GoInterface:
@@ -56,9 +56,9 @@ func (c *Codegen) genDecl(decl ast.GoDecl) {
 		}
 	case ast.GoVarDecl:
 		{
-			c.write("var ", d.Name)
+			// initialized in the init function
+			c.write("var ", d.Name, " ")
 			c.genType(d.Type)
-			c.toInit = append(c.toInit, d)
 		}
 	case ast.GoFuncDecl:
 		c.genFuncDecl(d)
@@ -68,15 +68,21 @@ func (c *Codegen) genDecl(decl ast.GoDecl) {
 }
 
 func (c *Codegen) genFuncDecl(d ast.GoFuncDecl) {
-	c.write("func ", d.Name, "(")
-	i := 0
-	for par, typ := range d.Params {
+	c.sb.WriteString("func ")
+	if d.Receiver != nil {
+		c.sb.WriteRune('(')
+		c.genType(d.Receiver)
+		c.sb.WriteString(") ")
+	}
+	c.sb.WriteString(d.Name)
+	c.genTypeParams(d.TypeParams)
+	c.sb.WriteRune('(')
+	for i, par := range d.Params {
 		if i > 0 {
 			c.sb.WriteString(", ")
 		}
-		c.write(par, " ")
-		c.genType(typ)
-		i++
+		c.write(par.Name, " ")
+		c.genType(par.Type)
 	}
 	c.sb.WriteRune(')')
 	if len(d.Returns) > 0 {
@@ -99,26 +105,30 @@ func (c *Codegen) genFuncDecl(d ast.GoFuncDecl) {
 	} else {
 		c.sb.WriteString(" {\n")
 		c.withTab(func() {
-			c.genExpr(*d.Body)
+			c.genStmt(*d.Body)
 		})
 		c.sb.WriteString("\n}\n\n")
 	}
 }
 
 func (c *Codegen) genStruct(d ast.GoStruct) {
-	c.write("type ", d.Name, " struct {")
+	c.write("type ", d.Name)
+	c.genTypeParams(d.TypeParams)
+	c.sb.WriteString(" struct {")
 	c.withTab(func() {
-		for name, field := range d.Fields {
+		for _, field := range d.Fields {
 			c.sb.WriteRune('\n')
-			c.writeTab(name, " ")
-			c.genType(field)
+			c.writeTab(field.Name, " ")
+			c.genType(field.Type)
 		}
 	})
 	c.sb.WriteString("\n}\n\n")
 }
 
 func (c *Codegen) genInterface(d ast.GoInterface) {
-	c.write("type ", d.Name, " interface {")
+	c.write("type ", d.Name)
+	c.genTypeParams(d.TypeParams)
+	c.sb.WriteString(" interface {")
 	c.withTab(func() {
 		for _, method := range d.Methods {
 			c.sb.WriteRune('\n')
@@ -156,22 +166,21 @@ func (c *Codegen) genExpr(exp ast.GoExpr) {
 		c.sb.WriteString(e.V)
 	case ast.GoVar:
 		{
-			if e.Package != "" {
-				c.write(e.Package, ".")
+			if e.Package != "" && e.Package != c.pack.Module {
+				c.write(c.qualifier(e.Package), ".")
 			}
 			c.write(e.Name)
+			c.genTypeArgs(e.TypeArgs)
 		}
 	case ast.GoFunc:
 		{
 			c.sb.WriteString("func (")
-			i := 0
-			for v, ty := range e.Args {
+			for i, arg := range e.Args {
 				if i > 0 {
 					c.sb.WriteString(", ")
 				}
-				c.write(v, " ")
-				c.genType(ty)
-				i++
+				c.write(arg.Name, " ")
+				c.genType(arg.Type)
 			}
 			c.sb.WriteRune(')')
 			if len(e.Returns) > 0 {
@@ -191,8 +200,7 @@ func (c *Codegen) genExpr(exp ast.GoExpr) {
 			}
 			c.write(" {\n")
 			c.withTab(func() {
-				c.sb.WriteString(c.tab)
-				c.genExpr(e.Body)
+				c.genStmt(e.Body)
 			})
 			c.write("\n", c.tab, "}")
 		}
@@ -219,14 +227,14 @@ func (c *Codegen) genExpr(exp ast.GoExpr) {
 			c.genExpr(e.Cond)
 			c.sb.WriteString(" {\n")
 			c.withTab(func() {
-				c.sb.WriteString(c.tab)
-				c.genExpr(e.Then)
-			})
-			c.write("\n", c.tab, "} else {\n")
-			c.withTab(func() {
-				c.sb.WriteString(c.tab)
-				c.genExpr(e.Else)
+				c.genStmt(e.Then)
 			})
+			if e.Else != nil {
+				c.write("\n", c.tab, "} else {\n")
+				c.withTab(func() {
+					c.genStmt(e.Else)
+				})
+			}
 			c.write("\n", c.tab, "}")
 		}
 	case ast.GoVarDef:
@@ -247,9 +255,11 @@ func (c *Codegen) genExpr(exp ast.GoExpr) {
 	case ast.GoStmts:
 		for i, exp := range e.Exps {
 			if i > 0 {
-				c.write("\n", c.tab)
+				c.sb.WriteRune('\n')
+				c.genStmt(exp)
+			} else {
+				c.genExpr(exp)
 			}
-			c.genExpr(exp)
 		}
 	case ast.GoUnit:
 		c.sb.WriteString("nil")
@@ -257,29 +267,123 @@ func (c *Codegen) genExpr(exp ast.GoExpr) {
 		c.sb.WriteString("nil")
 	case ast.GoWhile:
 		{
-			c.sb.WriteString("for ")
-			c.genExpr(e.Cond)
+			c.sb.WriteString("for")
+			if e.Cond != nil {
+				c.sb.WriteRune(' ')
+				c.genExpr(e.Cond)
+			}
 			c.withTab(func() {
-				c.write(" {\n", c.tab)
+				c.sb.WriteString(" {\n")
 				for i, exp := range e.Exps {
 					if i > 0 {
-						c.write("\n", c.tab)
+						c.sb.WriteRune('\n')
 					}
-					c.genExpr(exp)
+					c.genStmt(exp)
 				}
 			})
+			c.write("\n", c.tab, "}")
+		}
+	case ast.GoBreak:
+		c.sb.WriteString("break")
+	case ast.GoBinOp:
+		{
+			c.genExpr(e.Left)
+			c.write(" ", e.Op, " ")
+			c.genExpr(e.Right)
+		}
+	case ast.GoField:
+		{
+			c.genExpr(e.Exp)
+			c.write(".", e.Name)
+		}
+	case ast.GoSwitch:
+		c.genSwitch(e)
+	case ast.GoStructLit:
+		{
+			c.genType(e.Type)
+			c.sb.WriteRune('{')
+			for i, f := range e.Fields {
+				if i > 0 {
+					c.sb.WriteString(", ")
+				}
+				c.write(f.Name, ": ")
+				c.genExpr(f.Exp)
+			}
+			c.sb.WriteRune('}')
+		}
+	case ast.GoTypeAssert:
+		{
+			c.genExpr(e.Exp)
+			c.sb.WriteString(".(")
+			c.genType(e.Type)
+			c.sb.WriteRune(')')
 		}
 	default:
 		panic("unknow GoExpr in codegen")
 	}
 }
 
+// Statements start on their own line, after a line directive
+// to the novah code they came from.
+func (c *Codegen) genStmt(exp ast.GoExpr) {
+	if stmts, isStmts := exp.(ast.GoStmts); isStmts {
+		for i, stmt := range stmts.Exps {
+			if i > 0 {
+				c.sb.WriteRune('\n')
+			}
+			c.genStmt(stmt)
+		}
+		return
+	}
+	c.writePosLn(exp.GetPos())
+	c.sb.WriteString(c.tab)
+	c.genExpr(exp)
+}
+
+func (c *Codegen) genSwitch(e ast.GoSwitch) {
+	c.sb.WriteString("switch ")
+	if e.Bind != "" {
+		c.write(e.Bind, " := ")
+	}
+	c.genExpr(e.Exp)
+	if e.IsType {
+		c.sb.WriteString(".(type)")
+	}
+	c.sb.WriteString(" {")
+	for _, cas := range e.Cases {
+		c.write("\n", c.tab, "case ")
+		for i, val := range cas.Values {
+			if i > 0 {
+				c.sb.WriteString(", ")
+			}
+			c.genExpr(val)
+		}
+		for i, typ := range cas.Types {
+			if i > 0 {
+				c.sb.WriteString(", ")
+			}
+			c.genType(typ)
+		}
+		c.sb.WriteString(":\n")
+		c.withTab(func() {
+			c.genStmt(cas.Body)
+		})
+	}
+	if e.Default != nil {
+		c.write("\n", c.tab, "default:\n")
+		c.withTab(func() {
+			c.genStmt(e.Default)
+		})
+	}
+	c.write("\n", c.tab, "}")
+}
+
 func (c *Codegen) genType(typ ast.GoType) {
 	switch t := typ.(type) {
 	case ast.GoTConst:
 		{
-			if t.Package != "" {
-				c.write(t.Package, ".")
+			if t.Package != "" && t.Package != c.pack.Module {
+				c.write(c.qualifier(t.Package), ".")
 			}
 			c.write(t.Name)
 		}
@@ -290,7 +394,56 @@ func (c *Codegen) genType(typ ast.GoType) {
 			c.sb.WriteString(") ")
 			c.genType(t.Ret)
 		}
+	case ast.GoTStruct:
+		{
+			if len(t.Fields) == 0 {
+				c.sb.WriteString("struct{}")
+				return
+			}
+			c.sb.WriteString("struct{ ")
+			for i, f := range t.Fields {
+				if i > 0 {
+					c.sb.WriteString("; ")
+				}
+				c.write(f.Name, " ")
+				c.genType(f.Type)
+			}
+			c.sb.WriteString(" }")
+		}
+	case ast.GoTApp:
+		{
+			c.genType(t.Type)
+			c.genTypeArgs(t.Args)
+		}
+	}
+}
+
+func (c *Codegen) genTypeArgs(args []ast.GoType) {
+	if len(args) == 0 {
+		return
+	}
+	c.sb.WriteRune('[')
+	for i, arg := range args {
+		if i > 0 {
+			c.sb.WriteString(", ")
+		}
+		c.genType(arg)
+	}
+	c.sb.WriteRune(']')
+}
+
+func (c *Codegen) genTypeParams(params []string) {
+	if len(params) == 0 {
+		return
+	}
+	c.sb.WriteRune('[')
+	for i, param := range params {
+		if i > 0 {
+			c.sb.WriteString(", ")
+		}
+		c.write(param, " any")
 	}
+	c.sb.WriteRune(']')
 }
 
 func (c *Codegen) write(strs ...string) {
@@ -304,12 +457,27 @@ func (c *Codegen) writeTab(strs ...string) {
 	c.write(strs...)
 }
 
+// Positions are written as line directives: go reports the errors, panics
+// and profiles of the code after them at the novah source.
+// Unknown positions are skipped so the code keeps the previous one.
 func (c *Codegen) writePos(pos data.Pos) {
-	c.write("/*line :", strconv.Itoa(pos.Line), ":", strconv.Itoa(pos.Col), "*/")
+	if pos.Line > 0 {
+		c.write("/*line ", c.linePos(pos), "*/")
+	}
 }
 
+// The directives of declarations and statements start at the first column
 func (c *Codegen) writePosLn(pos data.Pos) {
-	c.write("//line :", strconv.Itoa(pos.Line), ":", strconv.Itoa(pos.Col), "\n")
+	if pos.Line > 0 {
+		c.write("//line ", c.linePos(pos), "\n")
+	}
+}
+
+func (c *Codegen) linePos(pos data.Pos) string {
+	if pos.Col > 0 {
+		return c.file + ":" + strconv.Itoa(pos.Line) + ":" + strconv.Itoa(pos.Col)
+	}
+	return c.file + ":" + strconv.Itoa(pos.Line)
 }
 
 func (c *Codegen) withTab(f func()) {
@@ -0,0 +1,7 @@
+package compiler
+
+import "testing"
+
+func TestCollectionCodegen(t *testing.T) {
+	testGolden(t, "collections", generateFunctions, "literals")
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/stackoverflow/novah-go/compiler/typechecker"
@@ -14,6 +15,14 @@ type Options struct {
 	Verbose bool
 	DevMode bool
 	Stdlib  bool
+	// directories where imported modules not passed
+	// to the compiler are looked up
+	SourceRoots []string
+	// the module with the main function of the program,
+	// compiled to the go main package
+	Entry string
+	// path of the generated go module
+	GoModule string
 }
 
 type Compiler struct {
@@ -27,6 +36,11 @@ func NewCompiler(sources []string, opts Options) *Compiler {
 	return &Compiler{sources: entries, opts: opts, env: NewEnviroment(opts)}
 }
 
+// Creates a compiler for sources that may already be in memory.
+func NewCompilerFromSources(sources []Source, opts Options) *Compiler {
+	return &Compiler{sources: sources, opts: opts, env: NewEnviroment(opts)}
+}
+
 func (c *Compiler) Compile() (map[string]typechecker.FullModuleEnv, []data.CompilerProblem) {
 	return c.env.ParseSources(c.sources)
 }
@@ -36,13 +50,50 @@ func (c *Compiler) Run(output string, dryRun bool) []data.CompilerProblem {
 	if errs != nil {
 		return errs
 	}
-	if len(c.env.errors) > 0 {
+	if errors, _ := data.CountProblems(c.env.errors); errors > 0 {
 		return c.env.errors
 	}
 	c.env.GenerateCode(output, dryRun)
 	return c.env.errors
 }
 
+// Compiles the sources to a go program in output.
+// If no entry was given the first source that declares a main function is the entry.
+func (c *Compiler) RunProgram(output string) []data.CompilerProblem {
+	_, errs := c.env.ParseSources(c.sources)
+	if errs != nil {
+		return errs
+	}
+	// a main function that doesn't typecheck has no type to check
+	if errors, _ := data.CountProblems(c.env.errors); errors > 0 {
+		return c.env.errors
+	}
+	if c.env.opts.Entry == "" {
+		c.env.opts.Entry = c.env.entryOf(c.sources)
+	}
+	c.env.checkEntry()
+	if errors, _ := data.CountProblems(c.env.errors); errors > 0 {
+		return c.env.errors
+	}
+	c.env.GenerateCode(output, false)
+	return c.env.errors
+}
+
+// The module with the main function of the program
+func (c *Compiler) Entry() string {
+	return c.env.opts.Entry
+}
+
+// The directory of the main package of the program, relative to the output
+func (c *Compiler) EntryPackage() string {
+	return "./" + filepath.ToSlash(packageDir(c.env.opts.Entry))
+}
+
+// Returns a demangler for the panics and stack traces of the program
+func (c *Compiler) Demangler() *Demangler {
+	return NewDemangler(c.env.goModule(), c.env.opts.Entry)
+}
+
 func (c *Compiler) Modules() map[string]typechecker.FullModuleEnv {
 	return c.env.modules
 }
@@ -51,6 +102,20 @@ func (c *Compiler) Errors() []data.CompilerProblem {
 	return c.env.errors
 }
 
+// Returns the text of a source file.
+// Sources read by the compiler are kept in memory,
+// anything else is read from disk.
+func (c *Compiler) SourceText(path string) (string, bool) {
+	if text, has := c.env.sources[path]; has {
+		return text, true
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(text), true
+}
+
 type Source struct {
 	Path string
 	Str  string
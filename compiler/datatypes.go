@@ -0,0 +1,347 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+// Data types with a single constructor are a struct with the name of the type.
+// Data types with more constructors are a sealed interface implemented
+// by one struct per constructor.
+// Every constructor also gets a curried function that creates it.
+//
+// The arguments of constructors have no names in novah, so the fields of their structs
+// are named after the types of the arguments, numbered when a type repeats:
+// Rect Int Int is Rect{Int1: 2, Int2: 3} and Cons a (List a) is Cons{A: x, List: xs}.
+func (o *Optimizer) convertTypeDecl(d ast.TypeDecl) []ast.GoDecl {
+	params := make([]string, 0, len(d.TyVars))
+	o.typeParams = make(map[string]string, len(d.TyVars))
+	rows := rowVars(d)
+	for _, v := range d.TyVars {
+		if rows.Contains(v) {
+			continue
+		}
+		param := o.freshTypeParam(typeParam(v))
+		o.typeParams[v] = param
+		params = append(params, param)
+	}
+	defer func() { o.typeParams = nil }()
+
+	dataTy := typeWithParams(ast.GoTConst{Name: mangle(d.Name.Val)}, params)
+	if len(d.DataCtors) == 1 {
+		ctor := d.DataCtors[0]
+		strct := ast.GoStruct{
+			Name:       mangle(d.Name.Val),
+			TypeParams: params,
+			Fields:     o.ctorFields(ctor),
+			Pos:        d.Span.Start,
+			Comment:    d.Comment,
+		}
+		return []ast.GoDecl{strct, o.ctorFunc(ctor, strct, dataTy)}
+	}
+
+	marker := ast.InterMethod{Name: "__is_" + mangle(d.Name.Val)}
+	decls := make([]ast.GoDecl, 0, 1+len(d.DataCtors)*3)
+	decls = append(decls, ast.GoInterface{
+		Name:       mangle(d.Name.Val),
+		TypeParams: params,
+		Methods:    []ast.InterMethod{marker},
+		Pos:        d.Span.Start,
+		Comment:    d.Comment,
+	})
+	for _, ctor := range d.DataCtors {
+		pos := ctor.Span.Start
+		strct := ast.GoStruct{
+			Name:       mangle(ctor.Name.Val),
+			TypeParams: params,
+			Fields:     o.ctorFields(ctor),
+			Pos:        pos,
+		}
+		// only the constructors of this type implement the marker method
+		impl := ast.GoFuncDecl{
+			Name:     marker.Name,
+			Receiver: typeWithParams(ast.GoTConst{Name: mangle(ctor.Name.Val)}, params),
+			Pos:      pos,
+		}
+		decls = append(decls, strct, impl, o.ctorFunc(ctor, strct, dataTy))
+	}
+	return decls
+}
+
+func (o *Optimizer) ctorFields(ctor ast.DataCtor) []ast.GoTField {
+	names := ctorFieldNames(ctor.Args)
+	fields := make([]ast.GoTField, 0, len(ctor.Args))
+	for i, arg := range ctor.Args {
+		fields = append(fields, ast.GoTField{Name: names[i], Type: o.convertType(arg)})
+	}
+	return fields
+}
+
+// Returns the names of the fields of the struct of a constructor
+func (o *Optimizer) ctorFieldsOf(ctor ast.Ctor) []string {
+	mod := o.mod
+	if ctor.ModuleName != "" && ctor.ModuleName != o.mod.Name.Val {
+		mod = o.modules[ctor.ModuleName].Ast
+	}
+	for _, decl := range mod.Decls {
+		if d, isType := decl.(ast.TypeDecl); isType {
+			for _, dc := range d.DataCtors {
+				if dc.Name.Val == ctor.Name {
+					return ctorFieldNames(dc.Args)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Generates the curried function that creates the constructor:
+// func NewPair[A any, B any](v0 A) func(B) Pair[A, B]
+func (o *Optimizer) ctorFunc(ctor ast.DataCtor, strct ast.GoStruct, dataTy ast.GoType) ast.GoFuncDecl {
+	pos := ctor.Span.Start
+	lit := ast.GoStructLit{
+		Fields: make([]ast.GoFieldVal, 0, len(strct.Fields)),
+		Type:   typeWithParams(ast.GoTConst{Name: strct.Name}, strct.TypeParams),
+		Pos:    pos,
+	}
+	for i, f := range strct.Fields {
+		lit.Fields = append(lit.Fields, ast.GoFieldVal{Name: f.Name, Exp: ast.GoVar{Name: ctorParam(i), Type: f.Type, Pos: pos}})
+	}
+
+	// build the nested functions from the inside out
+	var body ast.GoExpr = ast.GoReturn{Exp: lit, Pos: pos}
+	ret := dataTy
+	for i := len(strct.Fields) - 1; i > 0; i-- {
+		fun := ast.GoFunc{
+			Args:    []ast.GoParam{{Name: ctorParam(i), Type: strct.Fields[i].Type}},
+			Returns: []ast.GoType{ret},
+			Body:    body,
+			Type:    ast.GoTFunc{Arg: strct.Fields[i].Type, Ret: ret},
+			Pos:     pos,
+		}
+		body = ast.GoReturn{Exp: fun, Pos: pos}
+		ret = fun.Type
+	}
+
+	var params []ast.GoParam
+	if len(strct.Fields) > 0 {
+		params = []ast.GoParam{{Name: ctorParam(0), Type: strct.Fields[0].Type}}
+	}
+	return ast.GoFuncDecl{
+		Name:       ctorFuncName(ctor.Name.Val),
+		TypeParams: strct.TypeParams,
+		Params:     params,
+		Returns:    []ast.GoType{ret},
+		Body:       &body,
+		Pos:        pos,
+	}
+}
+
+// Constructors are references to their constructor function
+// and are coerced like variables.
+// Constructors without fields are called right away.
+func (o *Optimizer) convertCtor(name, module string, typ ast.Type, pos data.Pos) ast.GoExpr {
+	if _, isArr := ast.RealType(typ).(ast.TArrow); !isArr {
+		fn := o.ctorRef(name, module, typ, typ, pos)
+		return ast.GoCall{Fn: fn, Args: []ast.GoExpr{}, Type: o.convertType(typ), Pos: pos}
+	}
+	fn, decl := o.varRef(name, module, true, typ, pos)
+	if decl == nil || !o.needsCoercion(decl, typ) {
+		fn.Type = o.convertType(typ)
+		return fn
+	}
+	return o.coerce(fn, decl, typ)
+}
+
+// A reference to the function of a constructor of type typ.
+// The type arguments come from the instantiated type and are always passed
+// as Go can't infer the type parameters that only appear in the returned function.
+func (o *Optimizer) ctorRef(name, module string, typ, inst ast.Type, pos data.Pos) ast.GoVar {
+	dataTy := inst
+	for {
+		arr, isArr := ast.RealType(dataTy).(ast.TArrow)
+		if !isArr {
+			break
+		}
+		dataTy = arr.Ret
+	}
+
+	var typeArgs []ast.GoType
+	if app, isApp := o.convertType(dataTy).(ast.GoTApp); isApp {
+		typeArgs = app.Args
+	}
+	if typ == nil {
+		typ = inst
+	}
+	var gtyp ast.GoType = o.convertType(typ)
+	if _, isFun := gtyp.(ast.GoTFunc); !isFun {
+		gtyp = ast.GoTFunc{Ret: gtyp}
+	}
+	return ast.GoVar{Name: ctorFuncName(name), Package: module, TypeArgs: typeArgs, Type: gtyp, Pos: pos}
+}
+
+// Returns the declared type of the constructor of the pattern
+// instantiated to the type of the data being matched.
+func (o *Optimizer) ctorFieldsType(ctor ast.CtorP) ast.Type {
+	decl := o.declaredType(ctor.Ctor.Name, ctor.Ctor.ModuleName)
+	if decl == nil {
+		return nil
+	}
+	ret := decl
+	for {
+		arr, isArr := ast.RealType(ret).(ast.TArrow)
+		if !isArr {
+			break
+		}
+		ret = arr.Ret
+	}
+	m := make(map[ast.Id]ast.Type)
+	o.bindTypeVars(ret, ctor.GetType(), m)
+	return substTypeVars(decl, m)
+}
+
+// Returns which type arguments of the type constructor are rows.
+// Records with a row variable are always a novah.Record,
+// so rows are not go type parameters.
+func (o *Optimizer) rowParams(typ ast.Type) []bool {
+	con, isConst := ast.RealType(typ).(ast.TConst)
+	if !isConst {
+		return nil
+	}
+	i := strings.LastIndex(con.Name, ".")
+	if i == -1 {
+		return nil
+	}
+	mod, has := o.modules[con.Name[:i]]
+	if !has {
+		return nil
+	}
+	for _, decl := range mod.Ast.Decls {
+		if d, isType := decl.(ast.TypeDecl); isType && d.Name.Val == con.Name[i+1:] {
+			rows := rowVars(d)
+			return data.MapSlice(d.TyVars, rows.Contains)
+		}
+	}
+	return nil
+}
+
+// Returns the type variables of the data type used as the row of a record
+func rowVars(d ast.TypeDecl) data.Set[string] {
+	vars := data.NewSet[string]()
+	var find func(ast.Type)
+	find = func(ty ast.Type) {
+		switch t := ty.(type) {
+		case ast.TRecord:
+			find(t.Row)
+		case ast.TRowExtend:
+			for _, val := range t.Labels.Values() {
+				find(val)
+			}
+			if tail, isConst := t.Row.(ast.TConst); isConst {
+				vars.Add(tail.Name)
+			} else {
+				find(t.Row)
+			}
+		case ast.TApp:
+			for _, arg := range t.Types {
+				find(arg)
+			}
+		case ast.TArrow:
+			for _, arg := range t.Args {
+				find(arg)
+			}
+			find(t.Ret)
+		case ast.TImplicit:
+			find(t.Type)
+		}
+	}
+	for _, ctor := range d.DataCtors {
+		for _, arg := range ctor.Args {
+			find(arg)
+		}
+	}
+	return vars
+}
+
+// The struct type of a constructor given the type of the data it creates
+func ctorStructType(ctor ast.Ctor, dataTy ast.GoType) ast.GoType {
+	con := ast.GoTConst{Name: mangle(ctor.Name), Package: ctor.ModuleName}
+	if app, isApp := dataTy.(ast.GoTApp); isApp {
+		return ast.GoTApp{Type: con, Args: app.Args}
+	}
+	return con
+}
+
+func typeWithParams(typ ast.GoTConst, params []string) ast.GoType {
+	if len(params) == 0 {
+		return typ
+	}
+	args := data.MapSlice(params, func(p string) ast.GoType { return ast.GoTConst{Name: p} })
+	return ast.GoTApp{Type: typ, Args: args}
+}
+
+// The name of the function that creates a constructor
+func ctorFuncName(ctor string) string {
+	return "New" + mangle(ctor)
+}
+
+// The fields of a constructor are named after the types of its arguments:
+// the name of the type, of the applied type or of the type variable.
+// Types used more than once are numbered from 1.
+func ctorFieldNames(args []ast.Type) []string {
+	names := data.MapSlice(args, typeFieldName)
+	counts := make(map[string]int, len(names))
+	for _, name := range names {
+		counts[name]++
+	}
+	taken := data.NewSet(names...)
+	seen := make(map[string]int, len(names))
+	for i, name := range names {
+		if counts[name] == 1 {
+			continue
+		}
+		seen[name]++
+		field := fmt.Sprintf("%s%d", name, seen[name])
+		for taken.Contains(field) {
+			field += "_"
+		}
+		taken.Add(field)
+		names[i] = field
+	}
+	return names
+}
+
+func typeFieldName(typ ast.Type) string {
+	switch t := typ.(type) {
+	case ast.TConst:
+		name := escapeName(t.Name[strings.LastIndex(t.Name, ".")+1:])
+		r, size := utf8.DecodeRuneInString(name)
+		if up := unicode.ToUpper(r); unicode.IsUpper(up) {
+			return string(up) + name[size:]
+		}
+	case ast.TApp:
+		return typeFieldName(t.Type)
+	case ast.TImplicit:
+		return typeFieldName(t.Type)
+	case ast.TRecord:
+		return "Record"
+	case ast.TArrow:
+		return "Func"
+	}
+	return "V"
+}
+
+func ctorParam(i int) string {
+	return fmt.Sprintf("v%d", i)
+}
+
+// Type variables become capitalized Go type parameters
+func typeParam(tyVar string) string {
+	r, size := utf8.DecodeRuneInString(tyVar)
+	return string(unicode.ToUpper(r)) + tyVar[size:]
+}
@@ -0,0 +1,52 @@
+package compiler
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/compiler/typechecker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataTypeCodegen(t *testing.T) {
+	testGolden(t, "datatypes", generateDecls, "adts")
+}
+
+func TestTypeParam(t *testing.T) {
+	assert.Equal(t, "A", typeParam("a"))
+	assert.Equal(t, "Elem", typeParam("elem"))
+	assert.Equal(t, "Ä1", typeParam("ä1"))
+}
+
+func TestCtorFieldNames(t *testing.T) {
+	intTy := ast.TConst{Name: "prim.Int"}
+	list := ast.TApp{Type: ast.TConst{Name: "test.List"}, Types: []ast.Type{ast.TConst{Name: "a"}}}
+	record := ast.TRecord{Row: ast.TRowEmpty{}}
+	fun := ast.TArrow{Args: []ast.Type{intTy}, Ret: intTy}
+
+	assert.Equal(t, []string{"A", "List"}, ctorFieldNames([]ast.Type{ast.TConst{Name: "a"}, list}))
+	assert.Equal(t, []string{"Int1", "Int2"}, ctorFieldNames([]ast.Type{intTy, intTy}))
+	assert.Equal(t, []string{"Int1_", "Int2", "Int1"}, ctorFieldNames([]ast.Type{intTy, intTy, ast.TConst{Name: "test.Int1"}}))
+	assert.Equal(t, []string{"Record", "Func"}, ctorFieldNames([]ast.Type{record, fun}))
+	assert.Equal(t, []string{"Valid_3f_"}, ctorFieldNames([]ast.Type{ast.TConst{Name: "valid?"}}))
+}
+
+var lineComment = regexp.MustCompile(`(?m)^//line .*:\d+:\d+\n`)
+
+// Generates the go code of all the declarations in the module
+// without position information
+func generateDecls(code string, t *testing.T) string {
+	env := compileCode(code, t)
+	pack := NewOptimizer(env.Ast, map[string]typechecker.FullModuleEnv{"test": env}).Convert()
+
+	decls := make([]string, 0, len(pack.Decls))
+	for _, decl := range pack.Decls {
+		gen := NewCodegen(pack)
+		gen.genDecl(decl)
+		decls = append(decls, strings.TrimSpace(gen.sb.String()))
+	}
+	res := strings.Join(decls, "\n\n") + "\n"
+	return linePragma.ReplaceAllString(lineComment.ReplaceAllString(res, ""), "")
+}
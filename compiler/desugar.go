@@ -1239,10 +1239,10 @@ func (d *Desugar) checkShadow(name string, span data.Span) {
 	}
 }
 
-func (d *Desugar) makeError(msg string, span data.Span) data.CompilerProblem {
-	return data.CompilerProblem{Msg: msg, Span: span, Filename: d.smod.SourceName, Module: d.modName, Severity: data.ERROR}
+func (d *Desugar) makeError(msg data.Message, span data.Span) data.CompilerProblem {
+	return data.CompilerProblem{Msg: msg.Text, Code: msg.Code, Span: span, Filename: d.smod.SourceName, Module: d.modName, Severity: data.ERROR}
 }
 
-func (d *Desugar) makeWarn(msg string, span data.Span) data.CompilerProblem {
-	return data.CompilerProblem{Msg: msg, Span: span, Filename: d.smod.SourceName, Module: d.modName, Severity: data.WARN}
+func (d *Desugar) makeWarn(msg data.Message, span data.Span) data.CompilerProblem {
+	return data.CompilerProblem{Msg: msg.Text, Code: msg.Code, Span: span, Filename: d.smod.SourceName, Module: d.modName, Severity: data.WARN}
 }
@@ -3,9 +3,12 @@ package compiler
 import (
 	"bufio"
 	"fmt"
+	"go/token"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/stackoverflow/novah-go/compiler/ast"
 	"github.com/stackoverflow/novah-go/compiler/lexer"
@@ -22,10 +25,12 @@ type Environment struct {
 	opts    Options
 	modules map[string]tc.FullModuleEnv
 	errors  []data.CompilerProblem
+	// the text of every parsed source by path
+	sources map[string]string
 }
 
 func NewEnviroment(opts Options) *Environment {
-	return &Environment{opts: opts, modules: make(map[string]tc.FullModuleEnv), errors: make([]data.CompilerProblem, 0)}
+	return &Environment{opts: opts, modules: make(map[string]tc.FullModuleEnv), errors: make([]data.CompilerProblem, 0), sources: make(map[string]string)}
 }
 
 func (env *Environment) ParseSources(srcs []Source) (map[string]tc.FullModuleEnv, []data.CompilerProblem) {
@@ -37,35 +42,66 @@ func (env *Environment) parseSources(srcs []Source, isStdlib bool) (map[string]t
 	modGraph := data.NewDag[string, ast.SModule](len(srcs))
 
 	alreadySeenPath := data.NewSet[string]()
+	// modules in the order they were parsed
+	parsed := make([]*data.DagNode[string, ast.SModule], 0, len(srcs))
+	addModule := func(mod ast.SModule, path string) {
+		module := mod.Name.Val
+		node := data.NewDagNode(module, mod)
+		if _, has := modMap[module]; has {
+			env.errors = append(env.errors, duplicateError(mod, path))
+		}
+		modMap[module] = node
+		parsed = append(parsed, node)
+	}
+
 	for _, src := range srcs {
 		path := src.Path
-		// TODO: check for duplicate modules
 		// don't parse the same path
 		if alreadySeenPath.Contains(path) {
 			continue
 		}
 		alreadySeenPath.Add(path)
+		addModule(env.parseSource(src), path)
+	}
 
-		if env.opts.Verbose {
-			fmt.Printf("parsing %s\n", path)
-		}
-
-		src.WithReader(func(reader io.Reader) {
-			lex := lexer.New(src.Path, reader)
-			parser := parser.NewParser(lex)
-			mod, errs := parser.ParseFullModule()
-			env.errors = append(env.errors, errs...)
-
-			module := mod.Name.Val
-			node := data.NewDagNode(module, mod)
-			if _, has := modMap[module]; has {
-				env.errors = append(env.errors, duplicateError(mod, path))
+	// load the imported modules that were not given from the source roots
+	missingImports := false
+	for i := 0; i < len(parsed); i++ {
+		mod := parsed[i].Data
+		for _, imp := range mod.Imports {
+			name := imp.Module.Val
+			if _, has := modMap[name]; has {
+				continue
+			}
+			if _, has := env.modules[name]; has {
+				continue
+			}
+			path, found := env.findModule(name)
+			if !found || alreadySeenPath.Contains(path) {
+				env.errors = append(env.errors, importError(mod, imp, data.ModuleNotFound(name)))
+				missingImports = true
+				continue
+			}
+			alreadySeenPath.Add(path)
+			loaded := env.parseSource(Source{Path: path})
+			if loaded.Name.Val == "" {
+				// the module failed to parse
+				missingImports = true
+				continue
 			}
-			modMap[module] = node
-		})
+			if loaded.Name.Val != name {
+				// the module is still reported as not found in the importing module
+				msg := data.ModuleNameMismatch(loaded.Name.Val, name)
+				env.errors = append(env.errors, data.CompilerProblem{Msg: msg.Text, Code: msg.Code, Span: loaded.Name.Span, Filename: path, Module: loaded.Name.Val, Severity: data.ERROR})
+				env.errors = append(env.errors, importError(mod, imp, data.ModuleNotFound(name)))
+				missingImports = true
+				continue
+			}
+			addModule(loaded, path)
+		}
 	}
 
-	if shouldStop(env.errors) {
+	if missingImports || shouldStop(env.errors) {
 		return nil, env.errors
 	}
 
@@ -76,10 +112,12 @@ func (env *Environment) parseSources(srcs []Source, isStdlib bool) (map[string]t
 		return env.modules, nil
 	}
 
-	// add all nodes to the graph
-	modGraph.AddNodes(data.MapValues(modMap)...)
+	// add all nodes to the graph in the order they were parsed
+	// so cycles are always reported the same way
+	nodes := data.FilterSlice(parsed, func(node *data.DagNode[string, ast.SModule]) bool { return modMap[node.Val] == node })
+	modGraph.AddNodes(nodes...)
 	// link all the nodes
-	for _, node := range modMap {
+	for _, node := range nodes {
 		for _, imp := range node.Data.Imports {
 			if other, has := modMap[imp.Module.Val]; has {
 				other.Link(node)
@@ -87,8 +125,10 @@ func (env *Environment) parseSources(srcs []Source, isStdlib bool) (map[string]t
 		}
 	}
 
-	if cycle, hasCycle := modGraph.FindCycle(); hasCycle {
-		env.reportCycle(cycle)
+	if cycles := modGraph.FindCycles(); len(cycles) > 0 {
+		for _, cycle := range cycles {
+			env.reportCycle(cycle)
+		}
 		return nil, env.errors
 	}
 
@@ -129,24 +169,115 @@ func (env *Environment) parseSources(srcs []Source, isStdlib bool) (map[string]t
 			return nil, env.errors
 		}
 
-		env.modules[mod.Name.Val] = tc.FullModuleEnv{Env: menv, Ast: canon, TypeVarsMap: checker.TypeVarMap, Comment: mod.Comment, IsStdlib: isStdlib}
+		aliases := make([]ast.STypeAliasDecl, 0)
+		for _, decl := range mod.Decls {
+			if alias, isAlias := decl.(ast.STypeAliasDecl); isAlias {
+				aliases = append(aliases, alias)
+			}
+		}
+		env.modules[mod.Name.Val] = tc.FullModuleEnv{Env: menv, Ast: canon, Aliases: aliases, TypeVarsMap: checker.TypeVarMap, Comment: mod.Comment, IsStdlib: isStdlib}
 	}
 	return env.modules, nil
 }
 
+// Parses a source file, keeping its text for error reporting.
+func (env *Environment) parseSource(src Source) ast.SModule {
+	if env.opts.Verbose {
+		fmt.Printf("parsing %s\n", src.Path)
+	}
+
+	var mod ast.SModule
+	src.WithReader(func(reader io.Reader) {
+		text, err := io.ReadAll(reader)
+		if err != nil {
+			panic("Could not read file " + src.Path + ": " + err.Error())
+		}
+		env.sources[src.Path] = string(text)
+		lex := lexer.New(src.Path, strings.NewReader(env.sources[src.Path]))
+		parser := parser.NewParser(lex)
+		res, errs := parser.ParseFullModule()
+		env.errors = append(env.errors, errs...)
+		mod = res
+	})
+	return mod
+}
+
+// Returns the name of the module in the source file
+func (env *Environment) moduleOf(path string) string {
+	for name, mod := range env.modules {
+		if mod.Ast.SourceName == path {
+			return name
+		}
+	}
+	return ""
+}
+
+// Finds the file of a module in the source roots:
+// the module data.list is in data/list.novah
+func (env *Environment) findModule(name string) (string, bool) {
+	rel := filepath.Join(strings.Split(name, ".")...) + ".novah"
+	for _, root := range env.opts.SourceRoots {
+		path := filepath.Join(root, rel)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
 // Optimize the AST and generate go code
 func (env *Environment) GenerateCode(output string, dryRun bool) {
+	// modules are generated in order so the output is always the same
+	names := make([]string, 0, len(env.modules))
+	for name := range env.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
 	goasts := make([]ast.GoPackage, 0, len(env.modules))
-	for _, mod := range env.modules {
-		opt := NewOptimizer(mod.Ast)
-		goasts = append(goasts, opt.Convert())
+	for _, name := range names {
+		mod := env.modules[name]
+		opt := NewOptimizer(mod.Ast, env.modules)
+		opt.main = name == env.opts.Entry
+		goast := opt.Convert()
+		goast.GoModule = env.goModule()
+		goasts = append(goasts, goast)
 	}
 
 	if !dryRun {
+		if err := os.MkdirAll(output, os.ModePerm); err != nil {
+			panic("could not create directory " + output)
+		}
+		if err := env.writeGoModule(output); err != nil {
+			panic("could not write go module to " + output + ": " + err.Error())
+		}
+
+		// invalid go code is reported instead of written
+		fset := token.NewFileSet()
+		files := make([]*goFile, 0, len(goasts))
+		codes := make(map[string]string, len(goasts))
+		for _, goast := range goasts {
+			gen := NewCodegen(goast)
+			gen.file = lineFile(filepath.Join(output, packageDir(goast.Module)), goast.SourceName)
+			file, code, problem := parseGo(fset, goast, gen.Run())
+			if problem != nil {
+				env.errors = append(env.errors, *problem)
+				continue
+			}
+			files = append(files, file)
+			codes[goast.Module] = code
+		}
+		for _, problem := range typecheckGo(fset, files, env.goModule()) {
+			env.errors = append(env.errors, problem)
+			delete(codes, problem.Module)
+		}
+
 		for _, goast := range goasts {
-			codegen := NewCodegen(goast)
-			gocode := codegen.Run()
-			path := fmt.Sprintf("%s.go", filepath.Join(output, goast.Name))
+			gocode, valid := codes[goast.Module]
+			if !valid {
+				continue
+			}
+			path := filepath.Join(output, packageDir(goast.Module), packageName(goast.Module)+".go")
 			dir := filepath.Dir(path)
 
 			err := os.MkdirAll(dir, os.ModePerm)
@@ -165,13 +296,20 @@ func (env *Environment) GenerateCode(output string, dryRun bool) {
 				panic("could not write to file " + path)
 			}
 			writer.Flush()
+
+			sm := NewSourceMap(filepath.Base(path), goast.Module, lineFile(dir, goast.SourceName), gocode)
+			if err := sm.Write(path); err != nil {
+				panic("could not write source map for " + path)
+			}
 		}
 	}
 }
 
 func duplicateError(mod ast.SModule, path string) data.CompilerProblem {
+	msg := data.DuplicateModule(mod.Name.Val)
 	return data.CompilerProblem{
-		Msg:      data.DuplicateModule(mod.Name.Val),
+		Msg:      msg.Text,
+		Code:     msg.Code,
 		Span:     mod.Name.Span,
 		Filename: path,
 		Module:   mod.Name.Val,
@@ -179,12 +317,33 @@ func duplicateError(mod ast.SModule, path string) data.CompilerProblem {
 	}
 }
 
-func (env *Environment) reportCycle(nodes []data.DagNode[string, ast.SModule]) {
-	msg := data.CycleFound(data.MapSlice(nodes, func(t data.DagNode[string, ast.SModule]) string { return t.Val }))
-	for _, node := range nodes {
-		mod := node.Data
-		err := data.CompilerProblem{Msg: msg, Span: mod.Span, Filename: mod.SourceName, Module: mod.Name.Val, Severity: data.ERROR}
-		env.errors = append(env.errors, err)
+func importError(mod ast.SModule, imp ast.Import, msg data.Message) data.CompilerProblem {
+	return data.CompilerProblem{Msg: msg.Text, Code: msg.Code, Span: imp.Span, Filename: mod.SourceName, Module: mod.Name.Val, Severity: data.ERROR}
+}
+
+// Reports a cycle at every import that forms it.
+// The nodes of the graph link imported modules to the modules importing them,
+// so the cycle is reversed to follow the imports.
+func (env *Environment) reportCycle(cycle []*data.DagNode[string, ast.SModule]) {
+	mods := append([]*data.DagNode[string, ast.SModule]{cycle[0]}, data.ReverseSlice(cycle[1:])...)
+
+	imports := make([]ast.Import, len(mods))
+	locations := make([]string, len(mods))
+	for i, node := range mods {
+		next := mods[(i+1)%len(mods)].Val
+		for _, imp := range node.Data.Imports {
+			if imp.Module.Val == next {
+				imports[i] = imp
+				break
+			}
+		}
+		start := imports[i].Span.Start
+		locations[i] = fmt.Sprintf("%s:%d:%d", node.Data.SourceName, start.Line, start.Col)
+	}
+
+	msg := data.CycleFound(data.MapSlice(mods, func(n *data.DagNode[string, ast.SModule]) string { return n.Val }), locations)
+	for i, node := range mods {
+		env.errors = append(env.errors, importError(node.Data, imports[i], msg))
 	}
 }
 
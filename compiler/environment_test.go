@@ -0,0 +1,116 @@
+package compiler
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stackoverflow/novah-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+const appCode = `module app
+
+import data.list (size)
+
+pub
+main : Int -> Int
+main x = size x
+`
+
+func TestLoadImportsFromSourceRoots(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app.novah": appCode,
+		"data/list.novah": `module data.list
+
+import data.num
+
+pub
+size : Int -> Int
+size x = ident x
+`,
+		"data/num.novah": `module data.num
+
+pub
+ident : Int -> Int
+ident x = x
+`,
+		// not reachable from app so it's never compiled
+		"broken.novah": "module broken\n\nx : Int\nx = \"a\"\n",
+	})
+
+	c := NewCompiler([]string{filepath.Join(root, "app.novah")}, Options{SourceRoots: []string{root}})
+	mods, _ := c.Compile()
+	assert.Empty(t, c.Errors())
+	assert.Equal(t, 3, len(mods))
+	assert.Equal(t, filepath.Join(root, "data", "list.novah"), mods["data.list"].Ast.SourceName)
+	assert.Equal(t, "Int -> Int", mods["app"].Env.Decls["main"].Type.String())
+}
+
+func TestMissingImport(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"app.novah": appCode})
+
+	c := NewCompiler([]string{filepath.Join(root, "app.novah")}, Options{SourceRoots: []string{root}})
+	c.Compile()
+	errs := c.Errors()
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, data.ModuleNotFound("data.list").Code, errs[0].Code)
+	assert.Equal(t, "app", errs[0].Module)
+	assert.Equal(t, filepath.Join(root, "app.novah"), errs[0].Filename)
+	assert.Equal(t, data.NewSpan2(3, 1, 3, 24), errs[0].Span)
+}
+
+func TestImportedModuleNameMismatch(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app.novah":       appCode,
+		"data/list.novah": "module data.lists\n\npub\nsize : Int -> Int\nsize x = x\n",
+	})
+
+	c := NewCompiler([]string{filepath.Join(root, "app.novah")}, Options{SourceRoots: []string{root}})
+	c.Compile()
+	errs := c.Errors()
+	assert.Equal(t, 2, len(errs))
+	assert.Equal(t, data.ModuleNameMismatch("data.lists", "data.list"), data.Message{Code: errs[0].Code, Text: errs[0].Msg})
+	assert.Equal(t, filepath.Join(root, "data", "list.novah"), errs[0].Filename)
+	assert.Equal(t, data.ModuleNotFound("data.list").Code, errs[1].Code)
+	assert.Equal(t, "app", errs[1].Module)
+}
+
+func TestImportCycles(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.novah": "module a\n\nimport b\n\nx = 1\n",
+		"b.novah": "module b\n\nimport a\nimport c\n\ny = 1\n",
+		"c.novah": "module c\n\nimport d\n\nz = 1\n",
+		"d.novah": "module d\n\n// comment\nimport e\n\nw = 1\n",
+		"e.novah": "module e\n\nimport c\n\nv = 1\n",
+	})
+
+	c := NewCompiler([]string{filepath.Join(root, "a.novah")}, Options{SourceRoots: []string{root}})
+	c.Compile()
+	errs := c.Errors()
+	assert.Equal(t, 5, len(errs))
+
+	path := func(name string) string { return filepath.Join(root, name+".novah") }
+	assert.Equal(t, "Found cycle between modules:\n\n    a -> b -> a\n"+
+		"\n    a imports b at "+path("a")+":3:1"+
+		"\n    b imports a at "+path("b")+":3:1", errs[0].Msg)
+	assert.Equal(t, path("a"), errs[0].Filename)
+	assert.Equal(t, path("b"), errs[1].Filename)
+
+	expected := "Found cycle between modules:\n\n    c -> d -> e -> c\n" +
+		"\n    c imports d at " + path("c") + ":3:1" +
+		"\n    d imports e at " + path("d") + ":4:1" +
+		"\n    e imports c at " + path("e") + ":3:1"
+	assert.Equal(t, expected, errs[2].Msg)
+	assert.Equal(t, data.NewSpan2(3, 1, 3, 9), errs[2].Span)
+	assert.Equal(t, path("c"), errs[2].Filename)
+	assert.Equal(t, data.NewSpan2(4, 1, 4, 9), errs[3].Span)
+	assert.Equal(t, path("d"), errs[3].Filename)
+	assert.Equal(t, path("e"), errs[4].Filename)
+	for _, err := range errs {
+		assert.Equal(t, "N0081", err.Code)
+	}
+}
@@ -0,0 +1,220 @@
+package compiler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stackoverflow/novah-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNonExhaustiveNestedCtors(t *testing.T) {
+	code := `
+module test
+
+type Maybe a = Some a | None
+
+type Either a b = Left a | Right b
+
+f m = case m of
+  Some (Right x) -> x
+  None -> 0`
+
+	errs := typecheckCode(code)
+
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "N0109", errs[0].Code)
+	assert.Equal(t, data.ERROR, errs[0].Severity)
+	assert.Equal(t, "Pattern match is not exhaustive. Cases not matched:\n\n    Some (Left _)", errs[0].Msg)
+}
+
+func TestNonExhaustiveMissingCtors(t *testing.T) {
+	code := `
+module test
+
+type Shape = Circle Int | Square Int | Empty
+
+f s = case s of
+  Square x -> x`
+
+	errs := typecheckCode(code)
+
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Msg, "\n    Circle _\n    Empty")
+}
+
+func TestExhaustiveMatches(t *testing.T) {
+	code := `
+module test
+
+type Maybe a = Some a | None
+
+type Box a = Box a
+
+fromMaybe def m = case m of
+  Some x -> x
+  None -> def
+
+unbox (Box x) = x
+
+both a b = case a, b of
+  true, true -> 1
+  false, _ -> 2
+  _, false -> 3
+
+len l = case l of
+  [] -> 0
+  [_] -> 1
+  [_, _ :: _] -> 2
+
+named m = case m of
+  (Some _) as s -> s
+  None -> None
+
+fields r = case r of
+  { x: 0 } -> 0
+  { x, y: _ } -> x
+
+unit () = 1`
+
+	errs := typecheckCode(code)
+
+	assert.Empty(t, errs)
+}
+
+func TestNonExhaustiveLiterals(t *testing.T) {
+	code := `
+module test
+
+f n = case n of
+  0 -> "zero"
+  1 -> "one"
+
+g a b = case a, b of
+  true, true -> 1
+  false, _ -> 2`
+
+	errs := typecheckCode(code)
+
+	assert.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Msg, "\n    _")
+	assert.Contains(t, errs[1].Msg, "\n    true, false")
+}
+
+func TestNonExhaustiveLists(t *testing.T) {
+	code := `
+module test
+
+type Maybe a = Some a | None
+
+f l = case l of
+  [] -> 0
+  [Some x] -> x
+
+g l = case l of
+  [x :: _] -> x`
+
+	errs := typecheckCode(code)
+
+	assert.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Msg, "\n    [None :: _]")
+	assert.Contains(t, errs[1].Msg, "\n    []")
+}
+
+func TestNonExhaustiveRecords(t *testing.T) {
+	code := `
+module test
+
+type Maybe a = Some a | None
+
+f r = case r of
+  { name: Some n } -> n`
+
+	errs := typecheckCode(code)
+
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Msg, "\n    { name: None }")
+}
+
+func TestGuardsAreNotExhaustive(t *testing.T) {
+	code := `
+module test
+
+type Maybe a = Some a | None
+
+f p m = case m of
+  Some x if p x -> x
+  None -> 0`
+
+	errs := typecheckCode(code)
+
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Msg, "\n    Some _")
+}
+
+func TestRedundantCases(t *testing.T) {
+	code := `
+module test
+
+type Maybe a = Some a | None
+
+f m = case m of
+  Some _ -> 1
+  None -> 0
+  Some 5 -> 2
+
+g n = case n of
+  x -> x
+  0 -> 1
+
+h p m = case m of
+  Some x if p x -> 1
+  Some 0 -> 2
+  _ -> 3
+
+i r = case r of
+  { x } -> x
+  { x: 1 } -> 1`
+
+	errs := typecheckCode(code)
+
+	assert.Len(t, errs, 3)
+	for _, err := range errs {
+		assert.Equal(t, "N0110", err.Code)
+		assert.Equal(t, data.WARN, err.Severity)
+	}
+	assert.Equal(t, 9, errs[0].Span.Start.Line)
+	assert.Equal(t, 13, errs[1].Span.Start.Line)
+	assert.Equal(t, 22, errs[2].Span.Start.Line)
+}
+
+func TestNonExhaustiveImportedCtors(t *testing.T) {
+	shapes := `
+module shapes
+
+pub+
+type Shape = Circle Int | Square Int | Empty`
+	code := `
+module test
+
+import shapes (Shape(Circle))
+
+f s = case s of
+  Circle r -> r`
+
+	errs := typecheckCode(shapes, code)
+
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Msg, "\n    Square _\n    Empty")
+}
+
+// Typechecks the code without generating any go code
+func typecheckCode(codes ...string) []data.CompilerProblem {
+	sources := make([]Source, 0, len(codes))
+	for i, code := range codes {
+		sources = append(sources, Source{Path: fmt.Sprintf("test%d", i), Str: code})
+	}
+	comp := NewCompilerFromSources(sources, Options{})
+	comp.Compile()
+	return comp.Errors()
+}
@@ -0,0 +1,155 @@
+package compiler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/compiler/lexer"
+	"github.com/stackoverflow/novah-go/compiler/parser"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+// Formats a source file in the canonical layout.
+// Sources with parser errors are never formatted
+// and return the errors instead.
+func FormatSource(path, text string) (string, []data.CompilerProblem) {
+	mod, comments, errs := parseSource(path, text)
+	if len(errs) > 0 {
+		return "", errs
+	}
+	formatted := ast.FormatWithComments(mod, comments) + "\n"
+
+	// the formatted code should always mean the same as the source,
+	// if it doesn't the formatter has a bug and we should not touch the file
+	if reason := checkFormatted(path, mod, comments, formatted); reason != "" {
+		err := data.FormatterError(reason)
+		return "", []data.CompilerProblem{{
+			Msg:      err.Text,
+			Code:     err.Code,
+			Span:     mod.Name.Span,
+			Filename: path,
+			Module:   mod.Name.Val,
+			Severity: data.ERROR,
+		}}
+	}
+	return formatted, nil
+}
+
+// Checks that the formatted code parses to the same module with the same comments
+// and that formatting it again doesn't change it.
+// Returns the reason it doesn't, or an empty string.
+func checkFormatted(path string, mod ast.SModule, comments []lexer.Comment, formatted string) string {
+	fmod, fcomments, errs := parseSource(path, formatted)
+	if len(errs) > 0 {
+		return fmt.Sprintf("the formatted code doesn't parse: %s", errs[0].Msg)
+	}
+	if !sameSyntax(reflect.ValueOf(mod), reflect.ValueOf(fmod), false) {
+		return "the formatted code parses to a different module"
+	}
+	lines, flines := commentTexts(comments), commentTexts(fcomments)
+	for i, line := range lines {
+		if i >= len(flines) || flines[i] != line {
+			return fmt.Sprintf("the comment %q is not kept in its place", line)
+		}
+	}
+	if len(flines) > len(lines) {
+		return fmt.Sprintf("the comment %q is duplicated", flines[len(lines)])
+	}
+	if again := ast.FormatWithComments(fmod, fcomments) + "\n"; again != formatted {
+		return "formatting the formatted code changes it"
+	}
+	return ""
+}
+
+func parseSource(path, text string) (ast.SModule, []lexer.Comment, []data.CompilerProblem) {
+	lex := lexer.New(path, strings.NewReader(text))
+	mod, errs := parser.NewParser(lex).ParseFullModule()
+	return mod, lex.Comments, errs
+}
+
+// The lines of all comments: the formatter may
+// join adjacent comments or split them
+func commentTexts(comments []lexer.Comment) []string {
+	lines := make([]string, 0, len(comments))
+	for _, c := range comments {
+		for _, line := range strings.Split(c.Text, "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return lines
+}
+
+var spanType = reflect.TypeOf(data.Span{})
+var commentType = reflect.TypeOf(&lexer.Comment{})
+var exprType = reflect.TypeOf((*ast.SExpr)(nil)).Elem()
+
+// Compares two syntax trees ignoring their positions.
+// The comments inside expressions are also ignored, a comment the lexer didn't attach
+// to the code may be attached to it once formatted, they are compared by their text.
+func sameSyntax(a, b reflect.Value, inExpr bool) bool {
+	if a.Kind() != b.Kind() || a.Type() != b.Type() {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Struct:
+		if a.Type() == spanType {
+			return true
+		}
+		for i := 0; i < a.NumField(); i++ {
+			if !sameSyntax(a.Field(i), b.Field(i), inExpr) {
+				return false
+			}
+		}
+		return true
+	case reflect.Ptr, reflect.Interface:
+		if a.Type() == commentType && inExpr {
+			return true
+		}
+		if a.Type() == exprType {
+			inExpr = true
+		}
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return sameSyntax(a.Elem(), b.Elem(), inExpr)
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !sameSyntax(a.Index(i), b.Index(i), inExpr) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		iter := a.MapRange()
+		for iter.Next() {
+			bval := b.MapIndex(iter.Key())
+			if !bval.IsValid() || !sameSyntax(iter.Value(), bval, inExpr) {
+				return false
+			}
+		}
+		return true
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	default:
+		// functions and channels are not part of the syntax
+		return true
+	}
+}
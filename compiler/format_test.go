@@ -0,0 +1,230 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stackoverflow/novah-go/test"
+)
+
+func TestFormatSource(t *testing.T) {
+	code := `module fmtTest
+
+// a shape
+type Shape = Circle Int | Square Int | Empty
+
+area : Shape -> Int
+area s =
+  case s of
+    Circle r -> r * r * 3
+    Square x -> x * x
+    Empty -> 0
+
+loop x =
+  while x < 10 do
+    println x
+    x
+
+lets x =
+  let y = 1
+  let f a b = a + b
+  f y x
+
+ann = 12 : Int
+`
+	expected := `module fmtTest
+
+// a shape
+type Shape
+  = Circle Int
+  | Square Int
+  | Empty
+
+area : Shape -> Int
+area s =
+  case s of
+    Circle r -> r * r * 3
+    Square x -> x * x
+    Empty -> 0
+
+loop x =
+  while x < 10 do
+    println x
+    x
+
+lets x =
+  let y = 1
+  let f a b =
+    a + b
+  f y x
+
+ann =
+  12 : Int
+`
+	formatted, errs := FormatSource("test.novah", code)
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	test.Equals(t, formatted, expected)
+
+	again, errs := FormatSource("test.novah", formatted)
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	test.Equals(t, again, formatted)
+}
+
+func TestFormatRefusesBrokenSource(t *testing.T) {
+	formatted, errs := FormatSource("test.novah", "module broken\n\nfoo = (\n")
+	if len(errs) == 0 || formatted != "" {
+		t.Errorf("Expected parser errors and no output, got: %s", formatted)
+	}
+}
+
+func TestFormatCommentsInBodies(t *testing.T) {
+	code := `module fmtTest
+
+foo x =
+  // note
+  x + 1
+
+bar x =
+  // first
+  let y = x
+  /* block */
+  y
+
+baz x = case x of
+  // literal
+  1 -> { .y -> x | { y: 1 } }
+  // variable
+  z -> { .y = z | { y: 1 } }
+`
+	expected := `module fmtTest
+
+foo x =
+  // note
+  x + 1
+
+bar x =
+  // first
+  let y = x
+  /* block */
+  y
+
+baz x =
+  case x of
+    // literal
+    1 -> { .y -> x | { y: 1 } }
+    // variable
+    z -> { .y = z | { y: 1 } }
+`
+	formatted, errs := FormatSource("test.novah", code)
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	test.Equals(t, formatted, expected)
+
+	again, errs := FormatSource("test.novah", formatted)
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	test.Equals(t, again, formatted)
+}
+
+func TestFormatDetachedComments(t *testing.T) {
+	code := `// a header
+
+module fmtTest
+
+import a.b
+
+// between imports
+
+import c.d
+
+// a section
+
+/* the doc */
+// of foo
+foo x = x + x // twice
+
+bar x = if x // when x
+  then [1, // one
+    2]
+  else []
+
+// the end
+`
+	expected := `// a header
+
+module fmtTest
+
+import a.b
+
+// between imports
+
+import c.d
+
+// a section
+
+/* the doc */
+// of foo
+foo x =
+  x + x
+
+// twice
+
+bar x =
+  if x
+  // when x
+  // one
+  then [1, 2]
+  else []
+
+// the end
+`
+	formatted, errs := FormatSource("test.novah", code)
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	test.Equals(t, formatted, expected)
+}
+
+func TestFormatTestData(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "test_data", "*.novah"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			code, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, errs := parseSource(file, string(code)); len(errs) > 0 {
+				t.Skip("not a module")
+			}
+			formatted, errs := FormatSource(file, string(code))
+			if len(errs) > 0 {
+				t.Fatalf("Unexpected errors: %v", errs)
+			}
+			again, errs := FormatSource(file, formatted)
+			if len(errs) > 0 {
+				t.Fatalf("Unexpected errors: %v", errs)
+			}
+			test.Equals(t, again, formatted)
+		})
+	}
+}
+
+func TestFormatRefusesToDropComments(t *testing.T) {
+	code := "module fmtTest\n\n// section\n\nfoo x = x\n"
+	mod, comments, errs := parseSource("test.novah", code)
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	reason := checkFormatted("test.novah", mod, comments, "module fmtTest\n\nfoo x = x\n")
+	test.Equals(t, reason, `the comment "section" is not kept in its place`)
+}
@@ -0,0 +1,212 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+// Generalized type variables of top-level functions become Go type parameters.
+// Go has no generic closures or higher kinded types, so the type variables
+// of let bound functions and of type constructors are `any`.
+
+// Declares the type parameters for the generic type variables
+// of the top-level function of type typ and returns their names.
+func (o *Optimizer) declareTypeVars(typ ast.Type) []string {
+	vars := o.genericVars(typ)
+	o.typeVars = make(map[ast.Id]string, len(vars))
+	names := make([]string, 0, len(vars))
+	for i, id := range vars {
+		name := "T"
+		if len(vars) > 1 {
+			name = fmt.Sprintf("T%d", i+1)
+		}
+		name = o.freshTypeParam(name)
+		o.typeVars[id] = name
+		names = append(names, name)
+	}
+	return names
+}
+
+// Type parameters can't shadow the types declared in the module
+// nor the go names of its top-level values.
+func (o *Optimizer) freshTypeParam(name string) string {
+	types := o.modules[o.mod.Name.Val].Env.Types
+	taken := func(name string) bool {
+		if _, has := types[name]; has {
+			return true
+		}
+		for _, ty := range types {
+			if data.InSlice(ty.Ctors, name) {
+				return true
+			}
+		}
+		for _, decl := range o.mod.Decls {
+			if d, isVal := decl.(ast.ValDecl); isVal && o.topLevelName(d.Name.Val, "") == name {
+				return true
+			}
+		}
+		return false
+	}
+	for taken(name) {
+		name += "_"
+	}
+	return name
+}
+
+// Returns true if the variable is a top-level function,
+// which are the only values that can be generic in Go.
+func (o *Optimizer) isTopLevelFunc(name, module string) bool {
+	mod := o.mod
+	if module != "" {
+		env, has := o.modules[module]
+		if !has {
+			return false
+		}
+		mod = env.Ast
+	} else if _, isLocal := o.locals[name]; isLocal {
+		return false
+	}
+	for _, decl := range mod.Decls {
+		if val, isVal := decl.(ast.ValDecl); isVal && val.Name.Val == name {
+			_, isLambda := unwrapAnn(val.Exp).(ast.Lambda)
+			return isLambda
+		}
+	}
+	return false
+}
+
+// Instantiates the generic type `decl` to `inst`.
+// Returns the declared type with the type parameters replaced
+// by their instantiation and the go type arguments.
+// Type variables that don't become type parameters are left alone.
+func (o *Optimizer) instantiate(decl, inst ast.Type) (ast.Type, []ast.GoType) {
+	vars := o.genericVars(decl)
+	if len(vars) == 0 {
+		return decl, nil
+	}
+	m := make(map[ast.Id]ast.Type, len(vars))
+	o.bindTypeVars(decl, inst, m)
+	args := make([]ast.GoType, 0, len(vars))
+	for _, id := range vars {
+		if ty, has := m[id]; has {
+			args = append(args, o.convertType(ty))
+		} else {
+			args = append(args, goAny)
+		}
+	}
+	return substTypeVars(decl, m), args
+}
+
+// Returns the ids of the generic type variables of typ
+// that can become type parameters, in order of appearance.
+// Type variables of rows and type constructors are skipped.
+func (o *Optimizer) genericVars(typ ast.Type) []ast.Id {
+	ids := make([]ast.Id, 0, 2)
+	var find func(ast.Type)
+	find = func(ty ast.Type) {
+		switch t := ty.(type) {
+		case ast.TVar:
+			switch t.Tvar.Tag {
+			case ast.LINK:
+				find(t.Tvar.Type)
+			case ast.GENERIC:
+				if !data.InSlice(ids, t.Tvar.Id) {
+					ids = append(ids, t.Tvar.Id)
+				}
+			}
+		case ast.TApp:
+			if _, isVar := ast.RealType(t.Type).(ast.TVar); !isVar {
+				find(t.Type)
+			}
+			rows := o.rowParams(t.Type)
+			for i, arg := range t.Types {
+				if rows == nil || !rows[i] {
+					find(arg)
+				}
+			}
+		case ast.TArrow:
+			for _, arg := range t.Args {
+				find(arg)
+			}
+			find(t.Ret)
+		case ast.TImplicit:
+			find(t.Type)
+		case ast.TRecord:
+			labels, _ := recordRow(t)
+			for _, ent := range labels {
+				find(ent.Val)
+			}
+		}
+	}
+	find(typ)
+	return ids
+}
+
+// Binds the generic type variables of decl to the types in the same position of inst.
+// Row variables are not bound as rows are not type parameters.
+func (o *Optimizer) bindTypeVars(decl, inst ast.Type, m map[ast.Id]ast.Type) {
+	inst = ast.RealType(inst)
+	switch d := ast.RealType(decl).(type) {
+	case ast.TVar:
+		if _, has := m[d.Tvar.Id]; !has && d.Tvar.Tag == ast.GENERIC {
+			m[d.Tvar.Id] = inst
+		}
+	case ast.TApp:
+		if i, isApp := inst.(ast.TApp); isApp && len(i.Types) == len(d.Types) {
+			rows := o.rowParams(d.Type)
+			for j := range d.Types {
+				if rows == nil || !rows[j] {
+					o.bindTypeVars(d.Types[j], i.Types[j], m)
+				}
+			}
+		}
+	case ast.TArrow:
+		if i, isArr := inst.(ast.TArrow); isArr && len(i.Args) == len(d.Args) {
+			for j := range d.Args {
+				o.bindTypeVars(d.Args[j], i.Args[j], m)
+			}
+			o.bindTypeVars(d.Ret, i.Ret, m)
+		}
+	case ast.TImplicit:
+		if i, isImp := inst.(ast.TImplicit); isImp {
+			o.bindTypeVars(d.Type, i.Type, m)
+		}
+	case ast.TRecord:
+		if i, isRec := inst.(ast.TRecord); isRec {
+			labels, _ := recordRow(d)
+			for _, ent := range labels {
+				if ty, found := findLabel(i, ent.Label); found {
+					o.bindTypeVars(ent.Val, ty, m)
+				}
+			}
+		}
+	}
+}
+
+func substTypeVars(typ ast.Type, m map[ast.Id]ast.Type) ast.Type {
+	subst := func(ty ast.Type) ast.Type { return substTypeVars(ty, m) }
+	switch t := typ.(type) {
+	case ast.TVar:
+		if t.Tvar.Tag == ast.LINK {
+			return subst(t.Tvar.Type)
+		}
+		if ty, has := m[t.Tvar.Id]; has {
+			return ty
+		}
+		return t
+	case ast.TApp:
+		return ast.TApp{Type: subst(t.Type), Types: data.MapSlice(t.Types, subst), Span: t.Span}
+	case ast.TArrow:
+		return ast.TArrow{Args: data.MapSlice(t.Args, subst), Ret: subst(t.Ret), Span: t.Span}
+	case ast.TImplicit:
+		return ast.TImplicit{Type: subst(t.Type), Span: t.Span}
+	case ast.TRecord:
+		return ast.TRecord{Row: subst(t.Row), Span: t.Span}
+	case ast.TRowExtend:
+		return ast.TRowExtend{Labels: data.LabelMapValues(t.Labels, subst), Row: subst(t.Row), Span: t.Span}
+	default:
+		return typ
+	}
+}
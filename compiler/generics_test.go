@@ -0,0 +1,7 @@
+package compiler
+
+import "testing"
+
+func TestGenericsCodegen(t *testing.T) {
+	testGolden(t, "generics", generateFunctions, "functions", "shadowing")
+}
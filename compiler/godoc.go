@@ -0,0 +1,170 @@
+package compiler
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/stackoverflow/novah-go/compiler/lexer"
+)
+
+// Novah comments are written to the generated code as go doc comments,
+// so `go doc` and the editors show them for the generated packages.
+// The markdown of the comments is converted to the go doc syntax:
+//
+//   - fenced code blocks become indented code blocks
+//   - headings of every level become go headings
+//   - links become doc links with their definitions at the end of the comment
+//   - list items are indented, as go doc only has indented lists
+//
+// Other markdown is kept as it is.
+
+var mdHeadingRegex = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*$`)
+var mdListItemRegex = regexp.MustCompile(`^([-*+]|\d+[.)])\s`)
+var mdLinkRegex = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+
+// Returns the lines of the go doc comment, without the slashes.
+// Returns nil if the comment is empty.
+func goDocLines(comment *lexer.Comment) []string {
+	if comment == nil {
+		return nil
+	}
+	lines := commentLines(comment)
+	doc := make([]string, 0, len(lines))
+	blank := func() {
+		if len(doc) > 0 && doc[len(doc)-1] != "" {
+			doc = append(doc, "")
+		}
+	}
+	links := make([]string, 0)
+	seen := make(map[string]bool)
+	docLinks := func(line string) string {
+		return mdLinkRegex.ReplaceAllStringFunc(line, func(link string) string {
+			m := mdLinkRegex.FindStringSubmatch(link)
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				links = append(links, "["+m[1]+"]: "+m[2])
+			}
+			return "[" + m[1] + "]"
+		})
+	}
+
+	inCode, inList := false, false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if inList && line != "" && line == trimmed && doc[len(doc)-1] == "" && !mdListItemRegex.MatchString(trimmed) {
+			// an unindented line after a blank line ends the list
+			inList = false
+		}
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			inCode, inList = !inCode, false
+			blank()
+		case inCode:
+			if trimmed == "" {
+				doc = append(doc, "")
+			} else {
+				doc = append(doc, "\t"+line)
+			}
+		case mdHeadingRegex.MatchString(trimmed):
+			inList = false
+			blank()
+			doc = append(doc, "# "+mdHeadingRegex.FindStringSubmatch(trimmed)[1], "")
+		case mdListItemRegex.MatchString(trimmed):
+			if !inList {
+				blank()
+				inList = true
+			}
+			doc = append(doc, "  "+docLinks(line))
+		case inList && trimmed != "":
+			// the continuation of an item
+			doc = append(doc, "  "+docLinks(line))
+		default:
+			doc = append(doc, docLinks(line))
+		}
+	}
+	if len(links) > 0 {
+		blank()
+		doc = append(doc, links...)
+	}
+	for len(doc) > 0 && doc[len(doc)-1] == "" {
+		doc = doc[:len(doc)-1]
+	}
+	if len(doc) == 0 {
+		return nil
+	}
+	return doc
+}
+
+// Returns the lines of the comment without the decorations of block comments,
+// the common indentation and the surrounding blank lines.
+func commentLines(comment *lexer.Comment) []string {
+	text := strings.ReplaceAll(comment.Text, "\r\n", "\n")
+	if comment.IsMulti {
+		// /** doc comments */
+		text = strings.TrimPrefix(text, "*")
+	}
+	lines := strings.Split(text, "\n")
+	if comment.IsMulti && starred(lines) {
+		for i, line := range lines {
+			if trimmed := strings.TrimSpace(line); trimmed != "" {
+				lines[i] = strings.TrimPrefix(trimmed, "*")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == -1 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+			continue
+		}
+		lines[i] = strings.TrimRight(line[indent:], " \t")
+	}
+	return lines
+}
+
+// Block comments with every line after the first starting with *
+func starred(lines []string) bool {
+	found := false
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "*") {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// The go doc comment of a declaration or package, ending with a new line
+func goDoc(comment *lexer.Comment) string {
+	lines := goDocLines(comment)
+	var sb strings.Builder
+	for _, line := range lines {
+		if line == "" {
+			sb.WriteString("//\n")
+		} else {
+			// the space also keeps comments from becoming go directives
+			sb.WriteString("// " + line + "\n")
+		}
+	}
+	return sb.String()
+}
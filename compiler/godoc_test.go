@@ -0,0 +1,107 @@
+package compiler
+
+import (
+	goast "go/ast"
+	"go/doc/comment"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stackoverflow/novah-go/compiler/lexer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoDoc(t *testing.T) {
+	tests := []struct {
+		comment  lexer.Comment
+		expected string
+	}{
+		{lexer.Comment{Text: " Returns the size."}, "// Returns the size.\n"},
+		{lexer.Comment{Text: " first\n\n second"}, "// first\n//\n// second\n"},
+		{lexer.Comment{Text: "line comment"}, "// line comment\n"},
+		{lexer.Comment{Text: "\n  Multi\n    line\n", IsMulti: true}, "// Multi\n//   line\n"},
+		{lexer.Comment{Text: "*\n * Starred\n * doc\n ", IsMulti: true}, "// Starred\n// doc\n"},
+		{lexer.Comment{Text: " ## Usage\n Call it:\n ```novah\n size [1, 2]\n ```\n Done."},
+			"// # Usage\n//\n// Call it:\n//\n// \tsize [1, 2]\n//\n// Done.\n"},
+		{lexer.Comment{Text: " See [novah](https://novah-lang.org) and [novah](https://novah-lang.org)."},
+			"// See [novah] and [novah].\n//\n// [novah]: https://novah-lang.org\n"},
+		{lexer.Comment{Text: " Shapes:\n - square\n - rect with\n   two sides\n\n Done."},
+			"// Shapes:\n//\n//   - square\n//   - rect with\n//     two sides\n//\n// Done.\n"},
+		{lexer.Comment{Text: " Order:\n 1. [first](https://a.com)\n 2) second"},
+			"// Order:\n//\n//   1. [first]\n//   2) second\n//\n// [first]: https://a.com\n"},
+		{lexer.Comment{Text: "   \n  ", IsMulti: true}, ""},
+	}
+	for _, test := range tests {
+		cmt := test.comment
+		assert.Equal(t, test.expected, goDoc(&cmt), test.comment.Text)
+	}
+	assert.Equal(t, "", goDoc(nil))
+}
+
+func TestGoDocLists(t *testing.T) {
+	var p comment.Parser
+	var pr comment.Printer
+
+	cmt := lexer.Comment{Text: " Steps:\n - parse\n - check\n   the types\n\n Done."}
+	doc := p.Parse(strings.Join(goDocLines(&cmt), "\n"))
+	assert.Len(t, doc.Content, 3)
+	list, isList := doc.Content[1].(*comment.List)
+	assert.True(t, isList)
+	assert.Len(t, list.Items, 2)
+	assert.Equal(t, "Steps:\n\n  - parse\n  - check the types\n\nDone.\n", string(pr.Text(doc)))
+
+	cmt = lexer.Comment{Text: " Order:\n 1. one\n 2. two"}
+	doc = p.Parse(strings.Join(goDocLines(&cmt), "\n"))
+	assert.Len(t, doc.Content, 2)
+	list, isList = doc.Content[1].(*comment.List)
+	assert.True(t, isList)
+	assert.Equal(t, "2", list.Items[1].Number)
+}
+
+func TestDocComments(t *testing.T) {
+	root, output := t.TempDir(), t.TempDir()
+	writeFiles(t, root, map[string]string{"data/shapes.novah": `// Shapes and their sizes.
+module data.shapes
+
+/**
+ * A shape with its sides.
+ */
+pub+
+type Shape = Square Int | Rect Int Int
+
+// The size of the shape.
+// See [sizes](https://example.com/sizes).
+pub
+size : Shape -> Int
+size s = case s of
+  Square x -> x
+  Rect x _ -> x
+`})
+
+	c := NewCompiler([]string{filepath.Join(root, "data", "shapes.novah")}, Options{})
+	assert.Empty(t, c.Run(output, false))
+
+	path := filepath.Join(output, "data", "shapes", "shapes.go")
+	code, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(code), "// Code generated by novah from the module data.shapes. DO NOT EDIT.\n\n")
+
+	file, err := parser.ParseFile(token.NewFileSet(), path, code, parser.ParseComments)
+	assert.Nil(t, err)
+	assert.Equal(t, "Shapes and their sizes.\n", file.Doc.Text())
+
+	docs := make(map[string]string)
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *goast.FuncDecl:
+			docs[d.Name.Name] = d.Doc.Text()
+		case *goast.GenDecl:
+			docs[d.Specs[0].(*goast.TypeSpec).Name.Name] = d.Doc.Text()
+		}
+	}
+	assert.Equal(t, "A shape with its sides.\n", docs["Shape"])
+	assert.Equal(t, "The size of the shape.\nSee [sizes].\n\n[sizes]: https://example.com/sizes\n", docs["Size"])
+}
@@ -10,9 +10,9 @@ import (
 
 func resolveImports(mod *ast.SModule, mods map[string]tc.FullModuleEnv, env *tc.Env) []data.CompilerProblem {
 
-	makeError := func(span data.Span, sev data.Severity) func(string) data.CompilerProblem {
-		return func(msg string) data.CompilerProblem {
-			return data.CompilerProblem{Msg: msg, Span: span, Filename: mod.SourceName, Module: mod.Name.Val, Severity: sev}
+	makeError := func(span data.Span, sev data.Severity) func(data.Message) data.CompilerProblem {
+		return func(msg data.Message) data.CompilerProblem {
+			return data.CompilerProblem{Msg: msg.Text, Code: msg.Code, Span: span, Filename: mod.SourceName, Module: mod.Name.Val, Severity: sev}
 		}
 	}
 
@@ -36,7 +36,7 @@ func resolveImports(mod *ast.SModule, mods map[string]tc.FullModuleEnv, env *tc.
 			for name, ty := range m.Types {
 				if ty.Visibility == ast.PUBLIC {
 					resolved[fmt.Sprintf("%s%s", alias, name)] = mname
-					env.ExtendType(fmt.Sprintf("%s.%s", mname, name), ty.Type)
+					importType(env, mname, name, ty, m)
 				}
 			}
 			for name, d := range m.Decls {
@@ -53,6 +53,30 @@ func resolveImports(mod *ast.SModule, mods map[string]tc.FullModuleEnv, env *tc.
 			}
 		}
 
+		// a plain import brings every public declaration into scope
+		if imp.Alias == "" && len(imp.Defs) == 0 {
+			for name, ty := range m.Types {
+				if ty.Visibility == ast.PUBLIC {
+					resolved[name] = mname
+					importType(env, mname, name, ty, m)
+				}
+			}
+			for name, d := range m.Decls {
+				if d.Visibility == ast.PUBLIC {
+					resolved[name] = mname
+					env.Extend(fmt.Sprintf("%s.%s", mname, name), d.Type)
+					if d.IsInstance {
+						env.ExtendInstance(fmt.Sprintf("%s.%s", mname, name), d.Type, false)
+					}
+				}
+			}
+			for _, ta := range typealiases {
+				if ta.Visibility == ast.PUBLIC {
+					resolvedTypealias = append(resolvedTypealias, ta)
+				}
+			}
+		}
+
 		for _, ref := range imp.Defs {
 			refname := ref.Name.Val
 			if ref.Tag == ast.VAR {
@@ -90,7 +114,7 @@ func resolveImports(mod *ast.SModule, mods map[string]tc.FullModuleEnv, env *tc.
 					errors = append(errors, mkError(data.CannotImportInModule(fmt.Sprintf("type %s", refname), mname)))
 					continue
 				}
-				env.ExtendType(fmt.Sprintf("%s.%s", mname, refname), declRef.Type)
+				importType(env, mname, refname, declRef, m)
 				resolved[refname] = mname
 				if ref.All {
 					// import all constructors
@@ -128,6 +152,13 @@ func resolveImports(mod *ast.SModule, mods map[string]tc.FullModuleEnv, env *tc.
 	return errors
 }
 
+// Adds an imported type and all its constructors to the environment
+func importType(env *tc.Env, mname, name string, ref tc.TypeDeclRef, m tc.ModuleEnv) {
+	fullname := fmt.Sprintf("%s.%s", mname, name)
+	env.ExtendType(fullname, ref.Type)
+	env.ExtendCtors(fullname, m.TypeCtors(ref))
+}
+
 func getTypealiases(name string, mods map[string]tc.FullModuleEnv) map[string]ast.STypeAliasDecl {
 	mod, found := mods[name]
 	m := make(map[string]ast.STypeAliasDecl)
@@ -0,0 +1,7 @@
+package compiler
+
+import "testing"
+
+func TestInitCodegen(t *testing.T) {
+	testGolden(t, "init", generateDecls, "values")
+}
@@ -105,11 +105,14 @@ func (t Token) IsDoubleColon() bool {
 }
 
 type Lexer struct {
-	Name   string
-	buffer *bufio.Reader
-	peeked *rune
-	line   int
-	col    int
+	Name string
+	// every comment scanned in order, including the ones
+	// that are not attached to a token
+	Comments []Comment
+	buffer   *bufio.Reader
+	peeked   *rune
+	line     int
+	col      int
 }
 
 func New(name string, reader io.Reader) *Lexer {
@@ -150,6 +153,7 @@ func (lex *Lexer) Scan() Token {
 		if peeked == '/' {
 			comm := lex.lineComment()
 			span := data.NewSpan2(startLine, startCol, lex.line, lex.col)
+			lex.Comments = append(lex.Comments, Comment{comm, span, false})
 			lex.consumeWhiteSpace()
 			nex := lex.Scan()
 			nextComm := nex.Comment
@@ -170,6 +174,7 @@ func (lex *Lexer) Scan() Token {
 		if peeked == '*' {
 			comm := lex.multilineComment()
 			span := data.NewSpan2(startLine, startCol, lex.line, lex.col)
+			lex.Comments = append(lex.Comments, Comment{comm, span, true})
 			lex.consumeWhiteSpace()
 			nex := lex.Scan()
 			// if there's a blank line between the comment the definition don't add
@@ -205,7 +210,7 @@ func (lex *Lexer) Scan() Token {
 	case '#':
 		{
 			switch lex.peekNoErr() {
-			case '}':
+			case '{':
 				{
 					lex.next()
 					token = Token{Type: SETBRACKET}
@@ -263,7 +268,7 @@ func (lex *Lexer) Scan() Token {
 			} else if validIdentifierStart(c) {
 				token = lex.ident(&c)
 			} else {
-				lex.lexError("Unexpected Identifier:: " + string(c))
+				lex.lexError(data.UnexpectedCharacter(string(c)))
 			}
 		}
 	}
@@ -292,7 +297,7 @@ func (lex *Lexer) ident(init *rune) Token {
 	str := sb.String()
 	switch str {
 	case "":
-		lex.lexError("Identifiers cannot be empty")
+		lex.lexError(data.EMPTY_IDENTIFIER)
 	case "true":
 		return Token{Type: BOOL, Value: true, Text: &str}
 	case "false":
@@ -360,12 +365,12 @@ func (lex *Lexer) ident(init *rune) Token {
 	}
 
 	if len(str) >= 2 && strings.Contains(str, "__") {
-		lex.lexError(fmt.Sprintf("Identifiers cannot have a double underscore (__)."))
+		lex.lexError(data.DOUBLE_UNDERSCORE)
 	}
 
 	if IsUpper(str) {
 		if hasOpEnd {
-			lex.lexError("Upper case identifiers cannot end with `?` or `!`.")
+			lex.lexError(data.UPPER_IDENT_END)
 		}
 		return Token{Type: UPPERIDENT, Value: str, Text: &str}
 	}
@@ -417,7 +422,7 @@ func (lex *Lexer) backtickOperator() string {
 	c := lex.next()
 	for c != '`' {
 		if escapes[c] {
-			lex.lexError("Invalid character in backtick operator.")
+			lex.lexError(data.BACKTICK_OPERATOR)
 		}
 		sb.WriteRune(c)
 		c = lex.next()
@@ -454,7 +459,7 @@ func (lex *Lexer) number(init rune, neg bool) Token {
 	}
 
 	if err != nil {
-		lex.lexError("Invalid number " + str)
+		lex.lexError(data.InvalidNumber(str))
 	}
 	return Token{Type: tk, Value: v, Text: &str}
 }
@@ -474,7 +479,7 @@ func (lex *Lexer) string() Token {
 
 	for c != '"' {
 		if c == '\n' {
-			lex.lexError("Newline is not allowed inside strings.")
+			lex.lexError(data.STRING_NEWLINE)
 		}
 		if c == '\\' {
 			esc, str := lex.readEscapes()
@@ -519,7 +524,7 @@ func (lex *Lexer) char() Token {
 	}
 	n := lex.next()
 	if n != '\'' {
-		lex.lexError("Expected ' after char literal")
+		lex.lexError(data.CHAR_QUOTE)
 	}
 	return token
 }
@@ -560,7 +565,7 @@ var validEscapes map[rune]bool = map[rune]bool{'t': true, '\\': true, 'n': true,
 func (lex *Lexer) readEscapes() (rune, string) {
 	c := lex.next()
 	if !validEscapes[c] {
-		lex.lexError("Unexpected UTF-8 escape character")
+		lex.lexError(data.ESCAPE_CHARACTER)
 	}
 	switch c {
 	case 'n':
@@ -583,7 +588,7 @@ func (lex *Lexer) readEscapes() (rune, string) {
 			u3 := lex.accept(chars)
 			u4 := lex.accept(chars)
 			if u1 == nil || u2 == nil || u3 == nil || u4 == nil {
-				lex.lexError("Unexpected UTF-8 escape character ")
+				lex.lexError(data.ESCAPE_CHARACTER)
 			}
 			str := fmt.Sprintf("%c%c%c%c", *u1, *u2, *u3, *u4)
 			u, _ := strconv.ParseInt(str, 16, 32)
@@ -645,7 +650,7 @@ func (lex *Lexer) consumeWhiteSpace() {
 	}
 }
 
-func (lex *Lexer) lexError(msg string) {
+func (lex *Lexer) lexError(msg data.Message) {
 	span := data.NewSpan2(lex.line, lex.col, lex.line, lex.col)
 	panic(LexerError{msg, span})
 }
@@ -686,7 +691,7 @@ func (lex *Lexer) advancePos(r rune) rune {
 		lex.col = 1
 	} else {
 		if r == '\t' {
-			lex.col = lex.col + 2
+			lex.col = lex.col + data.TAB_COLUMNS
 		} else {
 			lex.col++
 		}
@@ -744,10 +749,10 @@ var operators map[rune]bool = map[rune]bool{
 }
 
 type LexerError struct {
-	Msg  string
+	Msg  data.Message
 	Span data.Span
 }
 
 func (err LexerError) Error() string {
-	return err.Msg
+	return err.Msg.Text
 }
@@ -38,6 +38,21 @@ func TestComments(t *testing.T) {
 	test.Equals(t, foo.Comment, nil)
 }
 
+func TestAllComments(t *testing.T) {
+	reader, _ := os.Open("../../test_data/comments.novah")
+	defer reader.Close()
+	lexer := New("comments.novah", reader)
+	for !lexer.Scan().IsEOF() {
+	}
+
+	texts := data.MapSlice(lexer.Comments, func(c Comment) string { return c.Text })
+	test.Equals(t, len(texts), 5)
+	test.Equals(t, texts[2], " comments on var declaration work")
+	test.Equals(t, texts[3], " and are concatenated")
+	// comments not attached to a token are also kept
+	test.Equals(t, texts[4], " comments with a empty line are not added")
+}
+
 func TestUTFEscapes(t *testing.T) {
 	tk := lexString(` "bla bla \u0062 a" `)[0]
 
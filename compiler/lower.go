@@ -0,0 +1,263 @@
+// Lowers novah expressions to go statements.
+// Conditionals, lets, do blocks, matches and loops are expressions in novah but statements in go,
+// so they are converted in A-normal form: when they appear where go needs an expression,
+// their statements are emitted before the statement being converted and their value
+// is assigned to a temporary. No closures are created for them.
+package compiler
+
+import (
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+type destKind int
+
+const (
+	// the value is returned
+	destReturn destKind = iota
+	// the value is assigned to a variable
+	destAssign
+	// the value is not used
+	destDiscard
+)
+
+// Where the value of an expression lowered to statements goes
+type dest struct {
+	kind destKind
+	name string
+}
+
+var (
+	returnDest  = dest{kind: destReturn}
+	discardDest = dest{kind: destDiscard}
+)
+
+func assignDest(name string) dest {
+	return dest{kind: destAssign, name: name}
+}
+
+// Converts the statements of a new go block (a function body, a branch or a loop body).
+// The statements emitted while running f come before the statement it returns.
+func (o *Optimizer) block(f func() ast.GoExpr) ast.GoExpr {
+	prevStmts, prevDeclared := o.stmts, o.declared
+	o.stmts, o.declared = make([]ast.GoExpr, 0, 2), data.NewSet[string]()
+	defer func() { o.stmts, o.declared = prevStmts, prevDeclared }()
+
+	if last := f(); last != nil {
+		o.emit(last)
+	}
+	if len(o.stmts) == 1 {
+		return o.stmts[0]
+	}
+	var pos data.Pos
+	if len(o.stmts) > 0 {
+		pos = o.stmts[0].GetPos()
+	}
+	return ast.GoStmts{Exps: o.stmts, Pos: pos}
+}
+
+// The body of a function
+func (o *Optimizer) convertBody(exp ast.Expr) ast.GoExpr {
+	return o.block(func() ast.GoExpr { return o.lower(exp, returnDest) })
+}
+
+// Adds a statement to the block being converted
+func (o *Optimizer) emit(stmt ast.GoExpr) {
+	if stmt != nil {
+		o.stmts = append(o.stmts, stmt)
+	}
+}
+
+// Converts the expression to statements that send its value to d.
+// Returns the last statement, the others are emitted.
+// Returns nil if a discarded expression needs no statement.
+func (o *Optimizer) lower(expr ast.Expr, d dest) ast.GoExpr {
+	switch e := expr.(type) {
+	case ast.Ann:
+		return o.lower(e.Exp, d)
+	case ast.If:
+		{
+			cond := o.convertExpr(e.Cond)
+			then := o.block(func() ast.GoExpr { return o.lower(e.Then, d) })
+			els := o.block(func() ast.GoExpr { return o.lower(e.Else, d) })
+			if isEmptyBlock(els) {
+				els = nil
+			}
+			return ast.GoIf{Cond: cond, Then: then, Else: els, Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
+		}
+	case ast.Let:
+		return o.convertLet(e, func() ast.GoExpr { return o.lower(e.Body, d) })
+	case ast.Do:
+		{
+			last := len(e.Exps) - 1
+			for _, exp := range e.Exps[:last] {
+				o.emit(o.lower(exp, discardDest))
+			}
+			return o.lower(e.Exps[last], d)
+		}
+	case ast.Match:
+		return o.convertMatch(e, d)
+	case ast.While:
+		o.emit(o.convertWhile(e))
+		return o.send(ast.GoUnit{Type: o.convertType(e.Type.Type), Pos: e.Span.Start}, d)
+	default:
+		return o.send(o.convertExpr(expr), d)
+	}
+}
+
+// The statement that sends the value to d
+func (o *Optimizer) send(exp ast.GoExpr, d dest) ast.GoExpr {
+	switch d.kind {
+	case destReturn:
+		return ast.GoReturn{Exp: exp, Pos: exp.GetPos()}
+	case destAssign:
+		return ast.GoSetvar{Name: d.name, Exp: exp, Pos: exp.GetPos()}
+	}
+	switch exp.(type) {
+	case ast.GoCall:
+		return exp
+	case ast.GoUnit, ast.GoNil:
+		return nil
+	default:
+		// the value is still evaluated and the variables used
+		return ast.GoSetvar{Name: "_", Exp: exp, Pos: exp.GetPos()}
+	}
+}
+
+// Lowers a statement expression where go needs an expression:
+// the value is assigned to a new temporary which is returned.
+func (o *Optimizer) hoist(expr ast.Expr) ast.GoExpr {
+	typ := o.convertType(expr.GetType())
+	pos := expr.GetSpan().Start
+	name := o.newTmp()
+	o.emit(ast.GoVarDef{Name: name, Type: typ, Pos: pos})
+	o.emit(o.lower(expr, assignDest(name)))
+	return ast.GoVar{Name: name, Type: typ, Pos: pos}
+}
+
+// Emits the binding of the let and converts the body with f
+func (o *Optimizer) convertLet(e ast.Let, f func() ast.GoExpr) ast.GoExpr {
+	name := e.Def.Binder.Name
+	o.locals[name] = e.Def.Expr.GetType()
+	pos := e.Def.Binder.Span.Start
+	used := usedVars(e.Body).Contains(name)
+	if used && isStmtExpr(e.Def.Expr) {
+		// the statements assign the variable directly
+		varname, restore := o.declare(name)
+		defer restore()
+		o.emit(ast.GoVarDef{Name: varname, Type: o.convertType(e.Def.Expr.GetType()), Pos: pos})
+		o.emit(o.lower(e.Def.Expr, assignDest(varname)))
+		return f()
+	}
+
+	bind := o.convertExpr(e.Def.Expr)
+	if !used {
+		// go doesn't allow unused variables
+		o.emit(ast.GoSetvar{Name: "_", Exp: bind, Pos: pos})
+		return f()
+	}
+
+	varname, restore := o.declare(name)
+	defer restore()
+	o.emit(ast.GoLet{Binder: varname, BindExpr: bind, Type: bind.GetType(), Pos: pos})
+	return f()
+}
+
+// Declares a local variable in the go block being converted and returns its go name,
+// valid until restore is called. Variables from different novah scopes can end up
+// in the same block, so names already declared in it are renamed.
+func (o *Optimizer) declare(name string) (string, func()) {
+	varname := mangle(name)
+	if o.declared.Contains(varname) {
+		varname = o.newTmp()
+	}
+	o.declared.Add(varname)
+
+	prev, hasPrev := o.renames[name]
+	o.renames[name] = varname
+	return varname, func() {
+		if hasPrev {
+			o.renames[name] = prev
+		} else {
+			delete(o.renames, name)
+		}
+	}
+}
+
+// Loops are always statements. Conditions that need statements
+// are checked inside the loop as they run before every iteration.
+func (o *Optimizer) convertWhile(e ast.While) ast.GoExpr {
+	loop := ast.GoWhile{Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
+	body := func() ast.GoExpr {
+		for _, exp := range e.Exps {
+			o.emit(o.lower(exp, discardDest))
+		}
+		return nil
+	}
+	loop.Exps = []ast.GoExpr{o.block(func() ast.GoExpr {
+		cond := o.convertExpr(e.Cond)
+		if len(o.stmts) == 0 {
+			loop.Cond = cond
+			return body()
+		}
+		return ast.GoIf{Cond: cond, Then: o.block(body), Else: ast.GoBreak{Pos: e.Span.Start}, Type: loop.Type, Pos: e.Span.Start}
+	})}
+	return loop
+}
+
+// Converts the operands of an expression in order.
+// When an operand needs statements, the operands before it are bound
+// to temporaries first so they are still evaluated before those statements.
+func (o *Optimizer) convertOperands(exps ...ast.Expr) []ast.GoExpr {
+	res := make([]ast.GoExpr, 0, len(exps))
+	for _, exp := range exps {
+		mark := len(o.stmts)
+		gexp := o.convertExpr(exp)
+		if len(o.stmts) > mark {
+			lets := make([]ast.GoExpr, 0, len(res))
+			for i, prev := range res {
+				if isPureExpr(prev) {
+					continue
+				}
+				name := o.newTmp()
+				lets = append(lets, ast.GoLet{Binder: name, BindExpr: prev, Type: prev.GetType(), Pos: prev.GetPos()})
+				res[i] = ast.GoVar{Name: name, Type: prev.GetType(), Pos: prev.GetPos()}
+			}
+			hoisted := append(lets, o.stmts[mark:]...)
+			o.stmts = append(o.stmts[:mark], hoisted...)
+		}
+		res = append(res, gexp)
+	}
+	return res
+}
+
+// Expressions without side effects that can be evaluated in any order
+func isPureExpr(exp ast.GoExpr) bool {
+	switch e := exp.(type) {
+	case ast.GoVar, ast.GoConst, ast.GoFunc, ast.GoUnit, ast.GoNil:
+		return true
+	case ast.GoField:
+		return isPureExpr(e.Exp)
+	case ast.GoStructLit:
+		return !data.AnySlice(e.Fields, func(f ast.GoFieldVal) bool { return !isPureExpr(f.Exp) })
+	default:
+		return false
+	}
+}
+
+// Expressions that are always converted to go statements
+func isStmtExpr(exp ast.Expr) bool {
+	switch e := exp.(type) {
+	case ast.Ann:
+		return isStmtExpr(e.Exp)
+	case ast.If, ast.Match:
+		return true
+	default:
+		return false
+	}
+}
+
+func isEmptyBlock(exp ast.GoExpr) bool {
+	stmts, isStmts := exp.(ast.GoStmts)
+	return isStmts && len(stmts.Exps) == 0
+}
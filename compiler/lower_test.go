@@ -0,0 +1,76 @@
+package compiler
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLowerCodegen(t *testing.T) {
+	testGolden(t, "lower", generateFunctions, "expressions")
+}
+
+// The benchmarks of the package generated for test_data/lower/hot.novah
+const hotBenchmarks = `package hot
+
+import "testing"
+
+var sink int
+
+func BenchmarkIf(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		sink = clamp(i)
+	}
+}
+
+func BenchmarkLet(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		sink = total(i)
+	}
+}
+
+func BenchmarkDo(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		sink = steps(i)
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	m := NewSome(3)
+	for i := 0; i < b.N; i++ {
+		sink = orZero(m)
+	}
+}
+`
+
+var benchResult = regexp.MustCompile(`(?m)^(Benchmark\w+)(?:-\d+)?\s.*\s(\d+) allocs/op$`)
+
+// Runs the benchmarks against the generated code: conditionals, lets, do blocks
+// and matches used as values should not allocate.
+func TestLoweredCodeDoesNotAllocate(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil || testing.Short() {
+		t.Skip("the go tool is needed to run the benchmarks")
+	}
+	output := t.TempDir()
+	c := NewCompiler([]string{filepath.Join("..", "test_data", "lower", "hot.novah")}, Options{})
+	assert.Empty(t, c.Run(output, false))
+	writeFiles(t, output, map[string]string{filepath.Join("hot", "hot_test.go"): hotBenchmarks})
+
+	cmd := exec.Command("go", "test", "-run", "^$", "-bench", ".", "-benchmem", "-benchtime", "1000x", "./hot")
+	cmd.Dir = output
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("could not run the benchmarks: %s\n%s", err, strings.TrimSpace(string(out)))
+	}
+	results := benchResult.FindAllStringSubmatch(string(out), -1)
+	assert.Equal(t, 4, len(results), string(out))
+	for _, res := range results {
+		assert.Equal(t, "0", res[2], res[1])
+	}
+}
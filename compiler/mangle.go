@@ -0,0 +1,337 @@
+package compiler
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+// Novah names are mangled to valid go identifiers in a reversible way:
+//
+//   - runes not allowed in go identifiers are escaped as their hex code between underscores:
+//     valid? is valid_3f_ and <|> is _3c__7c__3e_
+//   - an underscore followed by a digit is escaped as _5f_, so `_` and a digit always start an escape
+//   - names reserved by go or by the generated code have their first rune escaped: map is _6d_ap
+//
+// Public values are exported by capitalizing their first rune: pub size is Size.
+// Values that can't be capitalized or that would clash with a type, a constructor
+// or a constructor function of the module are prefixed with X_0_ instead:
+// pub (<|>) is X_0__3c__7c__3e_.
+
+// The prefix of exported names that can't be capitalized.
+// Escapes never start with _0_, so it can't clash with a capitalized name.
+const EXPORT_PREFIX = "X_0_"
+
+var goKeywords = data.NewSet("break", "case", "chan", "const", "continue", "default", "defer",
+	"else", "fallthrough", "for", "func", "go", "goto", "if", "import", "interface", "map",
+	"package", "range", "return", "select", "struct", "switch", "type", "var")
+
+var goPredeclared = data.NewSet("any", "append", "bool", "byte", "cap", "clear", "close",
+	"comparable", "complex", "complex64", "complex128", "copy", "delete", "error", "false",
+	"float32", "float64", "imag", "int", "int8", "int16", "int32", "int64", "iota", "len",
+	"make", "max", "min", "new", "nil", "panic", "print", "println", "real", "recover", "rune",
+	"string", "true", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr")
+
+// Returns true if the name can't be used by novah values:
+// go keywords, predeclared identifiers used by the generated code,
+// the special main and init functions, the runtime package
+// and the names generated by the compiler.
+func isReserved(name string) bool {
+	if goKeywords.Contains(name) || goPredeclared.Contains(name) {
+		return true
+	}
+	if name == "init" || name == "main" || name == RUNTIME_PACKAGE || strings.HasPrefix(name, "__is_") {
+		return true
+	}
+	if strings.HasPrefix(name, "__m") {
+		_, err := strconv.Atoi(name[3:])
+		return err == nil
+	}
+	return false
+}
+
+// Mangles a novah name to a go identifier
+func mangle(name string) string {
+	mangled := escapeName(name)
+	if isReserved(mangled) {
+		r, size := utf8.DecodeRuneInString(name)
+		return escapeRune(r) + name[size:]
+	}
+	return mangled
+}
+
+// Returns the exported go name of a public value.
+// taken are the names of the types and constructors of the module.
+func exportName(name string, taken data.Set[string]) string {
+	r, size := utf8.DecodeRuneInString(name)
+	up := unicode.ToUpper(r)
+	if unicode.IsLower(r) && unicode.IsUpper(up) && unicode.ToLower(up) == r {
+		exported := string(up) + escapeName(name[size:])
+		if !taken.Contains(exported) && !isCtorFuncName(exported) {
+			return exported
+		}
+	}
+	return EXPORT_PREFIX + escapeName(name)
+}
+
+func escapeName(name string) string {
+	var sb strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '_':
+			if i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
+				sb.WriteString(escapeRune(r))
+			} else {
+				sb.WriteRune(r)
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		default:
+			sb.WriteString(escapeRune(r))
+		}
+	}
+	return sb.String()
+}
+
+// Escapes always start with a digit so they can be told apart from
+// underscores followed by letters.
+func escapeRune(r rune) string {
+	hex := fmt.Sprintf("%x", r)
+	if hex[0] > '9' {
+		hex = "0" + hex
+	}
+	return "_" + hex + "_"
+}
+
+// Reverts the escapes of a mangled name
+func unmangle(name string) string {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '_' && i+1 < len(name) && name[i+1] >= '0' && name[i+1] <= '9' {
+			if end := strings.IndexByte(name[i+1:], '_'); end != -1 {
+				if r, err := strconv.ParseInt(name[i+1:i+1+end], 16, 32); err == nil {
+					sb.WriteRune(rune(r))
+					i += end + 1
+					continue
+				}
+			}
+		}
+		sb.WriteByte(name[i])
+	}
+	return sb.String()
+}
+
+// Constructor functions are called New followed by the constructor
+func isCtorFuncName(name string) bool {
+	r, _ := utf8.DecodeRuneInString(strings.TrimPrefix(name, "New"))
+	return strings.HasPrefix(name, "New") && unicode.IsUpper(r)
+}
+
+// Returns the novah name of a go function or value generated by the compiler
+func Demangle(name string) string {
+	if strings.HasPrefix(name, EXPORT_PREFIX) {
+		return unmangle(name[len(EXPORT_PREFIX):])
+	}
+	if isCtorFuncName(name) {
+		return unmangle(name[3:])
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	if unicode.IsUpper(r) {
+		return string(unicode.ToLower(r)) + unmangle(name[size:])
+	}
+	return unmangle(name)
+}
+
+// Returns the go name of a top-level value of a module
+func (o *Optimizer) topLevelName(name, module string) string {
+	mod := o.mod
+	if module != "" && module != o.mod.Name.Val {
+		mod = o.modules[module].Ast
+	}
+	for _, decl := range mod.Decls {
+		if d, isVal := decl.(ast.ValDecl); isVal && d.Name.Val == name {
+			if d.IsPublic() {
+				return exportName(name, typeNames(mod))
+			}
+			break
+		}
+	}
+	return mangle(name)
+}
+
+// Returns the go name of a variable: local variables are only mangled
+func (o *Optimizer) valueName(name, module string) string {
+	if module == "" && !o.isTopLevel(name) {
+		if renamed, has := o.renames[name]; has {
+			return renamed
+		}
+		return mangle(name)
+	}
+	return o.topLevelName(name, module)
+}
+
+// Returns true if name is a top-level value of this module.
+// Local variables can't shadow them.
+func (o *Optimizer) isTopLevel(name string) bool {
+	for _, decl := range o.mod.Decls {
+		if d, isVal := decl.(ast.ValDecl); isVal && d.Name.Val == name {
+			return true
+		}
+	}
+	return false
+}
+
+// The go names of the types and constructors of a module
+func typeNames(mod ast.Module) data.Set[string] {
+	names := data.NewSet[string]()
+	for _, decl := range mod.Decls {
+		if d, isType := decl.(ast.TypeDecl); isType {
+			names.Add(mangle(d.Name.Val))
+			for _, ctor := range d.DataCtors {
+				names.Add(mangle(ctor.Name.Val))
+			}
+		}
+	}
+	return names
+}
+
+// Rewrites the go symbols of a program in panics and stack traces to novah names
+type Demangler struct {
+	goModule string
+	entry    string
+	// the source maps of the go files by path, nil if the file has none
+	maps map[string]*SourceMap
+}
+
+func NewDemangler(goModule, entry string) *Demangler {
+	if goModule == "" {
+		goModule = DEFAULT_GO_MODULE
+	}
+	return &Demangler{goModule: goModule, entry: entry, maps: make(map[string]*SourceMap)}
+}
+
+// Rewrites the function or the go file of a stack trace line.
+// Other lines are returned unchanged.
+func (d *Demangler) Line(line string) string {
+	if prefix, sym, found := strings.Cut(line, "created by "); found && prefix == "" {
+		sym, rest, hasRest := strings.Cut(sym, " ")
+		if name, ok := d.Symbol(sym); ok {
+			if hasRest {
+				name += " " + rest
+			}
+			return "created by " + name
+		}
+		return line
+	}
+	if strings.HasPrefix(line, "\t") {
+		return d.fileLine(line)
+	}
+	if !strings.HasSuffix(line, ")") {
+		return line
+	}
+	args := strings.LastIndex(line, "(")
+	if args <= 0 {
+		return line
+	}
+	if name, ok := d.Symbol(line[:args]); ok {
+		return name + line[args:]
+	}
+	return line
+}
+
+// Rewrites a file line like "\t/output/data/list/list.go:20 +0x1d"
+// to the novah source if the go file has a source map.
+// Code compiled with the line directives already reports the novah sources.
+func (d *Demangler) fileLine(line string) string {
+	loc, rest, _ := strings.Cut(line[1:], " ")
+	colon := strings.LastIndex(loc, ":")
+	if colon == -1 || !strings.HasSuffix(loc[:colon], ".go") {
+		return line
+	}
+	goLine, err := strconv.Atoi(loc[colon+1:])
+	if err != nil {
+		return line
+	}
+	path := loc[:colon]
+	sm, has := d.maps[path]
+	if !has {
+		sm, _ = ReadSourceMap(path)
+		d.maps[path] = sm
+	}
+	if sm == nil {
+		return line
+	}
+	novahLine, found := sm.Lookup(goLine)
+	if !found {
+		return line
+	}
+	source := filepath.Join(filepath.Dir(path), filepath.FromSlash(sm.Source))
+	res := "\t" + source + ":" + strconv.Itoa(novahLine)
+	if rest != "" {
+		res += " " + rest
+	}
+	return res
+}
+
+// Rewrites every function and go file of a stack trace
+func (d *Demangler) Trace(trace string) string {
+	lines := strings.Split(trace, "\n")
+	for i, line := range lines {
+		lines[i] = d.Line(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Returns the novah name of a go function symbol like app/data/list.Size
+// and false if the symbol is not part of a novah module.
+// Closures are reported as lambdas of their function.
+func (d *Demangler) Symbol(sym string) (string, bool) {
+	slash := strings.LastIndex(sym, "/")
+	dot := strings.Index(sym[slash+1:], ".")
+	if dot == -1 {
+		return "", false
+	}
+	dot += slash + 1
+	pkg, fun := sym[:dot], strings.ReplaceAll(sym[dot+1:], "[...]", "")
+
+	var module string
+	if pkg == "main" {
+		module = d.entry
+	} else if path := strings.TrimPrefix(pkg, d.goModule+"/"); path != pkg && path != RUNTIME_PACKAGE {
+		module = strings.ReplaceAll(path, "/", ".")
+	}
+	if module == "" {
+		return "", false
+	}
+
+	parts := strings.Split(fun, ".")
+	first := parts[0]
+	if strings.HasPrefix(first, "(*") {
+		// methods are only generated for constructors
+		return module + "." + unmangle(strings.Trim(first, "(*)")), true
+	}
+	if len(parts) > 1 && !isClosure(parts[1]) {
+		return module + "." + unmangle(first), true
+	}
+	if first == "init" || (pkg == "main" && first == "main") {
+		// generated by the compiler
+		return "", false
+	}
+	name := module + "." + Demangle(first)
+	if len(parts) > 1 {
+		name += " (lambda)"
+	}
+	return name, true
+}
+
+// Closures are named func1, func2, ... or gowrap1, ... after their function
+func isClosure(part string) bool {
+	return strings.HasPrefix(part, "func") || strings.HasPrefix(part, "gowrap") || (part != "" && unicode.IsDigit(rune(part[0])))
+}
@@ -0,0 +1,106 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/stackoverflow/novah-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMangleCodegen(t *testing.T) {
+	testGolden(t, "mangle", generateDecls, "names")
+}
+
+func TestMangle(t *testing.T) {
+	tests := map[string]string{
+		"size":     "size",
+		"valid?":   "valid_3f_",
+		"reset!":   "reset_21_",
+		"<|>":      "_3c__7c__3e_",
+		"map":      "_6d_ap",
+		"string":   "_73_tring",
+		"novah":    "_6e_ovah",
+		"main":     "_6d_ain",
+		"init":     "_69_nit",
+		"__if":     "__if",
+		"__m1":     "_5f__m1",
+		"__var1":   "__var1",
+		"x_1":      "x_5f_1",
+		"a_?":      "a__3f_",
+		"Point":    "Point",
+		"ação":     "ação",
+		"x_0_y":    "x_5f_0_y",
+		"_":        "_",
+		"elseIf":   "elseIf",
+		"__is_Foo": "_5f__is_Foo",
+	}
+	for name, mangled := range tests {
+		assert.Equal(t, mangled, mangle(name), name)
+		assert.Equal(t, name, unmangle(mangled), mangled)
+	}
+}
+
+func TestExportName(t *testing.T) {
+	taken := data.NewSet("Point", "Origin")
+	tests := map[string]string{
+		"size":     "Size",
+		"valid?":   "Valid_3f_",
+		"map":      "Map",
+		"x_1":      "X_5f_1",
+		"<|>":      "X_0__3c__7c__3e_",
+		"_hidden":  "X_0__hidden",
+		"point":    "X_0_point",
+		"newPoint": "X_0_newPoint",
+		"new":      "New",
+		"newest":   "Newest",
+	}
+	for name, exported := range tests {
+		assert.Equal(t, exported, exportName(name, taken), name)
+		assert.Equal(t, name, Demangle(exported), exported)
+	}
+	assert.Equal(t, "Point", Demangle("NewPoint"))
+	assert.Equal(t, "valid?", Demangle("valid_3f_"))
+}
+
+func TestDemangleTrace(t *testing.T) {
+	trace := `panic: test.novah:3:3: non-exhaustive pattern match
+
+goroutine 1 [running]:
+example.com/prog/data/list.X_0__3c__7c__3e_(...)
+	/tmp/novah-run/data/list/list.go:12
+example.com/prog/data/list.Valid_3f_[...](0xc000012345, 0x1)
+	/tmp/novah-run/data/list/list.go:20 +0x1d
+example.com/prog/data/list.reset_21_.func1({0x4b2f60, 0xc000012345})
+	/tmp/novah-run/data/list/list.go:30 +0x1d
+example.com/prog/data/list.(*Point).__is_Shape(...)
+	/tmp/novah-run/data/list/list.go:40
+example.com/prog/novah.Vector[...].Get(...)
+	/tmp/novah-run/novah/vector.go:50
+main._6d_ain(...)
+	/tmp/novah-run/main.go:9
+main.main()
+	/tmp/novah-run/main.go:20 +0x25
+created by example.com/prog/data/list.spawn in goroutine 1`
+
+	expected := `panic: test.novah:3:3: non-exhaustive pattern match
+
+goroutine 1 [running]:
+data.list.<|>(...)
+	/tmp/novah-run/data/list/list.go:12
+data.list.valid?(0xc000012345, 0x1)
+	/tmp/novah-run/data/list/list.go:20 +0x1d
+data.list.reset! (lambda)({0x4b2f60, 0xc000012345})
+	/tmp/novah-run/data/list/list.go:30 +0x1d
+data.list.Point(...)
+	/tmp/novah-run/data/list/list.go:40
+example.com/prog/novah.Vector[...].Get(...)
+	/tmp/novah-run/novah/vector.go:50
+prog.main(...)
+	/tmp/novah-run/main.go:9
+main.main()
+	/tmp/novah-run/main.go:20 +0x25
+created by data.list.spawn in goroutine 1`
+
+	d := NewDemangler("example.com/prog", "prog")
+	assert.Equal(t, expected, d.Trace(trace))
+}
@@ -0,0 +1,457 @@
+// Compiles pattern matching to decision trees.
+// Based on "Compiling Pattern Matching to Good Decision Trees" by Luc Maranget.
+package compiler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+// A row of the pattern matrix.
+// binds are the variables already bound by this row.
+type matchRow struct {
+	pats  []ast.Pattern
+	binds []matchBind
+	cas   ast.Case
+}
+
+// A variable bound to the value at occurrence occ
+type matchBind struct {
+	name string
+	occ  ast.GoExpr
+}
+
+type matchCompiler struct {
+	o    *Optimizer
+	span data.Span
+	// where the value of the matched case goes
+	dest dest
+	// the temporary variables referenced by the generated code
+	used data.Set[string]
+}
+
+var wildcard ast.Pattern = ast.Wildcard{}
+
+// Converts a match to a decision tree.
+// The scrutinees are bound to temporary variables and every leaf of the tree sends
+// the value of its case to d. Returns the tree, the bindings are emitted before it.
+func (o *Optimizer) convertMatch(e ast.Match, d dest) ast.GoExpr {
+	mc := &matchCompiler{o: o, span: e.Span, dest: d, used: data.NewSet[string]()}
+
+	occs := make([]ast.GoExpr, 0, len(e.Exps))
+	lets := make([]ast.GoLet, 0, len(e.Exps))
+	vars := make([]ast.GoVar, 0, len(e.Exps))
+	for _, gexp := range o.convertOperands(e.Exps...) {
+		if v, isVar := gexp.(ast.GoVar); isVar && v.Package == "" {
+			occs = append(occs, v)
+			vars = append(vars, v)
+			continue
+		}
+		name := o.newTmp()
+		lets = append(lets, ast.GoLet{Binder: name, BindExpr: gexp, Type: gexp.GetType(), Pos: gexp.GetPos()})
+		occs = append(occs, ast.GoVar{Name: name, Type: gexp.GetType(), Pos: gexp.GetPos()})
+	}
+
+	// the bindings have to come before the statements emitted by the tree
+	mark := len(o.stmts)
+	rows := data.MapSlice(e.Cases, func(c ast.Case) matchRow { return matchRow{pats: c.Patterns, cas: c} })
+	tree := mc.compile(occs, rows)
+
+	binds := make([]ast.GoExpr, 0, len(lets))
+	for _, let := range lets {
+		if mc.used.Contains(let.Binder) {
+			binds = append(binds, let)
+		} else {
+			// still evaluate the expression for its side effects
+			binds = append(binds, ast.GoSetvar{Name: "_", Exp: let.BindExpr, Pos: let.Pos})
+		}
+	}
+	for _, v := range vars {
+		// a let bound variable may be used only by the match
+		if o.declared.Contains(v.Name) && !mc.used.Contains(v.Name) {
+			binds = append(binds, ast.GoSetvar{Name: "_", Exp: v, Pos: v.Pos})
+		}
+	}
+	o.stmts = append(o.stmts[:mark], append(binds, o.stmts[mark:]...)...)
+	return tree
+}
+
+// Compiles the pattern matrix `rows` where each column
+// is matched against the value in the same index of `occs`.
+func (mc *matchCompiler) compile(occs []ast.GoExpr, rows []matchRow) ast.GoExpr {
+	if len(rows) == 0 {
+		return mc.fail()
+	}
+	for i := range rows {
+		rows[i] = normalizeRow(rows[i], occs)
+	}
+
+	col := -1
+	for i, pat := range rows[0].pats {
+		if !isWildcard(pat) {
+			col = i
+			break
+		}
+	}
+	if col == -1 {
+		return mc.leaf(occs, rows[0], rows[1:])
+	}
+
+	switch rows[0].pats[col].(type) {
+	case ast.CtorP:
+		return mc.switchCtor(occs, rows, col)
+	case ast.LiteralP:
+		return mc.switchLiteral(occs, rows, col)
+	case ast.ListP:
+		return mc.switchList(occs, rows, col)
+	case ast.RecordP:
+		return mc.expandRecord(occs, rows, col)
+	default:
+		panic(fmt.Sprintf("unsuported pattern %T", rows[0].pats[col]))
+	}
+}
+
+// The first row matches: bind its variables and send its expression to the destination.
+// If the case has a guard, falls through to the remaining rows when the guard fails.
+func (mc *matchCompiler) leaf(occs []ast.GoExpr, row matchRow, rest []matchRow) ast.GoExpr {
+	used := usedVars(row.cas.Exp, row.cas.Guard)
+	for _, bind := range row.binds {
+		// Go doesn't allow unused variables
+		if !used.Contains(bind.name) {
+			continue
+		}
+		mc.use(bind.occ)
+		varname, restore := mc.o.declare(bind.name)
+		defer restore()
+		mc.o.emit(ast.GoLet{Binder: varname, BindExpr: bind.occ, Type: bind.occ.GetType(), Pos: bind.occ.GetPos()})
+	}
+
+	if row.cas.Guard == nil {
+		return mc.o.lower(row.cas.Exp, mc.dest)
+	}
+	return ast.GoIf{
+		Cond: mc.o.convertExpr(row.cas.Guard),
+		Then: mc.o.block(func() ast.GoExpr { return mc.o.lower(row.cas.Exp, mc.dest) }),
+		Else: mc.branch(occs, rest),
+		Type: mc.o.convertType(row.cas.Exp.GetType()),
+		Pos:  row.cas.Guard.GetSpan().Start,
+	}
+}
+
+// Compiles a subtree in its own go block
+func (mc *matchCompiler) branch(occs []ast.GoExpr, rows []matchRow) ast.GoExpr {
+	return mc.o.block(func() ast.GoExpr { return mc.compile(occs, rows) })
+}
+
+// Constructors of single constructor types are always matched,
+// otherwise generates a type switch on the constructor structs.
+func (mc *matchCompiler) switchCtor(occs []ast.GoExpr, rows []matchRow, col int) ast.GoExpr {
+	occ := occs[col]
+	rest := removeAt(occs, col)
+	ctors := make([]ast.CtorP, 0, 2)
+	for _, row := range rows {
+		if ctor, isCtor := row.pats[col].(ast.CtorP); isCtor {
+			if !data.AnySlice(ctors, func(c ast.CtorP) bool { return c.Ctor.Name == ctor.Ctor.Name }) {
+				ctors = append(ctors, ctor)
+			}
+		}
+	}
+
+	specialize := func(ctor ast.CtorP) []matchRow {
+		return specializeRows(rows, col, len(ctor.Fields), func(p ast.Pattern) ([]ast.Pattern, bool) {
+			other := p.(ast.CtorP)
+			return other.Fields, other.Ctor.Name == ctor.Ctor.Name
+		})
+	}
+	fields := func(exp ast.GoExpr, ctor ast.CtorP) []ast.GoExpr {
+		res := make([]ast.GoExpr, 0, len(ctor.Fields)+len(rest))
+		declTy := mc.o.ctorFieldsType(ctor)
+		names := mc.o.ctorFieldsOf(ctor.Ctor)
+		for i, field := range ctor.Fields {
+			var occ ast.GoExpr = ast.GoField{Exp: exp, Name: names[i], Pos: exp.GetPos()}
+			// fields with open records in the constructor may be closed in the pattern
+			if arr, isArr := ast.RealType(declTy).(ast.TArrow); isArr {
+				if mc.o.needsCoercion(arr.Args[0], field.GetType()) {
+					occ = mc.o.coerce(occ, arr.Args[0], field.GetType())
+				}
+				declTy = arr.Ret
+			}
+			res = append(res, occ)
+		}
+		return append(res, rest...)
+	}
+
+	count := mc.o.ctorCount(ctors[0].Ctor)
+	if count == 1 {
+		return mc.compile(fields(occ, ctors[0]), specialize(ctors[0]))
+	}
+
+	bind := mc.o.newTmp()
+	narrowed := ast.GoVar{Name: bind, Pos: occ.GetPos()}
+	cases := make([]ast.GoCase, 0, len(ctors))
+	for _, ctor := range ctors {
+		cases = append(cases, ast.GoCase{
+			Types: []ast.GoType{ctorStructType(ctor.Ctor, mc.o.convertType(ctor.GetType()))},
+			Body:  mc.o.block(func() ast.GoExpr { return mc.compile(fields(narrowed, ctor), specialize(ctor)) }),
+		})
+	}
+	var def ast.GoExpr
+	if len(ctors) == count {
+		def = mc.fail()
+	} else {
+		def = mc.branch(rest, defaultRows(rows, col))
+	}
+
+	mc.use(occ)
+	sw := ast.GoSwitch{Exp: occ, IsType: true, Cases: cases, Default: def, Pos: occ.GetPos()}
+	if mc.used.Contains(bind) {
+		sw.Bind = bind
+	}
+	return sw
+}
+
+// Generates a switch comparing the value against every literal.
+func (mc *matchCompiler) switchLiteral(occs []ast.GoExpr, rows []matchRow, col int) ast.GoExpr {
+	occ := occs[col]
+	rest := removeAt(occs, col)
+	lits := make([]ast.GoConst, 0, 2)
+	for _, row := range rows {
+		if lit, isLit := row.pats[col].(ast.LiteralP); isLit {
+			glit := mc.o.convertExpr(lit.Lit).(ast.GoConst)
+			if !data.AnySlice(lits, func(l ast.GoConst) bool { return l.V == glit.V }) {
+				lits = append(lits, glit)
+			}
+		}
+	}
+
+	cases := make([]ast.GoCase, 0, len(lits))
+	for _, lit := range lits {
+		specialized := specializeRows(rows, col, 0, func(p ast.Pattern) ([]ast.Pattern, bool) {
+			other := mc.o.convertExpr(p.(ast.LiteralP).Lit).(ast.GoConst)
+			return nil, other.V == lit.V
+		})
+		cases = append(cases, ast.GoCase{Values: []ast.GoExpr{lit}, Body: mc.branch(rest, specialized)})
+	}
+
+	mc.use(occ)
+	return ast.GoSwitch{
+		Exp:     occ,
+		Cases:   cases,
+		Default: mc.branch(rest, defaultRows(rows, col)),
+		Pos:     occ.GetPos(),
+	}
+}
+
+// Lists are matched as if they were made of cons cells:
+// [x, y | t] is x :: y :: t and [x, y] is x :: y :: [].
+// The tail of the persistent vector is a constant time view.
+func (mc *matchCompiler) switchList(occs []ast.GoExpr, rows []matchRow, col int) ast.GoExpr {
+	occ := occs[col]
+	rest := removeAt(occs, col)
+	pos := occ.GetPos()
+	listTy := mc.o.convertType(rows[0].pats[col].GetType())
+	elemTy := listTy.(ast.GoTApp).Args[0]
+	intTy := ast.GoTConst{Name: "int"}
+
+	empty := specializeRows(rows, col, 0, func(p ast.Pattern) ([]ast.Pattern, bool) {
+		return nil, len(p.(ast.ListP).Elems) == 0
+	})
+	cons := specializeRows(rows, col, 2, func(p ast.Pattern) ([]ast.Pattern, bool) {
+		list := p.(ast.ListP)
+		if len(list.Elems) == 0 {
+			return nil, false
+		}
+		tail := ast.ListP{Elems: list.Elems[1:], Tail: list.Tail, Span: list.Span, Type: list.Type}
+		return []ast.Pattern{list.Elems[0], tail}, true
+	})
+	method := func(name string, typ ast.GoType, args ...ast.GoExpr) ast.GoExpr {
+		return ast.GoCall{Fn: ast.GoField{Exp: occ, Name: name, Pos: pos}, Args: args, Type: typ, Pos: pos}
+	}
+	head := method("Get", elemTy, ast.GoConst{V: "0", Type: intTy, Pos: pos})
+	tail := method("Drop", listTy, ast.GoConst{V: "1", Type: intTy, Pos: pos})
+	consOccs := append([]ast.GoExpr{head, tail}, rest...)
+
+	isEmpty := ast.GoBinOp{
+		Op:    "==",
+		Left:  method("Len", intTy),
+		Right: ast.GoConst{V: "0", Type: intTy, Pos: pos},
+		Type:  ast.GoTConst{Name: "bool"},
+		Pos:   pos,
+	}
+	mc.use(occ)
+	return ast.GoIf{
+		Cond: isEmpty,
+		Then: mc.branch(rest, empty),
+		Else: mc.branch(consOccs, cons),
+		Pos:  pos,
+	}
+}
+
+// Record patterns always match, so every label
+// used in the column becomes a new column.
+func (mc *matchCompiler) expandRecord(occs []ast.GoExpr, rows []matchRow, col int) ast.GoExpr {
+	occ := occs[col]
+	recTy := rows[0].pats[col].GetType()
+	labels := make([]string, 0, 2)
+	for _, row := range rows {
+		if rec, isRec := row.pats[col].(ast.RecordP); isRec {
+			for _, ent := range rec.Labels.Entries() {
+				if !data.InSlice(labels, ent.Label) {
+					labels = append(labels, ent.Label)
+				}
+			}
+		}
+	}
+
+	specialized := specializeRows(rows, col, len(labels), func(p ast.Pattern) ([]ast.Pattern, bool) {
+		entries := p.(ast.RecordP).Labels.Entries()
+		pats := make([]ast.Pattern, 0, len(labels))
+		for _, label := range labels {
+			ent, found := data.FindSlice(entries, func(e data.Entry[ast.Pattern]) bool { return e.Label == label })
+			if found {
+				pats = append(pats, ent.Val)
+			} else {
+				pats = append(pats, wildcard)
+			}
+		}
+		return pats, true
+	})
+	newOccs := make([]ast.GoExpr, 0, len(labels)+len(occs)-1)
+	for _, label := range labels {
+		sel, _ := mc.o.recordSelect(occ, recTy, label)
+		newOccs = append(newOccs, sel)
+	}
+	newOccs = append(newOccs, removeAt(occs, col)...)
+	return mc.compile(newOccs, specialized)
+}
+
+// No case matched
+func (mc *matchCompiler) fail() ast.GoExpr {
+	pos := mc.span.Start
+	msg := fmt.Sprintf("%s:%d:%d: non-exhaustive pattern match", mc.o.mod.SourceName, pos.Line, pos.Col)
+	return ast.GoCall{
+		Fn:   ast.GoVar{Name: "panic", Pos: pos},
+		Args: []ast.GoExpr{ast.GoConst{V: strconv.Quote(msg), Pos: pos}},
+		Pos:  pos,
+	}
+}
+
+// Marks the variable at the root of this occurrence as used
+func (mc *matchCompiler) use(occ ast.GoExpr) {
+	switch e := occ.(type) {
+	case ast.GoVar:
+		mc.used.Add(e.Name)
+	case ast.GoField:
+		mc.use(e.Exp)
+	case ast.GoTypeAssert:
+		mc.use(e.Exp)
+	case ast.GoCall:
+		mc.use(e.Fn)
+		for _, arg := range e.Args {
+			mc.use(arg)
+		}
+	case ast.GoStructLit:
+		for _, field := range e.Fields {
+			mc.use(field.Exp)
+		}
+	case ast.GoFunc:
+		mc.use(e.Body)
+	case ast.GoStmts:
+		for _, exp := range e.Exps {
+			mc.use(exp)
+		}
+	case ast.GoLet:
+		mc.use(e.BindExpr)
+	case ast.GoReturn:
+		mc.use(e.Exp)
+	}
+}
+
+// Keeps the rows that match the constructor being specialized,
+// replacing the pattern in column `col` by its sub patterns.
+// Wildcards are expanded to `arity` wildcards.
+func specializeRows(rows []matchRow, col, arity int, expand func(ast.Pattern) ([]ast.Pattern, bool)) []matchRow {
+	res := make([]matchRow, 0, len(rows))
+	for _, row := range rows {
+		var sub []ast.Pattern
+		if isWildcard(row.pats[col]) {
+			sub = make([]ast.Pattern, arity)
+			for i := range sub {
+				sub[i] = wildcard
+			}
+		} else if pats, matches := expand(row.pats[col]); matches {
+			sub = pats
+		} else {
+			continue
+		}
+		pats := make([]ast.Pattern, 0, len(sub)+len(row.pats)-1)
+		pats = append(pats, sub...)
+		pats = append(pats, removeAt(row.pats, col)...)
+		res = append(res, matchRow{pats: pats, binds: row.binds, cas: row.cas})
+	}
+	return res
+}
+
+// The rows that match any constructor not tested explicitly
+func defaultRows(rows []matchRow, col int) []matchRow {
+	return specializeRows(rows, col, 0, func(_ ast.Pattern) ([]ast.Pattern, bool) { return nil, false })
+}
+
+// Removes variable, named and unit patterns from the row,
+// recording the variables they bind.
+func normalizeRow(row matchRow, occs []ast.GoExpr) matchRow {
+	pats := make([]ast.Pattern, len(row.pats))
+	binds := row.binds[:len(row.binds):len(row.binds)]
+	for i, pat := range row.pats {
+		pats[i], binds = normalizePattern(pat, occs[i], binds)
+	}
+	return matchRow{pats: pats, binds: binds, cas: row.cas}
+}
+
+func normalizePattern(pat ast.Pattern, occ ast.GoExpr, binds []matchBind) (ast.Pattern, []matchBind) {
+	switch p := pat.(type) {
+	case ast.Wildcard, ast.UnitP:
+		return wildcard, binds
+	case ast.VarP:
+		return wildcard, append(binds, matchBind{name: p.V.Name, occ: occ})
+	case ast.NamedP:
+		return normalizePattern(p.Pat, occ, append(binds, matchBind{name: p.Name.Val, occ: occ}))
+	case ast.ListP:
+		if len(p.Elems) == 0 && p.Tail != nil {
+			return normalizePattern(p.Tail, occ, binds)
+		}
+		return p, binds
+	default:
+		return p, binds
+	}
+}
+
+// All the local variables referenced by these expressions
+func usedVars(exps ...ast.Expr) data.Set[string] {
+	vars := data.NewSet[string]()
+	for _, exp := range exps {
+		if exp == nil {
+			continue
+		}
+		ast.EverywhereExprUnit(exp, func(e ast.Expr) {
+			if v, isVar := e.(ast.Var); isVar && v.ModuleName == "" {
+				vars.Add(v.Name)
+			}
+		})
+	}
+	return vars
+}
+
+func isWildcard(pat ast.Pattern) bool {
+	_, isWild := pat.(ast.Wildcard)
+	return isWild
+}
+
+func removeAt[T any](s []T, at int) []T {
+	res := make([]T, 0, len(s)-1)
+	res = append(res, s[:at]...)
+	return append(res, s[at+1:]...)
+}
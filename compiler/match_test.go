@@ -0,0 +1,83 @@
+package compiler
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/compiler/typechecker"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestMatchCodegen(t *testing.T) {
+	testGolden(t, "match", generateFunctions, "ctors", "literals", "guards", "lists", "expression")
+}
+
+func TestMatchNotInReturnPosition(t *testing.T) {
+	code := `
+module test
+
+id x = x
+
+f x = id (case x of
+  1 -> "one"
+  _ -> "other")`
+
+	got := generateFunctions(code, t)
+
+	assert.Contains(t, got, "var __m0 string")
+	assert.Contains(t, got, "return id[string](__m0)")
+	assert.NotContains(t, got, "func () string {")
+}
+
+// Compares the go code generated for every test_data/dir/name.novah
+// file with the name.go.golden file next to it
+func testGolden(t *testing.T, dir string, generate func(string, *testing.T) string, names ...string) {
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			base := filepath.Join("..", "test_data", dir, name)
+			code, err := os.ReadFile(base + ".novah")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := generate(string(code), t)
+
+			golden := base + ".go.golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, string(expected), got)
+		})
+	}
+}
+
+var linePragma = regexp.MustCompile(`/\*line [^*]*:\d+:\d+\*/`)
+
+// Generates the go code of all the functions in the module
+// without position information
+func generateFunctions(code string, t *testing.T) string {
+	env := compileCode(code, t)
+	pack := NewOptimizer(env.Ast, map[string]typechecker.FullModuleEnv{"test": env}).Convert()
+
+	var sb strings.Builder
+	for _, decl := range pack.Decls {
+		if fun, isFun := decl.(ast.GoFuncDecl); isFun && fun.Body != nil {
+			gen := NewCodegen(pack)
+			gen.genFuncDecl(fun)
+			sb.WriteString(gen.sb.String())
+		}
+	}
+	return linePragma.ReplaceAllString(lineComment.ReplaceAllString(sb.String(), ""), "")
+}
@@ -6,17 +6,37 @@ import (
 	"strings"
 
 	"github.com/stackoverflow/novah-go/compiler/ast"
+	tc "github.com/stackoverflow/novah-go/compiler/typechecker"
 	"github.com/stackoverflow/novah-go/data"
 )
 
 // Generates go ast
 type Optimizer struct {
-	mod  ast.Module
-	init map[string]ast.Expr
+	mod     ast.Module
+	modules map[string]tc.FullModuleEnv
+	// the top-level values initialized in the init function
+	init []ast.ValDecl
+	// the types of let bound variables before instantiation
+	locals map[string]ast.Type
+	// the go type parameters of the type variables
+	// of the data type being converted
+	typeParams map[string]string
+	// the go type parameters of the generic type variables
+	// of the top-level function being converted
+	typeVars map[ast.Id]string
+	tmps     int
+	// the statements of the go block being converted
+	stmts []ast.GoExpr
+	// the local variables declared in the go block being converted
+	declared data.Set[string]
+	// the go names of the let bound variables renamed in their scope
+	renames map[string]string
+	// true if this module is the entry of a program
+	main bool
 }
 
-func NewOptimizer(mod ast.Module) *Optimizer {
-	return &Optimizer{mod: mod, init: make(map[string]ast.Expr)}
+func NewOptimizer(mod ast.Module, modules map[string]tc.FullModuleEnv) *Optimizer {
+	return &Optimizer{mod: mod, modules: modules, locals: make(map[string]ast.Type), renames: make(map[string]string)}
 }
 
 func (o *Optimizer) Convert() ast.GoPackage {
@@ -24,92 +44,66 @@ func (o *Optimizer) Convert() ast.GoPackage {
 	for _, decl := range o.mod.Decls {
 		decls = append(decls, o.convertDecl(decl)...)
 	}
+	if len(o.init) > 0 {
+		decls = append(decls, o.initFunc())
+	}
 
-	return ast.GoPackage{
-		Name:       o.mod.Name.Val,
+	pack := ast.GoPackage{
+		Name:       packageName(o.mod.Name.Val),
+		Module:     o.mod.Name.Val,
 		SourceName: o.mod.SourceName,
 		Decls:      decls,
 		Pos:        o.mod.Name.Span.Start,
 		Comment:    o.mod.Comment,
 	}
+	if o.main {
+		pack.Name = "main"
+		pack.Decls = append(pack.Decls, o.mainFunc())
+	}
+	return pack
 }
 
 func (o *Optimizer) convertDecl(decl ast.Decl) []ast.GoDecl {
 	decls := make([]ast.GoDecl, 0, 1)
 	switch d := decl.(type) {
 	case ast.TypeDecl:
-		if len(d.DataCtors) == 1 {
-			ctor := d.DataCtors[0]
-			fields := make(map[string]ast.GoType)
-			for i, f := range ctor.Args {
-				fields[fmt.Sprintf("v%d", i)] = o.convertType(f)
-			}
-			decls = append(decls, ast.GoStruct{
-				Name:    ctor.Name.Val,
-				Fields:  fields,
-				Pos:     d.Span.Start,
-				Comment: d.Comment,
-			})
-		} else {
-			// interface
-			method := ast.InterMethod{Name: fmt.Sprintf("__is_%s", d.Name.Val)}
-			decls = append(decls, ast.GoInterface{
-				Name:    d.Name.Val,
-				Methods: []ast.InterMethod{method},
-				Pos:     d.Span.Start,
-				Comment: d.Comment,
-			})
-			//structs
-			for _, ctor := range d.DataCtors {
-				fields := make(map[string]ast.GoType)
-				for i, f := range ctor.Args {
-					fields[fmt.Sprintf("v%d", i)] = o.convertType(f)
-				}
-				decls = append(decls, ast.GoStruct{
-					Name:    ctor.Name.Val,
-					Fields:  fields,
-					Pos:     d.Span.Start,
-					Comment: d.Comment,
-				})
-
-				// implement the interface
-				impl := ast.GoFuncDecl{Name: method.Name, Pos: d.Span.Start}
-				decls = append(decls, impl)
-			}
-		}
+		decls = append(decls, o.convertTypeDecl(d)...)
 	case ast.ValDecl:
-		if ast.IsConst(d.Exp) {
+		exp := unwrapAnn(d.Exp)
+		if ast.IsConst(exp) {
 			decls = append(decls, ast.GoConstDecl{
-				Name:    d.Name.Val,
-				Val:     o.convertExpr(d.Exp, false).(ast.GoConst),
+				Name:    o.topLevelName(d.Name.Val, ""),
+				Val:     o.convertExpr(exp).(ast.GoConst),
 				Pos:     d.Span.Start,
 				Comment: d.Comment,
 			})
-		} else if lam, ok := d.Exp.(ast.Lambda); ok {
+		} else if lam, ok := exp.(ast.Lambda); ok {
+			typeParams := o.declareTypeVars(o.declaredType(d.Name.Val, ""))
+			defer func() { o.typeVars = nil }()
 			ty := o.convertType(lam.Type.Type)
 			tfun, ok := ty.(ast.GoTFunc)
 			if !ok {
 				panic("got wrong type for lambda expression")
 			}
-			params := make(map[string]ast.GoType)
-			params[lam.Binder.Name] = tfun.Arg
-			body := o.convertExpr(lam.Body, true)
+			params := []ast.GoParam{{Name: mangle(lam.Binder.Name), Type: tfun.Arg}}
+			body := o.convertBody(lam.Body)
 			decls = append(decls, ast.GoFuncDecl{
-				Name:    d.Name.Val,
-				Params:  params,
-				Returns: []ast.GoType{tfun.Ret},
-				Body:    &body,
-				Pos:     d.Span.Start,
-				Comment: d.Comment,
+				Name:       o.topLevelName(d.Name.Val, ""),
+				TypeParams: typeParams,
+				Params:     params,
+				Returns:    []ast.GoType{tfun.Ret},
+				Body:       &body,
+				Pos:        d.Span.Start,
+				Comment:    d.Comment,
 			})
 		} else {
 			decls = append(decls, ast.GoVarDecl{
-				Name:    d.Name.Val,
+				Name:    o.topLevelName(d.Name.Val, ""),
 				Type:    o.convertType(d.Exp.GetType()),
 				Pos:     d.Span.Start,
 				Comment: d.Comment,
 			})
-			o.init[d.Name.Val] = d.Exp
+			o.init = append(o.init, d)
 		}
 	default:
 		panic("unknow declaration in optimizer")
@@ -117,26 +111,48 @@ func (o *Optimizer) convertDecl(decl ast.Decl) []ast.GoDecl {
 	return decls
 }
 
-func (o *Optimizer) convertExpr(expr ast.Expr, retur bool) ast.GoExpr {
+// Top-level values that are not constants are assigned in the init function
+// of the package, in the dependency order computed by the desugarer.
+// Imported modules are initialized first as go initializes the imported packages
+// before the package that imports them.
+func (o *Optimizer) initFunc() ast.GoFuncDecl {
+	body := o.block(func() ast.GoExpr {
+		for _, d := range o.init {
+			o.emit(o.lower(unwrapAnn(d.Exp), assignDest(o.topLevelName(d.Name.Val, ""))))
+		}
+		return nil
+	})
+	return ast.GoFuncDecl{
+		Name: "init",
+		Body: &body,
+		Pos:  o.mod.Name.Span.Start,
+	}
+}
+
+// Converts the expression to a go expression,
+// emitting the statements it needs before it.
+func (o *Optimizer) convertExpr(expr ast.Expr) ast.GoExpr {
 	switch e := expr.(type) {
 	case ast.Int:
-		return _return(retur, ast.GoConst{V: e.Raw, Type: o.convertType(e.Type.Type), Pos: e.Span.Start})
+		return ast.GoConst{V: e.Raw, Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
 	case ast.Float:
-		return _return(retur, ast.GoConst{V: e.Raw, Type: o.convertType(e.Type.Type), Pos: e.Span.Start})
+		return ast.GoConst{V: e.Raw, Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
 	case ast.Complex:
-		return _return(retur, ast.GoConst{V: e.Raw, Type: o.convertType(e.Type.Type), Pos: e.Span.Start})
+		return ast.GoConst{V: e.Raw, Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
 	case ast.Bool:
-		return _return(retur, ast.GoConst{V: strconv.FormatBool(e.V), Type: o.convertType(e.Type.Type), Pos: e.Span.Start})
+		return ast.GoConst{V: strconv.FormatBool(e.V), Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
 	case ast.Char:
-		return _return(retur, ast.GoConst{V: e.Raw, Type: o.convertType(e.Type.Type), Pos: e.Span.Start})
+		return ast.GoConst{V: strconv.QuoteRune(e.V), Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
 	case ast.String:
-		return _return(retur, ast.GoConst{V: e.Raw, Type: o.convertType(e.Type.Type), Pos: e.Span.Start})
+		return ast.GoConst{V: strconv.Quote(e.V), Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
 	case ast.Var:
-		return _return(retur, ast.GoVar{Name: e.Name, Package: e.ModuleName, Type: o.convertType(e.Type.Type), Pos: e.Span.Start})
+		return o.convertVar(e.Name, e.ModuleName, e.Type.Type, e.Span.Start)
 	case ast.Ctor:
-		return _return(retur, ast.GoVar{Name: e.Name, Package: e.ModuleName, Type: o.convertType(e.Type.Type), Pos: e.Span.Start})
+		return o.convertCtor(e.Name, e.ModuleName, e.Type.Type, e.Span.Start)
+	case ast.Ann:
+		return o.convertExpr(e.Exp)
 	case ast.ImplicitVar:
-		return _return(retur, ast.GoVar{Name: e.Name, Package: e.ModuleName, Type: o.convertType(e.Type.Type), Pos: e.Span.Start})
+		return ast.GoVar{Name: o.valueName(e.Name, e.ModuleName), Package: e.ModuleName, Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
 	case ast.Lambda:
 		{
 			ty := o.convertType(e.Type.Type)
@@ -145,100 +161,44 @@ func (o *Optimizer) convertExpr(expr ast.Expr, retur bool) ast.GoExpr {
 				panic("got wrong type for lambda expression")
 			}
 
-			args := map[string]ast.GoType{e.Binder.Name: tfun.Arg}
-			return _return(retur, ast.GoFunc{
+			args := []ast.GoParam{{Name: mangle(e.Binder.Name), Type: tfun.Arg}}
+			return ast.GoFunc{
 				Args:    args,
 				Returns: []ast.GoType{tfun.Ret},
-				Body:    o.convertExpr(e.Body, true),
+				Body:    o.convertBody(e.Body),
 				Type:    ty,
 				Pos:     e.Span.Start,
-			})
+			}
 		}
 	case ast.App:
-		return _return(retur, ast.GoCall{
-			Fn:   o.convertExpr(e.Fn, false),
-			Args: []ast.GoExpr{o.convertExpr(e.Arg, false)},
-			Type: o.convertType(e.Type.Type),
-			Pos:  e.Span.Start,
-		})
+		return o.convertApp(e)
 	case ast.If:
-		{
-			then := o.convertExpr(e.Then, true)
-			els := o.convertExpr(e.Else, true)
-			return _return(retur, ast.GoCall{
-				Fn: ast.GoVar{Name: "__if"},
-				Args: []ast.GoExpr{
-					o.convertExpr(e.Cond, false),
-					ast.GoFunc{
-						Args:    nil,
-						Returns: []ast.GoType{then.GetType()},
-						Body:    then,
-						Pos:     then.GetPos(),
-					},
-					ast.GoFunc{
-						Args:    nil,
-						Returns: []ast.GoType{els.GetType()},
-						Body:    els,
-						Pos:     els.GetPos(),
-					},
-				},
-				Type: o.convertType(e.Type.Type),
-				Pos:  e.Span.Start,
-			})
-		}
+		return o.hoist(e)
 	case ast.Let:
-		{
-			stmts := make([]ast.GoExpr, 0, 2)
-			typ := o.convertType(e.Def.Expr.GetType())
-			varname := e.Def.Binder.Name
-			if _if, ok := e.Def.Expr.(ast.If); ok {
-				stmts = append(stmts, ast.GoVarDef{Name: varname, Type: typ, Pos: e.Def.Binder.Span.Start})
-				stmts = append(stmts, ast.GoIf{
-					Cond: o.convertExpr(_if.Cond, false),
-					Then: ast.GoSetvar{Name: varname, Exp: o.convertExpr(_if.Then, false)},
-					Else: ast.GoSetvar{Name: varname, Exp: o.convertExpr(_if.Else, false)},
-					Type: typ,
-					Pos:  e.Def.Binder.Span.Start,
-				})
-			} else {
-				stmts = append(stmts, ast.GoLet{
-					Binder:   e.Def.Binder.Name,
-					BindExpr: o.convertExpr(e.Def.Expr, false),
-					Type:     o.convertType(e.Type.Type),
-					Pos:      e.Span.Start,
-				})
-			}
-			stmts = append(stmts, o.convertExpr(e.Body, retur))
-			return ast.GoStmts{Exps: stmts, Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
-		}
+		return o.convertLet(e, func() ast.GoExpr { return o.convertExpr(e.Body) })
 	case ast.Do:
 		{
-			size := len(e.Exps)
-			exps := make([]ast.GoExpr, 0, size)
-			for i, exp := range e.Exps {
-				isRet := false
-				if i == size-1 {
-					isRet = retur
-				}
-				exps = append(exps, o.convertExpr(exp, isRet))
-			}
-			return ast.GoStmts{
-				Exps: exps,
-				Type: o.convertType(e.Type.Type),
-				Pos:  e.Span.Start,
+			last := len(e.Exps) - 1
+			for _, exp := range e.Exps[:last] {
+				o.emit(o.lower(exp, discardDest))
 			}
+			return o.convertExpr(e.Exps[last])
 		}
 	case ast.Unit:
-		return _return(retur, ast.GoUnit{Type: o.convertType(e.Type.Type), Pos: e.Span.Start})
+		return ast.GoUnit{Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
 	case ast.While:
-		return ast.GoWhile{
-			Cond: o.convertExpr(e.Cond, false),
-			Exps: data.MapSlice(e.Exps, func(ex ast.Expr) ast.GoExpr { return o.convertExpr(ex, false) }),
-			Type: o.convertType(e.Type.Type),
-			Pos:  e.Span.Start,
-		}
+		o.emit(o.convertWhile(e))
+		return ast.GoUnit{Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
 	case ast.Nil:
-		return _return(retur, ast.GoNil{Type: o.convertType(e.Type.Type), Pos: e.Span.Start})
+		return ast.GoNil{Type: o.convertType(e.Type.Type), Pos: e.Span.Start}
+	case ast.Match:
+		return o.hoist(e)
+	case ast.ListLiteral:
+		return o.convertCollection("NewVector", e.Exps, e.Type.Type, e.Span.Start)
+	case ast.SetLiteral:
+		return o.convertCollection("NewSet", e.Exps, e.Type.Type, e.Span.Start)
+	case ast.RecordEmpty, ast.RecordSelect, ast.RecordExtend, ast.RecordRestrict, ast.RecordUpdate, ast.RecordMerge:
+		return o.convertRecordExpr(e)
 	default:
 		panic("unsuported expression")
 	}
@@ -248,20 +208,28 @@ func (o *Optimizer) convertType(typ ast.Type) ast.GoType {
 	switch t := typ.(type) {
 	case ast.TConst:
 		{
-			strs := strings.Split(t.Name, ".")
-			pack := ""
-			if len(strs) > 1 {
-				pack = strs[0]
+			if param, isParam := o.typeParams[t.Name]; isParam {
+				return ast.GoTConst{Name: param}
+			}
+			if t.Name == tc.PrimUnit {
+				return goUnit
+			}
+			name, pack := t.Name, ""
+			if i := strings.LastIndex(t.Name, "."); i != -1 {
+				name, pack = t.Name[i+1:], t.Name[:i]
 			}
-			name := convertGoType(strs[len(strs)-1])
-			return ast.GoTConst{Name: name, Package: pack}
+			if pack == o.mod.Name.Val {
+				pack = ""
+			}
+			return ast.GoTConst{Name: convertGoType(name), Package: pack}
 		}
 	case ast.TVar:
 		switch t.Tvar.Tag {
-		case ast.UNBOUND:
-			return ast.GoTConst{Name: "any"}
-		case ast.GENERIC:
-			return ast.GoTConst{Name: "any"}
+		case ast.UNBOUND, ast.GENERIC:
+			if param, isParam := o.typeVars[t.Tvar.Id]; isParam {
+				return ast.GoTConst{Name: param}
+			}
+			return goAny
 		case ast.LINK:
 			return o.convertType(t.Tvar.Type)
 		default:
@@ -270,21 +238,156 @@ func (o *Optimizer) convertType(typ ast.Type) ast.GoType {
 	case ast.TArrow:
 		return ast.GoTFunc{Arg: o.convertType(t.Args[0]), Ret: o.convertType(t.Ret)}
 	case ast.TApp:
-		return o.convertType(t.Type)
+		con, isConst := ast.RealType(t.Type).(ast.TConst)
+		if _, isParam := o.typeParams[con.Name]; !isConst || isParam {
+			// higher kinded types can't be represented in go
+			return goAny
+		}
+		args := make([]ast.GoType, 0, len(t.Types))
+		rows := o.rowParams(con)
+		for i, arg := range t.Types {
+			if rows == nil || !rows[i] {
+				args = append(args, o.convertType(arg))
+			}
+		}
+		switch con.Name {
+		case tc.PrimList:
+			return ast.GoTApp{Type: goVector, Args: args}
+		case tc.PrimSet:
+			return ast.GoTApp{Type: goSet, Args: args}
+		}
+		head := o.convertType(con).(ast.GoTConst)
+		if len(args) == 0 {
+			return head
+		}
+		return ast.GoTApp{Type: head, Args: args}
 	case ast.TImplicit:
 		return o.convertType(t.Type)
+	case ast.TRecord:
+		return o.convertRecordType(t)
+	case ast.TRowEmpty, ast.TRowExtend:
+		// rows only appear as type arguments of data types
+		return goAny
 	default:
 		panic("unknow type " + typ.String())
 	}
 }
 
-func _return(retur bool, exp ast.GoExpr) ast.GoExpr {
-	if !retur {
-		return exp
+// Lists and sets are created by the runtime constructor `fun`.
+// The type argument is always passed as Go can't infer it for empty literals.
+func (o *Optimizer) convertCollection(fun string, exps []ast.Expr, typ ast.Type, pos data.Pos) ast.GoExpr {
+	gtyp := o.convertType(typ)
+	args := o.convertOperands(exps...)
+	return ast.GoCall{
+		Fn:   ast.GoVar{Name: fun, Package: "novah", TypeArgs: gtyp.(ast.GoTApp).Args, Pos: pos},
+		Args: args,
+		Type: gtyp,
+		Pos:  pos,
+	}
+}
+
+// Variables are coerced to their instantiated type
+// if the representation of some record changed.
+func (o *Optimizer) convertVar(name, module string, typ ast.Type, pos data.Pos) ast.GoExpr {
+	fn, decl := o.varRef(name, module, false, typ, pos)
+	if decl == nil || !o.needsCoercion(decl, typ) {
+		fn.Type = o.convertType(typ)
+		return fn
+	}
+	return o.coerce(fn, decl, typ)
+}
+
+// Returns a reference to the variable or constructor `name` used at type inst
+// and the type it has to be coerced from.
+// Generic functions are instantiated with explicit type arguments
+// as go can't infer the type parameters that only appear in returned functions.
+func (o *Optimizer) varRef(name, module string, isCtor bool, inst ast.Type, pos data.Pos) (ast.GoVar, ast.Type) {
+	decl := o.declaredType(name, module)
+	if isCtor {
+		if decl != nil {
+			decl, _ = o.instantiate(decl, inst)
+		}
+		return o.ctorRef(name, module, decl, inst, pos), decl
+	}
+	var typeArgs []ast.GoType
+	if decl != nil && o.isTopLevelFunc(name, module) {
+		decl, typeArgs = o.instantiate(decl, inst)
+	}
+	var gtyp ast.GoType
+	if decl != nil {
+		gtyp = o.convertType(decl)
+	}
+	return ast.GoVar{Name: o.valueName(name, module), Package: module, TypeArgs: typeArgs, Type: gtyp, Pos: pos}, decl
+}
+
+// Applications of variables that need coercion convert
+// the argument and the result instead of the whole function.
+func (o *Optimizer) convertApp(e ast.App) ast.GoExpr {
+	var name, module string
+	isCtor := false
+	switch fn := e.Fn.(type) {
+	case ast.Var:
+		name, module = fn.Name, fn.ModuleName
+	case ast.Ctor:
+		name, module, isCtor = fn.Name, fn.ModuleName, true
+	}
+	if inst, isArr := ast.RealType(e.Fn.GetType()).(ast.TArrow); name != "" && isArr {
+		fn, decl := o.varRef(name, module, isCtor, inst, e.Fn.GetSpan().Start)
+		declArr, isDeclArr := ast.RealType(decl).(ast.TArrow)
+		if isDeclArr && o.needsCoercion(decl, inst) {
+			arg := o.coerce(o.convertExpr(e.Arg), inst.Args[0], declArr.Args[0])
+			call := ast.GoCall{Fn: fn, Args: []ast.GoExpr{arg}, Type: o.convertType(declArr.Ret), Pos: e.Span.Start}
+			return o.coerce(call, declArr.Ret, inst.Ret)
+		}
+	}
+	operands := o.convertOperands(e.Fn, e.Arg)
+	return ast.GoCall{
+		Fn:   operands[0],
+		Args: operands[1:],
+		Type: o.convertType(e.Type.Type),
+		Pos:  e.Span.Start,
+	}
+}
+
+// Returns how many constructors the type of this constructor has
+// or -1 if the type could not be found
+func (o *Optimizer) ctorCount(ctor ast.Ctor) int {
+	modName := ctor.ModuleName
+	if modName == "" {
+		modName = o.mod.Name.Val
+	}
+	if mod, has := o.modules[modName]; has {
+		for _, typ := range mod.Env.Types {
+			if data.InSlice(typ.Ctors, ctor.Name) {
+				return len(typ.Ctors)
+			}
+		}
+	}
+	return -1
+}
+
+// Creates a new unique temporary variable name
+func (o *Optimizer) newTmp() string {
+	name := fmt.Sprintf("__m%d", o.tmps)
+	o.tmps++
+	return name
+}
+
+// Type annotations don't change the generated code
+func unwrapAnn(exp ast.Expr) ast.Expr {
+	if ann, isAnn := exp.(ast.Ann); isAnn {
+		return unwrapAnn(ann.Exp)
 	}
-	return ast.GoReturn{Exp: exp, Pos: exp.GetPos()}
+	return exp
 }
 
+// the types of the runtime
+var (
+	goVector = ast.GoTConst{Name: "Vector", Package: "novah"}
+	goSet    = ast.GoTConst{Name: "Set", Package: "novah"}
+	goUnit   = ast.GoTConst{Name: "Unit", Package: "novah"}
+)
+
 var primTypes = data.NewSet("Int", "Int8", "Int16", "Int32", "Int64",
 	"Uint", "Uint8", "Uint16", "Uint32", "Uint64", "Byte", "Float32", "Float64",
 	"Complex64", "Complex128", "Rune", "Uintptr", "Bool", "String")
@@ -293,5 +396,5 @@ func convertGoType(name string) string {
 	if primTypes.Contains(name) {
 		return strings.ToLower(name)
 	}
-	return name
+	return mangle(name)
 }
@@ -0,0 +1,157 @@
+package compiler
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/novah"
+)
+
+// The output is a go module with one package per novah module:
+// the module data.list is the package list in the directory data/list.
+// The runtime is copied to the novah package of the output.
+
+const (
+	DEFAULT_GO_MODULE = "app"
+	RUNTIME_PACKAGE   = "novah"
+)
+
+// The name of the go package of a module.
+// Only the entry of a program is compiled to the main package.
+func packageName(module string) string {
+	if module == RUNTIME_PACKAGE {
+		return module
+	}
+	return mangle(module[strings.LastIndex(module, ".")+1:])
+}
+
+// The directory of the go package of a module, relative to the output
+func packageDir(module string) string {
+	return filepath.Join(strings.Split(module, ".")...)
+}
+
+// The import path of a package referenced by the generated code:
+// either a module or the runtime
+func importPath(goModule, pkg string) string {
+	if pkg == RUNTIME_PACKAGE {
+		return goModule + "/" + RUNTIME_PACKAGE
+	}
+	return goModule + "/" + strings.ReplaceAll(pkg, ".", "/")
+}
+
+// Returns the name used to reference the package in the generated code
+// and adds it to the imports.
+// Modules are referenced by their full name, so packages
+// with the same name in different directories don't clash.
+func (c *Codegen) qualifier(pkg string) string {
+	if q, has := c.imports[pkg]; has {
+		return q
+	}
+	taken := func(name string) bool {
+		if c.declNames.Contains(name) {
+			return true
+		}
+		for _, q := range c.imports {
+			if q == name {
+				return true
+			}
+		}
+		return false
+	}
+	q := strings.ReplaceAll(pkg, ".", "_")
+	for taken(q) {
+		q += "_"
+	}
+	c.imports[pkg] = q
+	return q
+}
+
+// Writes the imports of the packages referenced in the file, sorted by path
+func (c *Codegen) genImports(sb *strings.Builder) {
+	if len(c.imports) == 0 {
+		return
+	}
+	goModule := c.pack.GoModule
+	if goModule == "" {
+		goModule = DEFAULT_GO_MODULE
+	}
+	pkgs := make([]string, 0, len(c.imports))
+	for pkg := range c.imports {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return importPath(goModule, pkgs[i]) < importPath(goModule, pkgs[j]) })
+
+	sb.WriteString("import (\n")
+	for _, pkg := range pkgs {
+		sb.WriteString("  ")
+		if q := c.imports[pkg]; q != packageName(pkg) {
+			sb.WriteString(q + " ")
+		}
+		sb.WriteString(strconv.Quote(importPath(goModule, pkg)) + "\n")
+	}
+	sb.WriteString(")\n\n")
+}
+
+// The names declared at the top-level of a package
+func declNames(decls []ast.GoDecl) []string {
+	names := make([]string, 0, len(decls))
+	for _, decl := range decls {
+		switch d := decl.(type) {
+		case ast.GoInterface:
+			names = append(names, d.Name)
+		case ast.GoStruct:
+			names = append(names, d.Name)
+		case ast.GoConstDecl:
+			names = append(names, d.Name)
+		case ast.GoVarDecl:
+			names = append(names, d.Name)
+		case ast.GoFuncDecl:
+			if d.Receiver == nil {
+				names = append(names, d.Name)
+			}
+		}
+	}
+	return names
+}
+
+// The path of the go module of the program
+func (env *Environment) goModule() string {
+	if env.opts.GoModule == "" {
+		return DEFAULT_GO_MODULE
+	}
+	return env.opts.GoModule
+}
+
+// Writes the go.mod file and the runtime to the output
+// so the program can be built by the go tool.
+func (env *Environment) writeGoModule(output string) error {
+	gomod := fmt.Sprintf("module %s\n\ngo 1.18\n", env.goModule())
+	if err := os.WriteFile(filepath.Join(output, "go.mod"), []byte(gomod), 0644); err != nil {
+		return err
+	}
+
+	dir := filepath.Join(output, RUNTIME_PACKAGE)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	files, err := fs.ReadDir(novah.Sources, ".")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		src, err := novah.Sources.ReadFile(file.Name())
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, file.Name()), src, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package compiler
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stretchr/testify/assert"
+)
+
+var packagesCode = map[string]string{
+	"app.novah": `module app
+
+import data.shapes (Shape(..), empty?)
+import util.shapes as U
+
+circle : Unit -> Shape
+circle _ = Circle 1
+
+isEmpty : Shape -> Bool
+isEmpty s = empty? s
+
+sizes : Unit -> List U.Size
+sizes _ = [U.Small, U.Big]
+`,
+	"data/shapes.novah": `module data.shapes
+
+pub+
+type Shape = Circle Int | Square Int
+
+pub
+empty? : Shape -> Bool
+empty? _ = false
+`,
+	"util/shapes.novah": `module util.shapes
+
+pub+
+type Size = Small | Big
+`,
+}
+
+func TestPackageLayout(t *testing.T) {
+	root, output := t.TempDir(), t.TempDir()
+	writeFiles(t, root, packagesCode)
+
+	c := NewCompiler([]string{filepath.Join(root, "app.novah")}, Options{SourceRoots: []string{root}, GoModule: "example.com/shapes"})
+	errs := c.Run(output, false)
+	assert.Empty(t, errs)
+
+	gomod, err := os.ReadFile(filepath.Join(output, "go.mod"))
+	assert.Nil(t, err)
+	assert.Equal(t, "module example.com/shapes\n\ngo 1.18\n", string(gomod))
+	_, err = os.Stat(filepath.Join(output, RUNTIME_PACKAGE, "vector.go"))
+	assert.Nil(t, err)
+
+	shapes, err := os.ReadFile(filepath.Join(output, "data", "shapes", "shapes.go"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(shapes), "\npackage shapes\n")
+	assert.NotContains(t, string(shapes), "import")
+
+	app, err := os.ReadFile(filepath.Join(output, "app", "app.go"))
+	assert.Nil(t, err)
+	// packages with the same name are imported with the module name
+	assert.Contains(t, string(app), "import (\n\tdata_shapes \"example.com/shapes/data/shapes\"\n\t\"example.com/shapes/novah\"\n\tutil_shapes \"example.com/shapes/util/shapes\"\n)")
+	assert.Contains(t, string(app), "func circle(__var1 novah.Unit) data_shapes.Shape {")
+	// public values are exported
+	assert.Contains(t, string(shapes), "func Empty_3f_(__var1 Shape) bool {")
+	assert.Contains(t, string(app), "data_shapes.Empty_3f_(")
+}
+
+func TestLibraryModuleMain(t *testing.T) {
+	root, output := t.TempDir(), t.TempDir()
+	writeFiles(t, root, map[string]string{"main.novah": `module main
+
+main : Int -> Int
+main x = x
+
+init : Int -> Int
+init x = main x
+`})
+
+	c := NewCompiler([]string{filepath.Join(root, "main.novah")}, Options{SourceRoots: []string{root}})
+	assert.Empty(t, c.Run(output, false))
+
+	code, err := os.ReadFile(filepath.Join(output, "main", "_6d_ain.go"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(code), "\npackage _6d_ain\n")
+	assert.Contains(t, string(code), "func _6d_ain(x int) int {")
+	assert.Contains(t, string(code), "func _69_nit(x int) int {")
+}
+
+func TestQualifierClashes(t *testing.T) {
+	c := NewCodegen(ast.GoPackage{Name: "app", Module: "app", Decls: []ast.GoDecl{ast.GoVarDecl{Name: "util_shapes"}}})
+
+	assert.Equal(t, "data_shapes", c.qualifier("data.shapes"))
+	assert.Equal(t, "data_shapes", c.qualifier("data.shapes"))
+	assert.Equal(t, "util_shapes_", c.qualifier("util.shapes"))
+	assert.Equal(t, "novah", c.qualifier(RUNTIME_PACKAGE))
+}
+
+func TestBuildPackages(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil || testing.Short() {
+		t.Skip("the go tool is needed to build packages")
+	}
+	root, output := t.TempDir(), t.TempDir()
+	writeFiles(t, root, packagesCode)
+
+	c := NewCompiler([]string{filepath.Join(root, "app.novah")}, Options{SourceRoots: []string{root}})
+	assert.Empty(t, c.Run(output, false))
+
+	cmd := exec.Command("go", "build", "./...")
+	cmd.Dir = output
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("could not build packages: %s\n%s", err, strings.TrimSpace(string(out)))
+	}
+}
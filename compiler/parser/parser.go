@@ -2,6 +2,7 @@ package parser
 
 import (
 	"math"
+	"strings"
 
 	"github.com/stackoverflow/novah-go/compiler/ast"
 	"github.com/stackoverflow/novah-go/compiler/lexer"
@@ -26,7 +27,7 @@ func NewParser(tokens *lexer.Lexer) *parser {
 func (p *parser) ParseFullModule() (res ast.SModule, errs []data.CompilerProblem) {
 	defer func() {
 		if r := recover(); r != nil {
-			var msg string
+			var msg data.Message
 			var span data.Span
 			switch e := r.(type) {
 			case lexer.LexerError:
@@ -45,7 +46,7 @@ func (p *parser) ParseFullModule() (res ast.SModule, errs []data.CompilerProblem
 				panic("Got unexpected error in parseFullModule")
 			}
 			errs = append(errs, p.errors...)
-			errs = append(errs, data.CompilerProblem{Msg: msg, Span: span, Filename: p.sourceName, Module: p.moduleName, Severity: data.FATAL})
+			errs = append(errs, data.CompilerProblem{Msg: msg.Text, Code: msg.Code, Span: span, Filename: p.sourceName, Module: p.moduleName, Severity: data.FATAL})
 		}
 	}()
 
@@ -71,7 +72,7 @@ func (p *parser) parseFullModule() ast.SModule {
 		func() {
 			defer func() {
 				if r := recover(); r != nil {
-					var msg string
+					var msg data.Message
 					var span data.Span
 					switch e := r.(type) {
 					case lexer.LexerError:
@@ -87,7 +88,7 @@ func (p *parser) parseFullModule() ast.SModule {
 					default:
 						panic("Got unexpected error in parseFullModule")
 					}
-					p.errors = append(p.errors, data.CompilerProblem{Msg: msg, Span: span, Filename: p.sourceName, Module: p.moduleName, Severity: data.ERROR})
+					p.errors = append(p.errors, data.CompilerProblem{Msg: msg.Text, Code: msg.Code, Span: span, Filename: p.sourceName, Module: p.moduleName, Severity: data.ERROR})
 					p.fastForward()
 				}
 			}()
@@ -111,9 +112,32 @@ func (p *parser) parseModule() ModuleDef {
 	return ModuleDef{name: p.parseModuleName(), span: span(m.Span, p.iter.current.Span), comment: m.Comment}
 }
 
+// Module names are lower case identifiers separated by dots,
+// like data.list
 func (p *parser) parseModuleName() ast.Spanned[string] {
-	ident := p.expect(lexer.IDENT, withError(data.MODULE_NAME))
-	return ast.Spanned[string]{Val: *ident.Text, Span: ident.Span}
+	parts := make([]string, 0, 2)
+	first := p.expect(lexer.IDENT, withError(data.MODULE_NAME))
+	last := first
+	for {
+		if strings.ContainsAny(*last.Text, "?!") {
+			throwError(withError(data.MODULE_NAME)(last))
+		}
+		parts = append(parts, *last.Text)
+		next := p.iter.peek()
+		if next.Type != lexer.DOT {
+			break
+		}
+		// the dots cannot be separated by spaces
+		if next.Span.Start != last.Span.End {
+			throwError(withError(data.MODULE_NAME)(next))
+		}
+		p.iter.next()
+		last = p.expect(lexer.IDENT, withError(data.MODULE_NAME))
+		if last.Span.Start != next.Span.End {
+			throwError(withError(data.MODULE_NAME)(last))
+		}
+	}
+	return ast.Spanned[string]{Val: strings.Join(parts, "."), Span: span(first.Span, last.Span)}
 }
 
 func (p *parser) parseImports() []ast.Import {
@@ -150,6 +174,8 @@ func (p *parser) parseImport() ast.Import {
 			alias := p.expect(lexer.UPPERIDENT, withError(data.IMPORT_ALIAS))
 			impor = ast.Import{Module: mod, Alias: *alias.Text, Span: span(impTk.Span, p.iter.current.Span)}
 		}
+	default:
+		impor = ast.Import{Module: mod, Span: span(impTk.Span, p.iter.current.Span)}
 	}
 	impor.Comment = impTk.Comment
 	return impor
@@ -556,12 +582,12 @@ func (p *parser) tryParseAtom() (ast.SExpr, bool) {
 	case lexer.SETBRACKET:
 		{
 			tk := p.iter.next()
-			if p.iter.peek().Type == lexer.RSBRACKET {
+			if p.iter.peek().Type == lexer.RBRACKET {
 				end := p.iter.next()
 				exp = ast.SSetLiteral{Exps: []ast.SExpr{}, Span: span(tk.Span, end.Span), Comment: tk.Comment}
 			} else {
 				exps := between(p, lexer.COMMA, func() ast.SExpr { return p.parseExpression(false) })
-				end := p.expect(lexer.RSBRACKET, withError(data.RSBracketExpected("set literal")))
+				end := p.expect(lexer.RBRACKET, withError(data.RBracketExpected("set literal")))
 				exp = ast.SSetLiteral{Exps: exps, Span: span(tk.Span, end.Span), Comment: tk.Comment}
 			}
 		}
@@ -1100,6 +1126,7 @@ func (p *parser) parseMatch() ast.SExpr {
 }
 
 func (p *parser) parseCase() ast.SCase {
+	comment := p.iter.peek().Comment
 	var guard ast.SExpr
 	pats := withIgnoreOffside(p, true, func() []ast.SPattern {
 		pats := between(p, lexer.COMMA, func() ast.SPattern {
@@ -1113,7 +1140,7 @@ func (p *parser) parseCase() ast.SCase {
 		return pats
 	})
 	return withOffsideDef(p, func() ast.SCase {
-		return ast.SCase{Pats: pats, Exp: p.parseDo(), Guard: guard}
+		return ast.SCase{Pats: pats, Exp: p.parseDo(), Guard: guard, Comment: comment}
 	})
 }
 
@@ -1139,27 +1166,27 @@ func (p *parser) tryParsePattern(isDestructuring bool) (ast.SPattern, bool) {
 	case lexer.INT:
 		{
 			p.iter.next()
-			pat = ast.SLiteralP{Lit: ast.SInt{V: tk.Value.(int64), Span: tk.Span}, Span: tk.Span}
+			pat = ast.SLiteralP{Lit: ast.SInt{V: tk.Value.(int64), Text: *tk.Text, Span: tk.Span}, Span: tk.Span}
 		}
 	case lexer.FLOAT:
 		{
 			p.iter.next()
-			pat = ast.SLiteralP{Lit: ast.SFloat{V: tk.Value.(float64), Span: tk.Span}, Span: tk.Span}
+			pat = ast.SLiteralP{Lit: ast.SFloat{V: tk.Value.(float64), Text: *tk.Text, Span: tk.Span}, Span: tk.Span}
 		}
 	case lexer.COMPLEX:
 		{
 			p.iter.next()
-			pat = ast.SLiteralP{Lit: ast.SComplex{V: tk.Value.(complex128), Span: tk.Span}, Span: tk.Span}
+			pat = ast.SLiteralP{Lit: ast.SComplex{V: tk.Value.(complex128), Text: *tk.Text, Span: tk.Span}, Span: tk.Span}
 		}
 	case lexer.CHAR:
 		{
 			p.iter.next()
-			pat = ast.SLiteralP{Lit: ast.SChar{V: tk.Value.(rune), Span: tk.Span}, Span: tk.Span}
+			pat = ast.SLiteralP{Lit: ast.SChar{V: tk.Value.(rune), Raw: *tk.Text, Span: tk.Span}, Span: tk.Span}
 		}
 	case lexer.STRING:
 		{
 			p.iter.next()
-			pat = ast.SLiteralP{Lit: ast.SString{V: tk.Value.(string), Span: tk.Span}, Span: tk.Span}
+			pat = ast.SLiteralP{Lit: ast.SString{V: tk.Value.(string), Raw: *tk.Text, Span: tk.Span}, Span: tk.Span}
 		}
 	case lexer.LPAREN:
 		{
@@ -1168,9 +1195,9 @@ func (p *parser) tryParsePattern(isDestructuring bool) (ast.SPattern, bool) {
 				end := p.iter.next()
 				pat = ast.SUnitP{Span: span(tk.Span, end.Span)}
 			} else {
-				pat := p.parsePattern(false)
+				inner := p.parsePattern(false)
 				end := p.expect(lexer.RPAREN, withError(data.RParensExpected("pattern declaration")))
-				pat = ast.SParensP{Pat: pat, Span: span(tk.Span, end.Span)}
+				pat = ast.SParensP{Pat: inner, Span: span(tk.Span, end.Span)}
 			}
 		}
 	case lexer.UPPERIDENT:
@@ -1194,10 +1221,10 @@ func (p *parser) tryParsePattern(isDestructuring bool) (ast.SPattern, bool) {
 			p.iter.next()
 			if p.iter.peek().Type == lexer.LBRACKET {
 				p.iter.next()
-				pat := p.parsePattern(false)
+				inner := p.parsePattern(false)
 				p.expect(lexer.RBRACKET, withError(data.INSTANCE_VAR))
 				end := p.expect(lexer.RBRACKET, withError(data.INSTANCE_VAR))
-				pat = ast.SImplicitP{Pat: pat, Span: span(tk.Span, end.Span)}
+				pat = ast.SImplicitP{Pat: inner, Span: span(tk.Span, end.Span)}
 			} else {
 				rows := between(p, lexer.COMMA, func() data.Entry[ast.SPattern] {
 					return p.parsePatternRow()
@@ -1209,7 +1236,7 @@ func (p *parser) tryParsePattern(isDestructuring bool) (ast.SPattern, bool) {
 	case lexer.LSBRACKET:
 		{
 			p.iter.next()
-			if p.iter.next().Type == lexer.RSBRACKET {
+			if p.iter.peek().Type == lexer.RSBRACKET {
 				end := p.iter.next().Span
 				pat = ast.SListP{Elems: []ast.SPattern{}, Span: span(tk.Span, end)}
 			} else {
@@ -1358,10 +1385,11 @@ func (p *parser) parseTypeAtom(inCtor bool) (ast.SType, bool) {
 			var alias *string
 			if p.iter.peek().Type == lexer.DOT {
 				p.iter.next()
-				*alias = typ
+				name := typ
+				alias = &name
 				typ = *p.expect(lexer.UPPERIDENT, withError(data.TYPEALIAS_DOT)).Text
 			}
-			if !inCtor {
+			if inCtor {
 				ty = ast.STConst{Name: typ, Alias: alias, Span: span(tk.Span, p.iter.current.Span)}
 			} else {
 				tconst := ast.STConst{Name: typ, Alias: alias, Span: span(tk.Span, p.iter.current.Span)}
@@ -1567,7 +1595,7 @@ func between[T any](p *parser, ttype lexer.TokenType, fun func() T) []T {
 	return res
 }
 
-func (p *parser) expect(ttype lexer.TokenType, err func(lexer.Token) data.Tuple[string, data.Span]) lexer.Token {
+func (p *parser) expect(ttype lexer.TokenType, err func(lexer.Token) data.Tuple[data.Message, data.Span]) lexer.Token {
 	tk := p.iter.next()
 	if tk.Type == ttype {
 		return tk
@@ -1580,27 +1608,27 @@ func throwMismatchedIdentation(tk lexer.Token) {
 	throwError(withError(data.MISMATCHED_INDENTATION)(tk))
 }
 
-func throwError(err data.Tuple[string, data.Span]) any {
+func throwError(err data.Tuple[data.Message, data.Span]) any {
 	panic(ParserError{err.V1, err.V2})
 }
 
-func throwError2(msg string, span data.Span) any {
+func throwError2(msg data.Message, span data.Span) any {
 	panic(ParserError{msg, span})
 }
 
-func withError(msg string) func(lexer.Token) data.Tuple[string, data.Span] {
-	return func(tk lexer.Token) data.Tuple[string, data.Span] {
-		return data.Tuple[string, data.Span]{
+func withError(msg data.Message) func(lexer.Token) data.Tuple[data.Message, data.Span] {
+	return func(tk lexer.Token) data.Tuple[data.Message, data.Span] {
+		return data.Tuple[data.Message, data.Span]{
 			V1: msg,
 			V2: tk.Span,
 		}
 	}
 }
 
-func noErr() func(lexer.Token) data.Tuple[string, data.Span] {
-	return func(tk lexer.Token) data.Tuple[string, data.Span] {
-		return data.Tuple[string, data.Span]{
-			V1: "Cannot happen, token " + *tk.Text,
+func noErr() func(lexer.Token) data.Tuple[data.Message, data.Span] {
+	return func(tk lexer.Token) data.Tuple[data.Message, data.Span] {
+		return data.Tuple[data.Message, data.Span]{
+			V1: data.UnexpectedToken(*tk.Text),
 			V2: tk.Span,
 		}
 	}
@@ -1639,12 +1667,12 @@ func spanned[T any](x T, span data.Span) ast.Spanned[T] {
 }
 
 type ParserError struct {
-	msg  string
+	msg  data.Message
 	span data.Span
 }
 
 func (err ParserError) Error() string {
-	return err.msg
+	return err.msg.Text
 }
 
 type ModuleDef struct {
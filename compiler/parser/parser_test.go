@@ -3,10 +3,12 @@ package parser
 import (
 	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stackoverflow/novah-go/compiler/ast"
 	"github.com/stackoverflow/novah-go/compiler/lexer"
+	"github.com/stackoverflow/novah-go/data"
 	"github.com/stackoverflow/novah-go/test"
 )
 
@@ -77,11 +79,65 @@ func TestTypeHints(t *testing.T) {
 	test.Equals(t, fmtt.ShowType(x.Type), "String")
 }
 
+func TestTypeApplications(t *testing.T) {
+	code := `module test
+
+type Pair a b = Pair (Maybe a) b Int
+
+x : Maybe Int -> Int
+x _ = 1`
+	mod := parseString(strings.NewReader(code), "types", t)
+
+	ctor := mod.Decls[0].(ast.STypeDecl).DataCtors[0]
+	test.Equals(t, len(ctor.Args), 3)
+	test.Equals(t, fmtt.ShowType(ctor.Args[0]), "(Maybe a)")
+	test.Equals(t, fmtt.ShowType(ctor.Args[2]), "Int")
+	test.Equals(t, fmtt.ShowType(mod.Decls[1].(ast.SValDecl).Signature.Type), "Maybe Int -> Int")
+}
+
+func TestAliasedTypes(t *testing.T) {
+	code := `module test
+
+x : List M.Map -> M.Map
+x _ = 1`
+	mod := parseString(strings.NewReader(code), "aliased", t)
+
+	test.Equals(t, fmtt.ShowType(mod.Decls[0].(ast.SValDecl).Signature.Type), "List M.Map -> M.Map")
+}
+
 func TestRecords(t *testing.T) {
 	parseResource("../../test_data/records.novah", t)
 	// should not panic
 }
 
+func TestModuleNames(t *testing.T) {
+	code := `module data.list
+
+import data.option (Option)
+import data.map as M
+import prelude
+
+x = 1`
+	mod := parseString(strings.NewReader(code), "names", t)
+
+	test.Equals(t, mod.Name.Val, "data.list")
+	test.Equals(t, mod.Name.Span, data.NewSpan2(1, 8, 1, 17))
+	test.Equals(t, len(mod.Imports), 3)
+	test.Equals(t, mod.Imports[0].Module.Val, "data.option")
+	test.Equals(t, mod.Imports[1].Module.Val, "data.map")
+	test.Equals(t, mod.Imports[1].Alias, "M")
+	test.Equals(t, mod.Imports[2].Module.Val, "prelude")
+	test.Equals(t, mod.Imports[2].Span, data.NewSpan2(5, 1, 5, 15))
+}
+
+func TestInvalidModuleNames(t *testing.T) {
+	for _, code := range []string{"module data. list", "module data .list", "module data.list?", "module data.Map"} {
+		_, errs := NewParser(lexer.New("invalid", strings.NewReader(code))).ParseFullModule()
+		test.Equals(t, len(errs), 1)
+		test.Equals(t, errs[0].Code, data.MODULE_NAME.Code)
+	}
+}
+
 func parseResource(input string, t *testing.T) ast.SModule {
 	reader, _ := os.Open(input)
 	defer reader.Close()
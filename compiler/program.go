@@ -0,0 +1,130 @@
+package compiler
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	tc "github.com/stackoverflow/novah-go/compiler/typechecker"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+// The entry module of a program is compiled to the go main package
+// and its main function is called with the command line arguments.
+
+const ENTRY_FUNCTION = "main"
+
+// Generates the go main function:
+// func main() { _6d_ain(novah.Args()) }
+func (o *Optimizer) mainFunc() ast.GoFuncDecl {
+	pos := o.mod.Name.Span.Start
+	args := ast.GoCall{
+		Fn:   ast.GoVar{Name: "Args", Package: RUNTIME_PACKAGE, Pos: pos},
+		Args: []ast.GoExpr{},
+		Type: ast.GoTApp{Type: goVector, Args: []ast.GoType{ast.GoTConst{Name: "string"}}},
+		Pos:  pos,
+	}
+	var body ast.GoExpr = ast.GoCall{
+		Fn:   ast.GoVar{Name: o.topLevelName(ENTRY_FUNCTION, ""), Pos: pos},
+		Args: []ast.GoExpr{args},
+		Type: goUnit,
+		Pos:  pos,
+	}
+	return ast.GoFuncDecl{Name: "main", Body: &body, Pos: pos}
+}
+
+// The entry of a program without one: the module of the first source
+// that declares a main function, or of the first source if none does.
+// The first given file is always the first source (see ProgramSources),
+// so it's the entry when it declares main.
+func (env *Environment) entryOf(sources []Source) string {
+	for _, src := range sources {
+		name := env.moduleOf(src.Path)
+		if mod, has := env.modules[name]; has && declaresMain(mod.Ast) {
+			return name
+		}
+	}
+	if len(sources) == 0 {
+		return ""
+	}
+	return env.moduleOf(sources[0].Path)
+}
+
+func declaresMain(mod ast.Module) bool {
+	return data.AnySlice(mod.Decls, func(decl ast.Decl) bool {
+		d, isVal := decl.(ast.ValDecl)
+		return isVal && d.Name.Val == ENTRY_FUNCTION
+	})
+}
+
+// Checks that the entry module declares a main function
+// that takes the command line arguments.
+func (env *Environment) checkEntry() {
+	name := env.opts.Entry
+	mod, has := env.modules[name]
+	if !has {
+		env.errors = append(env.errors, data.CompilerProblem{
+			Msg:      data.ModuleNotFound(name).Text,
+			Code:     data.ModuleNotFound(name).Code,
+			Module:   name,
+			Severity: data.ERROR,
+		})
+		return
+	}
+
+	span := mod.Ast.Name.Span
+	for _, decl := range mod.Ast.Decls {
+		if d, isVal := decl.(ast.ValDecl); isVal && d.Name.Val == ENTRY_FUNCTION {
+			if isMainType(mod.Env.Decls[ENTRY_FUNCTION].Type) {
+				return
+			}
+			span = d.Name.Span
+		}
+	}
+	msg := data.MainFunctionExpected(name)
+	env.errors = append(env.errors, data.CompilerProblem{
+		Msg:      msg.Text,
+		Code:     msg.Code,
+		Span:     span,
+		Filename: mod.Ast.SourceName,
+		Module:   name,
+		Severity: data.ERROR,
+	})
+}
+
+// Returns true if the type is List String -> Unit.
+// Novah has no array type: lists are the immutable vectors
+// of the runtime, so the arguments are a List String.
+func isMainType(typ ast.Type) bool {
+	arr, isArr := ast.RealType(typ).(ast.TArrow)
+	if !isArr || len(arr.Args) != 1 {
+		return false
+	}
+	ret, isConst := ast.RealType(arr.Ret).(ast.TConst)
+	if !isConst || ret.Name != tc.PrimUnit {
+		return false
+	}
+	list, isApp := ast.RealType(arr.Args[0]).(ast.TApp)
+	if !isApp || len(list.Types) != 1 {
+		return false
+	}
+	con, isConst := ast.RealType(list.Type).(ast.TConst)
+	elem, isElemConst := ast.RealType(list.Types[0]).(ast.TConst)
+	return isConst && con.Name == tc.PrimList && isElemConst && elem.Name == tc.PrimString
+}
+
+// Runs the go tool in dir using the standard streams of this process.
+// Errors of the tool itself are returned as *exec.ExitError.
+func GoTool(dir string, args ...string) error {
+	gotool, err := exec.LookPath("go")
+	if err != nil {
+		return fmt.Errorf("could not find the go tool, make sure go is installed and in the PATH")
+	}
+	cmd := exec.Command(gotool, args...)
+	cmd.Dir = dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
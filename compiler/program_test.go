@@ -0,0 +1,111 @@
+package compiler
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stackoverflow/novah-go/compiler/typechecker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgramCodegen(t *testing.T) {
+	testGolden(t, "program", generateProgram, "main")
+}
+
+func TestMainFunctionExpected(t *testing.T) {
+	missing := `
+module test
+
+start _ = ()`
+
+	wrongType := `
+module test
+
+main : String -> Unit
+main _ = ()`
+
+	for _, code := range []string{missing, wrongType} {
+		comp := NewCompilerFromSources([]Source{{Path: "test", Str: code}}, Options{})
+		errs := comp.RunProgram(t.TempDir())
+
+		assert.Len(t, errs, 1)
+		assert.Equal(t, "N0111", errs[0].Code)
+		assert.Equal(t, "test", errs[0].Module)
+	}
+}
+
+func TestMainWithTypeErrors(t *testing.T) {
+	code := `
+module test
+
+main : List String -> Unit
+main args = args`
+
+	comp := NewCompilerFromSources([]Source{{Path: "test", Str: code}}, Options{})
+	errs := comp.RunProgram(t.TempDir())
+
+	assert.True(t, len(errs) > 0)
+	for _, err := range errs {
+		assert.False(t, err.Code == "N0111", err.Msg)
+	}
+}
+
+func TestEntryDeclaresMain(t *testing.T) {
+	lib := Source{Path: "a.novah", Str: "module alib\n\npub\nvalue : Int\nvalue = 1\n"}
+	app := Source{Path: "b.novah", Str: "module app\n\nmain : List String -> Unit\nmain _ = ()\n"}
+
+	// the sources of a directory or project are sorted by path
+	comp := NewCompilerFromSources([]Source{lib, app}, Options{})
+	assert.Empty(t, comp.RunProgram(t.TempDir()))
+	assert.Equal(t, "app", comp.Entry())
+
+	// without any main the first source is the entry
+	comp = NewCompilerFromSources([]Source{lib}, Options{})
+	errs := comp.RunProgram(t.TempDir())
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "N0111", errs[0].Code)
+	assert.Equal(t, "alib", comp.Entry())
+}
+
+func TestBuildProgram(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil || testing.Short() {
+		t.Skip("the go tool is needed to build programs")
+	}
+	code, err := os.ReadFile(filepath.Join("..", "test_data", "program", "main.novah"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := t.TempDir()
+	comp := NewCompilerFromSources([]Source{{Path: "main.novah", Str: string(code)}}, Options{GoModule: "example.com/prog"})
+	errs := comp.RunProgram(output)
+	assert.Empty(t, errs)
+
+	gomod, err := os.ReadFile(filepath.Join(output, "go.mod"))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(gomod), "module example.com/prog\n"))
+
+	bin := filepath.Join(output, "prog")
+	cmd := exec.Command("go", "build", "-o", bin, comp.EntryPackage())
+	cmd.Dir = output
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("could not build program: %s\n%s", err, out)
+	}
+	if out, err := exec.Command(bin, "a", "b").CombinedOutput(); err != nil {
+		t.Fatalf("program failed: %s\n%s", err, out)
+	}
+}
+
+func generateProgram(code string, t *testing.T) string {
+	env := compileCode(code, t)
+	opt := NewOptimizer(env.Ast, map[string]typechecker.FullModuleEnv{"test": env})
+	opt.main = true
+	pack := opt.Convert()
+
+	res := lineComment.ReplaceAllString(NewCodegen(pack).Run(), "")
+	return linePragma.ReplaceAllString(res, "")
+}
@@ -0,0 +1,233 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const PROJECT_FILE = "novah.json"
+
+// A novah project as declared in the project file.
+// Paths are relative to the directory of the project file.
+type Project struct {
+	// directories searched recursively for novah sources
+	Sources []string `json:"sources"`
+	// output directory for the generated go code
+	Output string `json:"output"`
+	// path of the generated go module
+	GoModule string `json:"goModule"`
+	// the module containing the main function
+	Entry string `json:"entry"`
+	// the directory of the project file
+	Root string `json:"-"`
+}
+
+const (
+	DEFAULT_SOURCE_ROOT = "src"
+	DEFAULT_OUTPUT      = "output"
+)
+
+// Reads the project file in this directory.
+func LoadProject(dir string) (*Project, error) {
+	path := filepath.Join(dir, PROJECT_FILE)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	var proj Project
+	if err := dec.Decode(&proj); err != nil {
+		return nil, fmt.Errorf("invalid project file %s: %s", path, err.Error())
+	}
+
+	proj.Root = dir
+	if len(proj.Sources) == 0 {
+		proj.Sources = []string{DEFAULT_SOURCE_ROOT}
+	}
+	if proj.Output == "" {
+		proj.Output = DEFAULT_OUTPUT
+	}
+	for _, src := range proj.Sources {
+		if filepath.IsAbs(src) {
+			return nil, fmt.Errorf("invalid project file %s: source root %s should be relative to the project", path, src)
+		}
+	}
+	return &proj, nil
+}
+
+// Looks for a project file in this directory and its parents.
+// Returns nil if none was found.
+func FindProject(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, PROJECT_FILE)); err == nil {
+			return LoadProject(dir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Returns the output directory of this project.
+func (p *Project) OutputDir() string {
+	return filepath.Join(p.Root, p.Output)
+}
+
+// Returns the source roots of this project.
+func (p *Project) SourceRoots() []string {
+	roots := make([]string, 0, len(p.Sources))
+	for _, src := range p.Sources {
+		roots = append(roots, filepath.Join(p.Root, src))
+	}
+	return roots
+}
+
+// Returns the sources to start compiling from:
+// the entry module if there's one, or all the sources.
+// Modules imported by the entry are loaded from the source roots.
+func (p *Project) EntrySources() ([]string, error) {
+	if p.Entry == "" {
+		return p.SourceFiles()
+	}
+	rel := filepath.Join(strings.Split(p.Entry, ".")...) + ".novah"
+	for _, root := range p.SourceRoots() {
+		path := filepath.Join(root, rel)
+		if _, err := os.Stat(path); err == nil {
+			return []string{path}, nil
+		}
+	}
+	return nil, fmt.Errorf("could not find entry module %s in the source roots", p.Entry)
+}
+
+// Returns all novah sources inside the source roots, sorted.
+func (p *Project) SourceFiles() ([]string, error) {
+	sources := make([]string, 0)
+	for _, dir := range p.SourceRoots() {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("source root %s does not exist", dir)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("source root %s is not a directory", dir)
+		}
+		files, err := findSources(dir)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, files...)
+	}
+	return dedup(sources), nil
+}
+
+// Resolves the sources for the command line arguments.
+// Files are used as is and directories are searched recursively.
+// Without arguments the entry sources of the project in dir are used.
+// Returns the project if one was found.
+func ResolveSources(args []string, dir string) ([]string, *Project, error) {
+	proj, err := FindProject(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(args) == 0 {
+		if proj == nil {
+			return nil, nil, fmt.Errorf("no sources given and no %s found in %s or its parents", PROJECT_FILE, dir)
+		}
+		sources, err := proj.EntrySources()
+		return sources, proj, err
+	}
+
+	sources := make([]string, 0, len(args))
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not find source %s", arg)
+		}
+		if info.IsDir() {
+			files, err := findSources(arg)
+			if err != nil {
+				return nil, nil, err
+			}
+			sources = append(sources, files...)
+		} else if strings.HasSuffix(arg, ".novah") {
+			sources = append(sources, arg)
+		} else {
+			return nil, nil, fmt.Errorf("%s is not a novah source file", arg)
+		}
+	}
+	return dedup(sources), proj, nil
+}
+
+// Resolves the sources of a program like ResolveSources,
+// but the first given file stays the first source as it's the entry of the program.
+func ProgramSources(args []string, dir string) ([]string, *Project, error) {
+	sources, proj, err := ResolveSources(args, dir)
+	if err != nil || len(args) == 0 {
+		return sources, proj, err
+	}
+	first := filepath.Clean(args[0])
+	for i, src := range sources {
+		if src == first {
+			rest := append(sources[:i:i], sources[i+1:]...)
+			return append([]string{src}, rest...), proj, nil
+		}
+	}
+	return sources, proj, nil
+}
+
+// Returns all novah files in this directory recursively.
+// Hidden directories are skipped.
+func findSources(dir string) ([]string, error) {
+	files := make([]string, 0)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".novah") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		var perr *fs.PathError
+		if errors.As(err, &perr) {
+			return nil, fmt.Errorf("could not read %s: %s", perr.Path, perr.Err.Error())
+		}
+		return nil, err
+	}
+	return files, nil
+}
+
+func dedup(paths []string) []string {
+	seen := make(map[string]bool)
+	res := make([]string, 0, len(paths))
+	for _, path := range paths {
+		clean := filepath.Clean(path)
+		if !seen[clean] {
+			seen[clean] = true
+			res = append(res, clean)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,125 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		PROJECT_FILE: `{"sources": ["src", "lib"], "output": "gen", "goModule": "example.com/app", "entry": "app.main"}`,
+	})
+
+	proj, err := LoadProject(root)
+	assert.Nil(t, err)
+	assert.Equal(t, &Project{Sources: []string{"src", "lib"}, Output: "gen", GoModule: "example.com/app", Entry: "app.main", Root: root}, proj)
+	assert.Equal(t, filepath.Join(root, "gen"), proj.OutputDir())
+}
+
+func TestProjectDefaults(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{PROJECT_FILE: `{}`})
+
+	proj, err := LoadProject(root)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{DEFAULT_SOURCE_ROOT}, proj.Sources)
+	assert.Equal(t, DEFAULT_OUTPUT, proj.Output)
+}
+
+func TestInvalidProject(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{PROJECT_FILE: `{"source": ["src"]}`})
+	_, err := LoadProject(root)
+	assert.NotNil(t, err)
+
+	writeFiles(t, root, map[string]string{PROJECT_FILE: `{"sources": ["/src"]}`})
+	_, err = LoadProject(root)
+	assert.NotNil(t, err)
+}
+
+func TestProjectSources(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		PROJECT_FILE:          `{"sources": ["src"]}`,
+		"src/main.novah":      "module main",
+		"src/data/list.novah": "module data.list",
+		"src/data/README.md":  "not a source",
+		"src/.hidden/x.novah": "module x",
+		"other/y.novah":       "module y",
+	})
+
+	// the project is found from a child directory
+	sources, proj, err := ResolveSources(nil, filepath.Join(root, "src", "data"))
+	assert.Nil(t, err)
+	assert.Equal(t, root, proj.Root)
+	assert.Equal(t, []string{filepath.Join(root, "src/data/list.novah"), filepath.Join(root, "src/main.novah")}, sources)
+}
+
+func TestResolveSourcesFromArgs(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a/one.novah":     "module one",
+		"a/b/two.novah":   "module two",
+		"three.novah":     "module three",
+		"notes.txt":       "not a source",
+		"a/b/three.novah": "module three2",
+	})
+
+	sources, proj, err := ResolveSources([]string{filepath.Join(root, "a"), filepath.Join(root, "three.novah"), filepath.Join(root, "a/one.novah")}, root)
+	assert.Nil(t, err)
+	assert.Nil(t, proj)
+	expected := []string{filepath.Join(root, "a/b/three.novah"), filepath.Join(root, "a/b/two.novah"), filepath.Join(root, "a/one.novah"), filepath.Join(root, "three.novah")}
+	assert.Equal(t, expected, sources)
+
+	_, _, err = ResolveSources([]string{filepath.Join(root, "notes.txt")}, root)
+	assert.NotNil(t, err)
+	_, _, err = ResolveSources([]string{filepath.Join(root, "missing.novah")}, root)
+	assert.NotNil(t, err)
+	_, _, err = ResolveSources(nil, root)
+	assert.NotNil(t, err)
+}
+
+func TestProgramSources(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"prog.novah":     "module prog",
+		"data/ops.novah": "module data.ops",
+	})
+
+	sources, _, err := ProgramSources([]string{filepath.Join(root, "prog.novah"), filepath.Join(root, "data")}, root)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "prog.novah"), filepath.Join(root, "data/ops.novah")}, sources)
+}
+
+func TestEntrySources(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		PROJECT_FILE:          `{"sources": ["lib", "src"], "entry": "app.main"}`,
+		"src/app/main.novah":  "module app.main",
+		"src/app/other.novah": "module app.other",
+	})
+
+	sources, _, err := ResolveSources(nil, root)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "src", "app", "main.novah")}, sources)
+
+	writeFiles(t, root, map[string]string{PROJECT_FILE: `{"entry": "app.missing"}`})
+	_, _, err = ResolveSources(nil, root)
+	assert.NotNil(t, err)
+}
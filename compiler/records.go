@@ -0,0 +1,496 @@
+// Code generation for records.
+// Records with a closed row and no duplicated labels are compiled to anonymous go structs,
+// every other record is a novah.Record from the runtime.
+// Values change representation when a polymorphic function is instantiated
+// with a closed record, so variables are coerced at their use sites.
+package compiler
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+var (
+	goRecord = ast.GoTConst{Name: "Record", Package: "novah"}
+	goField  = ast.GoTConst{Name: "Field", Package: "novah"}
+	goAny    = ast.GoTConst{Name: "any"}
+)
+
+func (o *Optimizer) convertRecordExpr(expr ast.Expr) ast.GoExpr {
+	switch e := expr.(type) {
+	case ast.RecordEmpty:
+		return ast.GoStructLit{Type: ast.GoTStruct{}, Pos: e.Span.Start}
+	case ast.RecordSelect:
+		{
+			sel, _ := o.recordSelect(o.convertExpr(e.Exp), e.Exp.GetType(), e.Label.Val)
+			return sel
+		}
+	case ast.RecordExtend:
+		{
+			typ := e.Type.Type
+			pos := e.Span.Start
+			entries := e.Labels.Entries()
+			exps := data.MapSlice(entries, func(ent data.Entry[ast.Expr]) ast.Expr { return ent.Val })
+			_, fromEmpty := e.Exp.(ast.RecordEmpty)
+			if !fromEmpty {
+				exps = append(exps, e.Exp)
+			}
+			operands := o.convertOperands(exps...)
+
+			vals := make(map[string]ast.GoExpr)
+			fields := make([]ast.GoExpr, 0, len(entries))
+			for i, ent := range entries {
+				vals[ent.Label] = operands[i]
+				fields = append(fields, recordField(ent.Label, operands[i]))
+			}
+
+			if isStructRecord(typ) {
+				if fromEmpty {
+					return o.structLit(typ, func(label string) ast.GoExpr { return vals[label] }, pos)
+				}
+				return o.bindOnce(operands[len(entries)], func(rec ast.GoExpr) ast.GoExpr {
+					return o.structLit(typ, func(label string) ast.GoExpr {
+						if val, has := vals[label]; has {
+							return val
+						}
+						sel, _ := o.recordSelect(rec, e.Exp.GetType(), label)
+						return sel
+					}, pos)
+				})
+			}
+			if fromEmpty {
+				return ast.GoCall{Fn: ast.GoVar{Name: "NewRecord", Package: "novah", Pos: pos}, Args: fields, Type: goRecord, Pos: pos}
+			}
+			rec := o.toRecord(operands[len(entries)], e.Exp.GetType())
+			return recordMethod(rec, "Extend", goRecord, fields...)
+		}
+	case ast.RecordRestrict:
+		{
+			from := e.Exp.GetType()
+			typ := e.Type.Type
+			exp := o.convertExpr(e.Exp)
+			if isStructRecord(from) && isStructRecord(typ) {
+				return o.bindOnce(exp, func(rec ast.GoExpr) ast.GoExpr {
+					return o.structLit(typ, func(label string) ast.GoExpr {
+						sel, _ := o.recordSelect(rec, from, label)
+						return sel
+					}, e.Span.Start)
+				})
+			}
+			res := recordMethod(o.toRecord(exp, from), "Restrict", goRecord, labelConst(e.Label, e.Span.Start))
+			return o.fromRecord(res, typ)
+		}
+	case ast.RecordUpdate:
+		{
+			typ := e.Type.Type
+			label := e.Label.Val
+			operands := o.convertOperands(e.Exp, e.Value)
+			exp, val := operands[0], operands[1]
+			newVal := func(rec ast.GoExpr) ast.GoExpr {
+				if e.IsSet {
+					return val
+				}
+				old, _ := o.recordSelect(rec, typ, label)
+				return ast.GoCall{Fn: val, Args: []ast.GoExpr{old}, Type: old.GetType(), Pos: val.GetPos()}
+			}
+
+			if isStructRecord(typ) {
+				return o.bindOnce(exp, func(rec ast.GoExpr) ast.GoExpr {
+					return o.structLit(typ, func(l string) ast.GoExpr {
+						if l == label {
+							return newVal(rec)
+						}
+						sel, _ := o.recordSelect(rec, typ, l)
+						return sel
+					}, e.Span.Start)
+				})
+			}
+			if e.IsSet {
+				return recordMethod(exp, "Update", goRecord, labelConst(label, e.Label.Span.Start), newVal(exp))
+			}
+			return o.bindOnce(exp, func(rec ast.GoExpr) ast.GoExpr {
+				return recordMethod(rec, "Update", goRecord, labelConst(label, e.Label.Span.Start), newVal(rec))
+			})
+		}
+	case ast.RecordMerge:
+		{
+			typ := e.Type.Type
+			ty1 := e.Exp1.GetType()
+			ty2 := e.Exp2.GetType()
+			operands := o.convertOperands(e.Exp1, e.Exp2)
+			exp1, exp2 := operands[0], operands[1]
+			if isStructRecord(typ) && isStructRecord(ty1) && isStructRecord(ty2) {
+				labels2, _ := recordRow(ty2)
+				return o.bindOnce(exp1, func(rec1 ast.GoExpr) ast.GoExpr {
+					return o.bindOnce(exp2, func(rec2 ast.GoExpr) ast.GoExpr {
+						return o.structLit(typ, func(label string) ast.GoExpr {
+							if hasLabel(labels2, label) {
+								sel, _ := o.recordSelect(rec2, ty2, label)
+								return sel
+							}
+							sel, _ := o.recordSelect(rec1, ty1, label)
+							return sel
+						}, e.Span.Start)
+					})
+				})
+			}
+			res := recordMethod(o.toRecord(exp1, ty1), "Merge", goRecord, o.toRecord(exp2, ty2))
+			return o.fromRecord(res, typ)
+		}
+	default:
+		panic("unknow record expression")
+	}
+}
+
+// Converts a record type to a struct if the row is closed
+// and has no duplicated labels or to a novah.Record otherwise.
+func (o *Optimizer) convertRecordType(typ ast.TRecord) ast.GoType {
+	if !isStructRecord(typ) {
+		return goRecord
+	}
+	labels, _ := recordRow(typ)
+	sortLabels(labels)
+	fields := make([]ast.GoTField, 0, len(labels))
+	for _, ent := range labels {
+		fields = append(fields, ast.GoTField{Name: recordFieldName(ent.Label), Type: o.convertType(ent.Val)})
+	}
+	return ast.GoTStruct{Fields: fields}
+}
+
+// Selects the label from the record.
+// Returns the type of the field or nil if the label
+// is not known (it's part of the row variable).
+func (o *Optimizer) recordSelect(rec ast.GoExpr, typ ast.Type, label string) (ast.GoExpr, ast.Type) {
+	labels, _ := recordRow(typ)
+	var fieldTy ast.Type
+	if ent, found := data.FindSlice(labels, func(e data.Entry[ast.Type]) bool { return e.Label == label }); found {
+		fieldTy = ent.Val
+	}
+	pos := rec.GetPos()
+	if isStructRecord(typ) {
+		if lit, isLit := rec.(ast.GoStructLit); isLit {
+			field, _ := data.FindSlice(lit.Fields, func(f ast.GoFieldVal) bool { return f.Name == recordFieldName(label) })
+			return field.Exp, fieldTy
+		}
+		return ast.GoField{Exp: rec, Name: recordFieldName(label), Type: o.convertType(fieldTy), Pos: pos}, fieldTy
+	}
+	sel := recordMethod(rec, "Select", goAny, labelConst(label, pos))
+	if fieldTy == nil {
+		return sel, nil
+	}
+	return assertType(sel, o.convertType(fieldTy)), fieldTy
+}
+
+// Creates a struct of the record type getting the value of each label from f
+func (o *Optimizer) structLit(typ ast.Type, f func(string) ast.GoExpr, pos data.Pos) ast.GoExpr {
+	labels, _ := recordRow(typ)
+	sortLabels(labels)
+	fields := make([]ast.GoFieldVal, 0, len(labels))
+	for _, ent := range labels {
+		fields = append(fields, ast.GoFieldVal{Name: recordFieldName(ent.Label), Exp: f(ent.Label)})
+	}
+	return ast.GoStructLit{Fields: fields, Type: o.convertType(typ), Pos: pos}
+}
+
+// Converts a record of this type to a novah.Record
+func (o *Optimizer) toRecord(exp ast.GoExpr, typ ast.Type) ast.GoExpr {
+	if !isStructRecord(typ) {
+		return exp
+	}
+	pos := exp.GetPos()
+	newRecord := ast.GoVar{Name: "NewRecord", Package: "novah", Pos: pos}
+	return o.bindOnce(exp, func(rec ast.GoExpr) ast.GoExpr {
+		labels, _ := recordRow(typ)
+		sortLabels(labels)
+		fields := make([]ast.GoExpr, 0, len(labels))
+		for _, ent := range labels {
+			sel, _ := o.recordSelect(rec, typ, ent.Label)
+			fields = append(fields, recordField(ent.Label, sel))
+		}
+		return ast.GoCall{Fn: newRecord, Args: fields, Type: goRecord, Pos: pos}
+	})
+}
+
+// Converts a novah.Record to the representation of this type
+func (o *Optimizer) fromRecord(exp ast.GoExpr, typ ast.Type) ast.GoExpr {
+	if !isStructRecord(typ) {
+		return exp
+	}
+	return o.bindOnce(exp, func(rec ast.GoExpr) ast.GoExpr {
+		return o.structLit(typ, func(label string) ast.GoExpr {
+			ty, _ := findLabel(typ, label)
+			sel := recordMethod(rec, "Select", goAny, labelConst(label, rec.GetPos()))
+			return assertType(sel, o.convertType(ty))
+		}, exp.GetPos())
+	})
+}
+
+// The type of the variable before it was instantiated
+// or nil if it's not a let or top level declaration.
+func (o *Optimizer) declaredType(name, module string) ast.Type {
+	if module == "" {
+		if ty, has := o.locals[name]; has {
+			return ty
+		}
+		module = o.mod.Name.Val
+	}
+	if mod, has := o.modules[module]; has {
+		if decl, has := mod.Env.Decls[name]; has {
+			return decl.Type
+		}
+	}
+	return nil
+}
+
+// Returns true if the representation of some record
+// changes between the types `from` and `to`
+// or if one of them is represented as `any`.
+func (o *Optimizer) needsCoercion(from, to ast.Type) bool {
+	switch f := ast.RealType(from).(type) {
+	case ast.TRecord:
+		{
+			t, isRec := ast.RealType(to).(ast.TRecord)
+			if !isRec {
+				return false
+			}
+			if !reflect.DeepEqual(o.convertType(f), o.convertType(t)) {
+				return true
+			}
+			labels, _ := recordRow(f)
+			for _, ent := range labels {
+				if ty, found := findLabel(t, ent.Label); found && o.needsCoercion(ent.Val, ty) {
+					return true
+				}
+			}
+			return false
+		}
+	case ast.TArrow:
+		{
+			t, isArr := ast.RealType(to).(ast.TArrow)
+			return isArr && (o.needsCoercion(f.Args[0], t.Args[0]) || o.needsCoercion(f.Ret, t.Ret))
+		}
+	default:
+		return (o.convertType(from) == goAny) != (o.convertType(to) == goAny)
+	}
+}
+
+// Converts exp from the representation of the type `from` to the representation of `to`.
+// `from` is nil when the type of the value is unknown.
+func (o *Optimizer) coerce(exp ast.GoExpr, from, to ast.Type) ast.GoExpr {
+	toGo := o.convertType(to)
+	if from == nil {
+		return assertType(exp, toGo)
+	}
+	fromGo := o.convertType(from)
+	if reflect.DeepEqual(toGo, goAny) || (reflect.DeepEqual(fromGo, toGo) && !o.needsCoercion(from, to)) {
+		return exp
+	}
+	if reflect.DeepEqual(fromGo, goAny) {
+		return assertType(exp, toGo)
+	}
+
+	switch f := ast.RealType(from).(type) {
+	case ast.TRecord:
+		if t, isRec := ast.RealType(to).(ast.TRecord); isRec {
+			return o.coerceRecord(exp, f, t)
+		}
+	case ast.TArrow:
+		if t, isArr := ast.RealType(to).(ast.TArrow); isArr {
+			return o.bindOnce(exp, func(fun ast.GoExpr) ast.GoExpr {
+				pos := exp.GetPos()
+				param := o.newTmp()
+				paramTy := o.convertType(t.Args[0])
+				var retTy ast.GoType
+				body := o.block(func() ast.GoExpr {
+					arg := o.coerce(ast.GoVar{Name: param, Type: paramTy, Pos: pos}, t.Args[0], f.Args[0])
+					call := ast.GoCall{Fn: fun, Args: []ast.GoExpr{arg}, Type: o.convertType(f.Ret), Pos: pos}
+					ret := o.coerce(call, f.Ret, t.Ret)
+					retTy = ret.GetType()
+					return ast.GoReturn{Exp: ret, Pos: pos}
+				})
+				return ast.GoFunc{
+					Args:    []ast.GoParam{{Name: param, Type: paramTy}},
+					Returns: []ast.GoType{retTy},
+					Body:    body,
+					Type:    toGo,
+					Pos:     pos,
+				}
+			})
+		}
+	}
+	return exp
+}
+
+func (o *Optimizer) coerceRecord(exp ast.GoExpr, from, to ast.TRecord) ast.GoExpr {
+	pos := exp.GetPos()
+	return o.bindOnce(exp, func(rec ast.GoExpr) ast.GoExpr {
+		if isStructRecord(to) {
+			return o.structLit(to, func(label string) ast.GoExpr {
+				sel, ty := o.recordSelect(rec, from, label)
+				toTy, _ := findLabel(to, label)
+				return o.coerce(sel, ty, toTy)
+			}, pos)
+		}
+
+		labels, _ := recordRow(from)
+		if isStructRecord(from) {
+			sortLabels(labels)
+			fields := make([]ast.GoExpr, 0, len(labels))
+			for _, ent := range labels {
+				sel, _ := o.recordSelect(rec, from, ent.Label)
+				if toTy, found := findLabel(to, ent.Label); found {
+					sel = o.coerce(sel, ent.Val, toTy)
+				}
+				fields = append(fields, recordField(ent.Label, sel))
+			}
+			return ast.GoCall{Fn: ast.GoVar{Name: "NewRecord", Package: "novah", Pos: pos}, Args: fields, Type: goRecord, Pos: pos}
+		}
+
+		// both are novah.Record, only the fields need to change
+		res := rec
+		for _, ent := range labels {
+			if toTy, found := findLabel(to, ent.Label); found && o.needsCoercion(ent.Val, toTy) {
+				sel, _ := o.recordSelect(rec, from, ent.Label)
+				res = recordMethod(res, "Update", goRecord, labelConst(ent.Label, pos), o.coerce(sel, ent.Val, toTy))
+			}
+		}
+		return res
+	})
+}
+
+// Calls f with an expression equivalent to exp that can be safely duplicated.
+// Expressions other than variables are bound to a temporary.
+func (o *Optimizer) bindOnce(exp ast.GoExpr, f func(ast.GoExpr) ast.GoExpr) ast.GoExpr {
+	if isSimpleExpr(exp) {
+		return f(exp)
+	}
+	pos := exp.GetPos()
+	name := o.newTmp()
+	o.emit(ast.GoLet{Binder: name, BindExpr: exp, Type: exp.GetType(), Pos: pos})
+	return f(ast.GoVar{Name: name, Type: exp.GetType(), Pos: pos})
+}
+
+func isSimpleExpr(exp ast.GoExpr) bool {
+	switch e := exp.(type) {
+	case ast.GoVar, ast.GoConst:
+		return true
+	case ast.GoField:
+		return isSimpleExpr(e.Exp)
+	case ast.GoStructLit:
+		// selecting from a literal returns the field directly
+		return !data.AnySlice(e.Fields, func(f ast.GoFieldVal) bool { return !isSimpleExpr(f.Exp) })
+	default:
+		return false
+	}
+}
+
+// Returns the labels of the record type and true if the row is closed
+func recordRow(typ ast.Type) ([]data.Entry[ast.Type], bool) {
+	labels := make([]data.Entry[ast.Type], 0, 4)
+	row := typ
+	for {
+		switch t := row.(type) {
+		case ast.TRecord:
+			row = t.Row
+		case ast.TRowExtend:
+			labels = append(labels, t.Labels.Entries()...)
+			row = t.Row
+		case ast.TRowEmpty:
+			return labels, true
+		case ast.TVar:
+			if t.Tvar.Tag != ast.LINK {
+				return labels, false
+			}
+			row = t.Tvar.Type
+		default:
+			return labels, false
+		}
+	}
+}
+
+// Records are structs if all the labels are known and distinct
+func isStructRecord(typ ast.Type) bool {
+	labels, closed := recordRow(typ)
+	if !closed {
+		return false
+	}
+	seen := data.NewSet[string]()
+	for _, ent := range labels {
+		if seen.Contains(ent.Label) {
+			return false
+		}
+		seen.Add(ent.Label)
+	}
+	return true
+}
+
+// Returns the type of the visible label of the record type
+func findLabel(typ ast.Type, label string) (ast.Type, bool) {
+	labels, _ := recordRow(typ)
+	ent, found := data.FindSlice(labels, func(e data.Entry[ast.Type]) bool { return e.Label == label })
+	return ent.Val, found
+}
+
+func hasLabel(labels []data.Entry[ast.Type], label string) bool {
+	return data.AnySlice(labels, func(e data.Entry[ast.Type]) bool { return e.Label == label })
+}
+
+// Struct fields are sorted so the same record type
+// is always the same go type
+func sortLabels(labels []data.Entry[ast.Type]) {
+	sort.SliceStable(labels, func(i, j int) bool { return labels[i].Label < labels[j].Label })
+}
+
+func recordMethod(rec ast.GoExpr, method string, typ ast.GoType, args ...ast.GoExpr) ast.GoExpr {
+	return ast.GoCall{
+		Fn:   ast.GoField{Exp: rec, Name: method, Pos: rec.GetPos()},
+		Args: args,
+		Type: typ,
+		Pos:  rec.GetPos(),
+	}
+}
+
+func recordField(label string, val ast.GoExpr) ast.GoExpr {
+	return ast.GoStructLit{
+		Fields: []ast.GoFieldVal{{Name: "Label", Exp: labelConst(label, val.GetPos())}, {Name: "Val", Exp: val}},
+		Type:   goField,
+		Pos:    val.GetPos(),
+	}
+}
+
+func labelConst(label string, pos data.Pos) ast.GoExpr {
+	return ast.GoConst{V: strconv.Quote(label), Type: ast.GoTConst{Name: "string"}, Pos: pos}
+}
+
+func assertType(exp ast.GoExpr, typ ast.GoType) ast.GoExpr {
+	if reflect.DeepEqual(typ, goAny) {
+		return exp
+	}
+	return ast.GoTypeAssert{Exp: exp, Type: typ, Pos: exp.GetPos()}
+}
+
+// The name of the struct field of a record label.
+// Fields are exported so the same record type can be used by different packages,
+// underscores are doubled and runes not allowed in identifiers are escaped.
+func recordFieldName(label string) string {
+	var sb strings.Builder
+	sb.WriteString("F_")
+	for _, r := range label {
+		switch {
+		case r == '_':
+			sb.WriteString("__")
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		default:
+			sb.WriteString(fmt.Sprintf("_%x_", r))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,18 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordCodegen(t *testing.T) {
+	testGolden(t, "records", generateFunctions, "closed", "open")
+}
+
+func TestRecordFieldNames(t *testing.T) {
+	assert.Equal(t, "F_name", recordFieldName("name"))
+	assert.Equal(t, "F_first__name", recordFieldName("first_name"))
+	assert.Equal(t, "F_first_20_name", recordFieldName("first name"))
+	assert.Equal(t, "F_Blabla", recordFieldName("Blabla"))
+}
@@ -0,0 +1,126 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/stackoverflow/novah-go/data"
+)
+
+// The same sources always compile to byte-identical go code,
+// so the output can be committed and the builds cached.
+
+// Compiles the sources again and checks that the result is the same as the output already written.
+// The sources are compiled to a temporary directory next to the output: the line directives
+// and source maps name the sources relative to the output, so they are the same at the same depth.
+// Only the files the compiler writes are compared, so other files in the output
+// like a readme, a go.sum or a built binary don't matter.
+// Returns the hash of the output or an error listing the files that are different.
+func VerifyReproducible(sources []string, opts Options, output string) (string, []data.CompilerProblem, error) {
+	abs, err := filepath.Abs(output)
+	if err != nil {
+		return "", nil, err
+	}
+	dir, err := os.MkdirTemp(filepath.Dir(abs), "."+filepath.Base(abs)+"-verify")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	problems := NewCompiler(sources, opts).Run(dir, false)
+	if errs, _ := data.CountProblems(problems); errs > 0 {
+		return "", problems, nil
+	}
+	again, err := hashOutput(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	written, err := hashFiles(output, again)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if diff := diffHashes(written, again); len(diff) > 0 {
+		return "", nil, fmt.Errorf("the output is not reproducible, these files are different when compiled again: %s", strings.Join(diff, ", "))
+	}
+	return combinedHash(written), nil, nil
+}
+
+// Returns the sha256 of every file in the output by their path relative to dir
+func hashOutput(dir string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		hashes[filepath.ToSlash(rel)] = hex.EncodeToString(sum[:])
+		return nil
+	})
+	return hashes, err
+}
+
+// Returns the sha256 of the files of the output that are also in
+// the hashes of another output. Missing files are left out.
+func hashFiles(dir string, hashes map[string]string) (map[string]string, error) {
+	files := make(map[string]string, len(hashes))
+	for path := range hashes {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+		files[path] = hex.EncodeToString(sum[:])
+	}
+	return files, nil
+}
+
+// The files that are different, missing or extra in the second output, sorted
+func diffHashes(first, second map[string]string) []string {
+	diff := make([]string, 0)
+	for path, hash := range first {
+		if second[path] != hash {
+			diff = append(diff, path)
+		}
+	}
+	for path := range second {
+		if _, has := first[path]; !has {
+			diff = append(diff, path)
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+// A single hash for the whole output
+func combinedHash(hashes map[string]string) string {
+	paths := make([]string, 0, len(hashes))
+	for path := range hashes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(h, "%s %s\n", hashes[path], path)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
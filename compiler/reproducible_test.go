@@ -0,0 +1,88 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyReproducible(t *testing.T) {
+	root := t.TempDir()
+	src, output := filepath.Join(root, "src"), filepath.Join(root, "output")
+	writeFiles(t, src, packagesCode)
+
+	opts := Options{SourceRoots: []string{src}}
+	sources := []string{filepath.Join(src, "app.novah")}
+	assert.Empty(t, NewCompiler(sources, opts).Run(output, false))
+
+	hash, problems, err := VerifyReproducible(sources, opts, output)
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+	written, err := hashOutput(output)
+	assert.Nil(t, err)
+	assert.Equal(t, combinedHash(written), hash)
+
+	// the temporary output is removed
+	entries, err := os.ReadDir(root)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+
+	// files not written by the compiler are ignored
+	writeFiles(t, output, map[string]string{"README.md": "# app\n", "go.sum": "", "app/app": "binary", "old/old.go": "package old\n"})
+	again, _, err := VerifyReproducible(sources, opts, output)
+	assert.Nil(t, err)
+	assert.Equal(t, hash, again)
+
+	writeFiles(t, output, map[string]string{"app/app.go": "package app\n"})
+	_, _, err = VerifyReproducible(sources, opts, output)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "app/app.go")
+
+	assert.Nil(t, os.Remove(filepath.Join(output, "go.mod")))
+	_, _, err = VerifyReproducible(sources, opts, output)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "go.mod")
+}
+
+func TestVerifyReproducibleErrors(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"app.novah": "module app\n\nx = y\n"})
+
+	_, problems, err := VerifyReproducible([]string{filepath.Join(root, "app.novah")}, Options{}, filepath.Join(root, "output"))
+	assert.Nil(t, err)
+	assert.True(t, len(problems) > 0)
+}
+
+func TestDiffHashes(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writeFiles(t, first, map[string]string{"a.go": "package a", "b/b.go": "package b", "c.go": "package c"})
+	writeFiles(t, second, map[string]string{"a.go": "package a", "b/b.go": "package bb", "d.go": "package d"})
+
+	h1, err := hashOutput(first)
+	assert.Nil(t, err)
+	h2, err := hashOutput(second)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b/b.go", "c.go", "d.go"}, diffHashes(h1, h2))
+	assert.Empty(t, diffHashes(h1, h1))
+	assert.True(t, combinedHash(h1) != combinedHash(h2))
+}
+
+func TestParamsOrder(t *testing.T) {
+	intTy := ast.GoTConst{Name: "int"}
+	var body ast.GoExpr = ast.GoReturn{Exp: ast.GoVar{Name: "b", Type: intTy}}
+	fun := ast.GoFuncDecl{
+		Name:    "snd",
+		Params:  []ast.GoParam{{Name: "b", Type: intTy}, {Name: "a", Type: intTy}, {Name: "c", Type: intTy}},
+		Returns: []ast.GoType{intTy},
+		Body:    &body,
+	}
+
+	for i := 0; i < 20; i++ {
+		c := NewCodegen(ast.GoPackage{Name: "p", Decls: []ast.GoDecl{fun}})
+		c.genDecl(fun)
+		assert.Contains(t, c.sb.String(), "func snd(b int, a int, c int) int {\n")
+	}
+}
@@ -0,0 +1,140 @@
+package compiler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Every generated go file has a source map next to it (list.go has list.go.map)
+// linking its lines to the novah code they came from, for tools that only see go positions.
+// It has the same information as the line directives of the file.
+
+const SOURCE_MAP_EXT = ".map"
+
+// The json schema is versioned and
+// new fields are only added in new versions
+const sourceMapVersion = 1
+
+type SourceMap struct {
+	Version int `json:"version"`
+	// the go file, relative to the directory of the map
+	File   string `json:"file"`
+	Module string `json:"module"`
+	// the novah source, relative to the directory of the map
+	Source   string        `json:"source"`
+	Mappings []LineMapping `json:"mappings"`
+}
+
+// The go lines from GoStart to GoEnd were generated from the novah code
+// starting at Line and Col, one novah line for each go line like the line directives.
+type LineMapping struct {
+	GoStart int `json:"goStart"`
+	GoEnd   int `json:"goEnd"`
+	Line    int `json:"line"`
+	Col     int `json:"col"`
+}
+
+// Builds the source map of a go file from its line directives
+func NewSourceMap(file, module, source, code string) *SourceMap {
+	sm := &SourceMap{Version: sourceMapVersion, File: file, Module: module, Source: source, Mappings: []LineMapping{}}
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "//line ") {
+			continue
+		}
+		pos, valid := parseLineDirective(line)
+		if !valid {
+			continue
+		}
+		// the directive applies to the next line
+		if last := len(sm.Mappings) - 1; last >= 0 {
+			sm.Mappings[last].GoEnd = i
+		}
+		pos.GoStart = i + 2
+		pos.GoEnd = len(lines)
+		sm.Mappings = append(sm.Mappings, pos)
+	}
+	// drop the mappings of directives followed by other directives
+	valid := sm.Mappings[:0]
+	for _, mapping := range sm.Mappings {
+		if mapping.GoStart <= mapping.GoEnd {
+			valid = append(valid, mapping)
+		}
+	}
+	sm.Mappings = valid
+	return sm
+}
+
+// Parses the position of a //line file:line:col or //line file:line directive
+func parseLineDirective(directive string) (LineMapping, bool) {
+	text := strings.TrimPrefix(directive, "//line ")
+	colon := strings.LastIndex(text, ":")
+	if colon == -1 {
+		return LineMapping{}, false
+	}
+	n, err := strconv.Atoi(text[colon+1:])
+	if err != nil {
+		return LineMapping{}, false
+	}
+	if colon2 := strings.LastIndex(text[:colon], ":"); colon2 != -1 {
+		if line, err := strconv.Atoi(text[colon2+1 : colon]); err == nil {
+			return LineMapping{Line: line, Col: n}, line > 0
+		}
+	}
+	return LineMapping{Line: n}, n > 0
+}
+
+// Returns the novah line of this go line and false if the line
+// was not generated from novah code
+func (sm *SourceMap) Lookup(goLine int) (int, bool) {
+	for _, mapping := range sm.Mappings {
+		if goLine >= mapping.GoStart && goLine <= mapping.GoEnd {
+			return mapping.Line + goLine - mapping.GoStart, true
+		}
+	}
+	return 0, false
+}
+
+// Reads the source map of this go file
+func ReadSourceMap(goFile string) (*SourceMap, error) {
+	content, err := os.ReadFile(goFile + SOURCE_MAP_EXT)
+	if err != nil {
+		return nil, err
+	}
+	var sm SourceMap
+	if err := json.Unmarshal(content, &sm); err != nil {
+		return nil, err
+	}
+	return &sm, nil
+}
+
+// Writes the source map next to its go file
+func (sm *SourceMap) Write(goFile string) error {
+	content, err := json.MarshalIndent(sm, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(goFile+SOURCE_MAP_EXT, append(content, '\n'), 0644)
+}
+
+// The novah source as named in the line directives of a go file in dir.
+// Go resolves relative names from the directory of the go file, so the code
+// points to the source wherever the project is, as long as the output moves with it.
+func lineFile(dir, source string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return filepath.ToSlash(source)
+	}
+	absSource, err := filepath.Abs(source)
+	if err != nil {
+		return filepath.ToSlash(source)
+	}
+	rel, err := filepath.Rel(absDir, absSource)
+	if err != nil {
+		return filepath.ToSlash(absSource)
+	}
+	return filepath.ToSlash(rel)
+}
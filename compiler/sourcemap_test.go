@@ -0,0 +1,102 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineDirectives(t *testing.T) {
+	root := t.TempDir()
+	src, output := filepath.Join(root, "src"), filepath.Join(root, "output")
+	writeFiles(t, src, map[string]string{"app.novah": `module app
+
+loop : Bool -> Unit
+loop b =
+  while b do
+    loop false
+  ()
+`})
+
+	c := NewCompiler([]string{filepath.Join(src, "app.novah")}, Options{})
+	assert.Empty(t, c.Run(output, false))
+
+	path := filepath.Join(output, "app", "app.go")
+	app, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(app), "//line ../../src/app.novah:1\npackage app\n")
+	assert.Contains(t, string(app), "\n//line ../../src/app.novah:3:1\nfunc loop(b bool) novah.Unit {\n")
+	assert.Contains(t, string(app), "{\n//line ../../src/app.novah:5:3\n\tfor b {\n//line ../../src/app.novah:6:5\n\t\tloop(false)\n")
+
+	sm, err := ReadSourceMap(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "app.go", sm.File)
+	assert.Equal(t, "app", sm.Module)
+	assert.Equal(t, "../../src/app.novah", sm.Source)
+	line, found := sm.Lookup(lineOf(t, string(app), "\t\tloop(false)"))
+	assert.True(t, found)
+	assert.Equal(t, 6, line)
+}
+
+func TestNewSourceMap(t *testing.T) {
+	code := `//line app.novah:1
+package app
+
+//line app.novah:3:1
+func f() int {
+//line app.novah:4:3
+//line app.novah:5:3
+	return 1 +
+		2
+}
+`
+	sm := NewSourceMap("app.go", "app", "app.novah", code)
+	assert.Equal(t, []LineMapping{
+		{GoStart: 2, GoEnd: 3, Line: 1},
+		{GoStart: 5, GoEnd: 5, Line: 3, Col: 1},
+		{GoStart: 8, GoEnd: 11, Line: 5, Col: 3},
+	}, sm.Mappings)
+
+	tests := map[int]int{2: 1, 3: 2, 5: 3, 8: 5, 9: 6, 10: 7}
+	for goLine, line := range tests {
+		found, ok := sm.Lookup(goLine)
+		assert.True(t, ok, goLine)
+		assert.Equal(t, line, found, goLine)
+	}
+	_, ok := sm.Lookup(1)
+	assert.False(t, ok)
+	_, ok = sm.Lookup(6)
+	assert.False(t, ok)
+}
+
+func TestTraceSourceMap(t *testing.T) {
+	dir := t.TempDir()
+	goFile := filepath.Join(dir, "data", "list", "list.go")
+	writeFiles(t, dir, map[string]string{"data/list/list.go": ""})
+	sm := &SourceMap{Version: sourceMapVersion, File: "list.go", Module: "data.list", Source: "../../src/data/list.novah",
+		Mappings: []LineMapping{{GoStart: 10, GoEnd: 20, Line: 4, Col: 3}}}
+	assert.Nil(t, sm.Write(goFile))
+
+	novah := filepath.Join(dir, "src", "data", "list.novah")
+	d := NewDemangler("app", "")
+	assert.Equal(t, "\t"+novah+":6 +0x1d", d.Line("\t"+goFile+":12 +0x1d"))
+	assert.Equal(t, "\t"+novah+":4", d.Line("\t"+goFile+":10"))
+	// lines without a mapping or files without a map are kept
+	assert.Equal(t, "\t"+goFile+":30 +0x1d", d.Line("\t"+goFile+":30 +0x1d"))
+	assert.Equal(t, "\t/usr/lib/go/src/runtime/panic.go:770 +0x124", d.Line("\t/usr/lib/go/src/runtime/panic.go:770 +0x124"))
+	assert.Equal(t, "\t"+novah+":6 +0x1d", d.Line("\t"+novah+":6 +0x1d"))
+}
+
+// The 1-based line of the first line of code equal to text
+func lineOf(t *testing.T, code, text string) int {
+	for i, line := range strings.Split(code, "\n") {
+		if line == text {
+			return i + 1
+		}
+	}
+	t.Fatalf("%q not found", text)
+	return 0
+}
@@ -8,14 +8,26 @@ type InstanceEnv struct {
 	TypeName    string
 }
 
+// A constructor of a data type
+type CtorRef struct {
+	Name  string
+	Arity int
+}
+
 type Env struct {
 	env       map[string]ast.Type
 	types     map[string]ast.Type
 	instances map[string]InstanceEnv
+	ctors     map[string][]CtorRef
 }
 
 func NewEnv() *Env {
-	return &Env{env: make(map[string]ast.Type), types: make(map[string]ast.Type), instances: make(map[string]InstanceEnv)}
+	return &Env{
+		env:       make(map[string]ast.Type),
+		types:     make(map[string]ast.Type),
+		instances: make(map[string]InstanceEnv),
+		ctors:     make(map[string][]CtorRef),
+	}
 }
 
 func (e *Env) Extend(name string, typ ast.Type) {
@@ -40,6 +52,17 @@ func (e *Env) LookupType(name string) (ast.Type, bool) {
 	return ty, found
 }
 
+// Sets all the constructors of the type `name`.
+// Used to check the exhaustiveness of pattern matches.
+func (e *Env) ExtendCtors(name string, ctors []CtorRef) {
+	e.ctors[name] = ctors
+}
+
+func (e *Env) LookupCtors(name string) ([]CtorRef, bool) {
+	ctors, found := e.ctors[name]
+	return ctors, found
+}
+
 func (e *Env) ExtendInstance(name string, typ ast.Type, isLambdaVar bool) {
 	e.instances[name] = InstanceEnv{Type: typ, IsLambdaVar: isLambdaVar}
 }
@@ -62,6 +85,7 @@ func (e *Env) Fork() *Env {
 	env := make(map[string]ast.Type)
 	types := make(map[string]ast.Type)
 	instances := make(map[string]InstanceEnv)
+	ctors := make(map[string][]CtorRef)
 
 	for k, v := range e.env {
 		env[k] = v
@@ -72,7 +96,10 @@ func (e *Env) Fork() *Env {
 	for k, v := range e.instances {
 		instances[k] = v
 	}
-	return &Env{env: env, types: types, instances: instances}
+	for k, v := range e.ctors {
+		ctors[k] = v
+	}
+	return &Env{env: env, types: types, instances: instances, ctors: ctors}
 }
 
 // Default types
@@ -124,6 +151,9 @@ var tRune = ast.TConst{Name: PrimRune}
 
 var tUnit = ast.TConst{Name: PrimUnit}
 
+var tList = ast.TConst{Name: PrimList, Kind: ast.Kind{Type: ast.CTOR, Arity: 1}}
+var tSet = ast.TConst{Name: PrimSet, Kind: ast.Kind{Type: ast.CTOR, Arity: 1}}
+
 // All primitive types that should be added to the environment
 var PrimitiveTypes = map[string]ast.Type{
 	"Byte":       tByte,
@@ -146,4 +176,6 @@ var PrimitiveTypes = map[string]ast.Type{
 	"Rune":       tRune,
 	"String":     tString,
 	"Unit":       tUnit,
+	"List":       tList,
+	"Set":        tSet,
 }
@@ -0,0 +1,405 @@
+// Checks pattern matches for exhaustiveness and redundant cases.
+// Based on "Warnings for pattern matching" by Luc Maranget.
+package typechecker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+// how many missing patterns are reported for a match
+const MAX_MISSING_PATTERNS = 5
+
+const (
+	pWild = iota
+	pCtor
+	pRecord
+)
+
+// A simplified pattern.
+// Literals are constructors of a type with infinite constructors
+// and lists are made of the constructors `[]` and `::`.
+type spat struct {
+	kind   int
+	name   string
+	args   []spat
+	labels []string
+	dom    *patDomain
+}
+
+// All the constructors of a type.
+// A nil domain has infinite constructors.
+type patDomain struct {
+	ctors  []CtorRef
+	isList bool
+}
+
+const (
+	NIL_CTOR  = "[]"
+	CONS_CTOR = "::"
+)
+
+var wildPat = spat{kind: pWild}
+
+var boolDomain = &patDomain{ctors: []CtorRef{{Name: "true"}, {Name: "false"}}}
+var unitDomain = &patDomain{ctors: []CtorRef{{Name: "()"}}}
+var listDomain = &patDomain{ctors: []CtorRef{{Name: NIL_CTOR}, {Name: CONS_CTOR, Arity: 2}}, isList: true}
+
+// Checks every pattern match in this expression.
+// Non exhaustive matches are errors and unreachable cases are warnings.
+func (i *Inference) checkMatches(exp ast.Expr) {
+	ast.EverywhereExprUnit(exp, func(e ast.Expr) {
+		if match, isMatch := e.(ast.Match); isMatch {
+			i.checkMatch(match)
+		}
+	})
+}
+
+func (i *Inference) checkMatch(match ast.Match) {
+	rows := make([][]spat, 0, len(match.Cases))
+	for _, cas := range match.Cases {
+		row := data.MapSlice(cas.Patterns, i.simplify)
+		if !useful(rows, row) {
+			span := data.NewSpan(cas.Patterns[0].GetSpan(), cas.Patterns[len(cas.Patterns)-1].GetSpan())
+			i.addError(i.tc.makeWarningRef(data.REDUNDANT_MATCH, span))
+		}
+		// cases with guards may not match
+		if cas.Guard == nil {
+			rows = append(rows, row)
+		}
+	}
+
+	missing := missingPatterns(rows, len(match.Exps))
+	if len(missing) > 0 {
+		pats := data.MapSlice(missing, func(ps []spat) string {
+			return data.JoinToStringFunc(ps, ", ", func(p spat) string { return showSpat(p, false) })
+		})
+		i.addError(i.tc.makeErrorRef(data.NonExhaustiveMatch(pats), match.Span))
+	}
+}
+
+func (i *Inference) simplify(pat ast.Pattern) spat {
+	switch p := pat.(type) {
+	case ast.Wildcard, ast.VarP:
+		return wildPat
+	case ast.NamedP:
+		return i.simplify(p.Pat)
+	case ast.UnitP:
+		return spat{kind: pCtor, name: "()", dom: unitDomain}
+	case ast.LiteralP:
+		if b, isBool := p.Lit.(ast.Bool); isBool {
+			return spat{kind: pCtor, name: strconv.FormatBool(b.V), dom: boolDomain}
+		}
+		return spat{kind: pCtor, name: showLiteral(p.Lit)}
+	case ast.CtorP:
+		return spat{kind: pCtor, name: p.Ctor.Name, args: data.MapSlice(p.Fields, i.simplify), dom: i.ctorDomain(p)}
+	case ast.ListP:
+		{
+			tail := spat{kind: pCtor, name: NIL_CTOR, dom: listDomain}
+			if p.Tail != nil {
+				tail = i.simplify(p.Tail)
+			}
+			for j := len(p.Elems) - 1; j >= 0; j-- {
+				tail = spat{kind: pCtor, name: CONS_CTOR, args: []spat{i.simplify(p.Elems[j]), tail}, dom: listDomain}
+			}
+			return tail
+		}
+	case ast.RecordP:
+		{
+			entries := p.Labels.Entries()
+			return spat{
+				kind:   pRecord,
+				labels: data.MapSlice(entries, func(e data.Entry[ast.Pattern]) string { return e.Label }),
+				args:   data.MapSlice(entries, func(e data.Entry[ast.Pattern]) spat { return i.simplify(e.Val) }),
+			}
+		}
+	case ast.RegexP:
+		// regexes can match any number of strings
+		return spat{kind: pCtor, name: "#\"" + p.Regex + "\""}
+	default:
+		return wildPat
+	}
+}
+
+// Finds all the constructors of the type of this constructor pattern
+func (i *Inference) ctorDomain(p ast.CtorP) *patDomain {
+	typ := p.Type.Type
+	for {
+		if tv, isVar := typ.(ast.TVar); isVar && tv.Tvar.Tag == ast.LINK {
+			typ = tv.Tvar.Type
+		} else if tapp, isApp := typ.(ast.TApp); isApp {
+			typ = tapp.Type
+		} else {
+			break
+		}
+	}
+	if tc, isConst := typ.(ast.TConst); isConst {
+		if ctors, found := i.tc.env.LookupCtors(tc.Name); found {
+			return &patDomain{ctors: ctors}
+		}
+	}
+	return nil
+}
+
+// Returns true if there's a value matched by `row`
+// that is not matched by any of the `rows`.
+func useful(rows [][]spat, row []spat) bool {
+	if len(row) == 0 {
+		return len(rows) == 0
+	}
+	if labels, isRecord := recordLabels(rows, row); isRecord {
+		return useful(expandRecords(rows, labels), expandRecord(row, labels))
+	}
+
+	head := row[0]
+	if head.kind == pCtor {
+		arity := len(head.args)
+		spec, _ := specializeRow(row, head.name, arity)
+		return useful(specialize(rows, head.name, arity), spec)
+	}
+
+	dom, complete := headCtors(rows)
+	if complete {
+		for _, ctor := range dom.ctors {
+			spec, _ := specializeRow(row, ctor.Name, ctor.Arity)
+			if useful(specialize(rows, ctor.Name, ctor.Arity), spec) {
+				return true
+			}
+		}
+		return false
+	}
+	return useful(defaultMatrix(rows), row[1:])
+}
+
+// Returns examples of values of size `n`
+// that are not matched by any of the rows.
+func missingPatterns(rows [][]spat, n int) [][]spat {
+	if n == 0 {
+		if len(rows) == 0 {
+			return [][]spat{{}}
+		}
+		return nil
+	}
+	if labels, isRecord := recordLabels(rows, nil); isRecord {
+		missing := missingPatterns(expandRecords(rows, labels), len(labels)+n-1)
+		return data.MapSlice(missing, func(ps []spat) []spat {
+			rec := spat{kind: pRecord, labels: labels, args: ps[:len(labels)]}
+			return append([]spat{rec}, ps[len(labels):]...)
+		})
+	}
+
+	dom, complete := headCtors(rows)
+	if complete {
+		res := make([][]spat, 0, 1)
+		for _, ctor := range dom.ctors {
+			missing := missingPatterns(specialize(rows, ctor.Name, ctor.Arity), ctor.Arity+n-1)
+			for _, ps := range missing {
+				pat := spat{kind: pCtor, name: ctor.Name, args: ps[:ctor.Arity], dom: dom}
+				res = append(res, append([]spat{pat}, ps[ctor.Arity:]...))
+			}
+			if len(res) >= MAX_MISSING_PATTERNS {
+				return res[:MAX_MISSING_PATTERNS]
+			}
+		}
+		return res
+	}
+
+	missing := missingPatterns(defaultMatrix(rows), n-1)
+	if len(missing) == 0 {
+		return nil
+	}
+	heads := []spat{wildPat}
+	if dom != nil {
+		// show the constructors that are not matched
+		heads = make([]spat, 0, len(dom.ctors))
+		for _, ctor := range dom.ctors {
+			if !data.AnySlice(rows, func(row []spat) bool { return row[0].kind == pCtor && row[0].name == ctor.Name }) {
+				heads = append(heads, spat{kind: pCtor, name: ctor.Name, args: wilds(ctor.Arity), dom: dom})
+			}
+		}
+	}
+	res := make([][]spat, 0, len(heads)*len(missing))
+	for _, head := range heads {
+		for _, ps := range missing {
+			res = append(res, append([]spat{head}, ps...))
+			if len(res) >= MAX_MISSING_PATTERNS {
+				return res
+			}
+		}
+	}
+	return res
+}
+
+// Returns the domain of the constructors in the first column of the rows
+// and whether every constructor of the domain appears in that column.
+func headCtors(rows [][]spat) (*patDomain, bool) {
+	seen := data.NewSet[string]()
+	var dom *patDomain
+	for _, row := range rows {
+		if row[0].kind == pCtor {
+			seen.Add(row[0].name)
+			dom = row[0].dom
+		}
+	}
+	if dom == nil || seen.Size() == 0 {
+		return dom, false
+	}
+	for _, ctor := range dom.ctors {
+		if !seen.Contains(ctor.Name) {
+			return dom, false
+		}
+	}
+	return dom, true
+}
+
+// Keeps the rows that match constructor `name` in the first column,
+// replacing it by its arguments.
+func specialize(rows [][]spat, name string, arity int) [][]spat {
+	res := make([][]spat, 0, len(rows))
+	for _, row := range rows {
+		if spec, matches := specializeRow(row, name, arity); matches {
+			res = append(res, spec)
+		}
+	}
+	return res
+}
+
+func specializeRow(row []spat, name string, arity int) ([]spat, bool) {
+	head := row[0]
+	if head.kind == pWild {
+		return append(wilds(arity), row[1:]...), true
+	}
+	if head.name != name {
+		return nil, false
+	}
+	return append(append(make([]spat, 0, arity+len(row)-1), head.args...), row[1:]...), true
+}
+
+// The rows that have a wildcard in the first column
+func defaultMatrix(rows [][]spat) [][]spat {
+	res := make([][]spat, 0, len(rows))
+	for _, row := range rows {
+		if row[0].kind == pWild {
+			res = append(res, row[1:])
+		}
+	}
+	return res
+}
+
+// Returns all the labels of the records in the first column
+// if there's a record pattern there.
+func recordLabels(rows [][]spat, row []spat) ([]string, bool) {
+	if len(row) > 0 {
+		rows = append(rows[:len(rows):len(rows)], row)
+	}
+	labels := make([]string, 0, 2)
+	isRecord := false
+	for _, r := range rows {
+		if r[0].kind == pRecord {
+			isRecord = true
+			for _, label := range r[0].labels {
+				if !data.InSlice(labels, label) {
+					labels = append(labels, label)
+				}
+			}
+		}
+	}
+	return labels, isRecord
+}
+
+func expandRecords(rows [][]spat, labels []string) [][]spat {
+	return data.MapSlice(rows, func(row []spat) []spat { return expandRecord(row, labels) })
+}
+
+// Replaces the record in the first column by one column for each label
+func expandRecord(row []spat, labels []string) []spat {
+	head := row[0]
+	res := make([]spat, 0, len(labels)+len(row)-1)
+	for _, label := range labels {
+		idx := -1
+		if head.kind == pRecord {
+			idx = data.SliceLastIndexOf(head.labels, label)
+		}
+		if idx == -1 {
+			res = append(res, wildPat)
+		} else {
+			res = append(res, head.args[idx])
+		}
+	}
+	return append(res, row[1:]...)
+}
+
+func wilds(n int) []spat {
+	res := make([]spat, n)
+	for i := range res {
+		res[i] = wildPat
+	}
+	return res
+}
+
+func showSpat(p spat, nested bool) string {
+	switch p.kind {
+	case pRecord:
+		{
+			fields := make([]string, 0, len(p.labels))
+			for i, label := range p.labels {
+				if p.args[i].kind != pWild {
+					fields = append(fields, fmt.Sprintf("%s: %s", data.ShowLabel(label), showSpat(p.args[i], false)))
+				}
+			}
+			if len(fields) == 0 {
+				return "_"
+			}
+			return fmt.Sprintf("{ %s }", strings.Join(fields, ", "))
+		}
+	case pCtor:
+		{
+			if p.dom != nil && p.dom.isList {
+				return showList(p)
+			}
+			if len(p.args) == 0 {
+				return p.name
+			}
+			str := p.name + " " + data.JoinToStringFunc(p.args, " ", func(a spat) string { return showSpat(a, true) })
+			if nested {
+				return "(" + str + ")"
+			}
+			return str
+		}
+	default:
+		return "_"
+	}
+}
+
+func showList(p spat) string {
+	elems := make([]string, 0, 2)
+	for p.kind == pCtor && p.name == CONS_CTOR {
+		elems = append(elems, showSpat(p.args[0], false))
+		p = p.args[1]
+	}
+	if p.kind == pCtor && p.name == NIL_CTOR {
+		return "[" + strings.Join(elems, ", ") + "]"
+	}
+	return fmt.Sprintf("[%s :: %s]", strings.Join(elems, ", "), showSpat(p, false))
+}
+
+func showLiteral(lit ast.Expr) string {
+	switch l := lit.(type) {
+	case ast.Int:
+		return strconv.FormatInt(l.V, 10)
+	case ast.Float:
+		return strconv.FormatFloat(l.V, 'g', -1, 64)
+	case ast.Complex:
+		return l.Raw
+	case ast.Char:
+		return strconv.QuoteRune(l.V)
+	case ast.String:
+		return strconv.Quote(l.V)
+	default:
+		return fmt.Sprintf("%v", lit)
+	}
+}
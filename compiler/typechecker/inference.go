@@ -51,9 +51,11 @@ func (i *Inference) inferModule(mod ast.Module) (ModuleEnv, error) {
 		}
 
 		ctorNames := make([]string, 0, len(d.DataCtors))
+		ctorRefs := make([]CtorRef, 0, len(d.DataCtors))
 		for _, dc := range d.DataCtors {
 			dcname := dc.Name.Val
 			ctorNames = append(ctorNames, dcname)
+			ctorRefs = append(ctorRefs, CtorRef{Name: dcname, Arity: len(dc.Args)})
 			dcty := getCtorType(dc, ty, m)
 			err := i.checkShadow(env, dcname, dc.Span)
 			if err != nil {
@@ -63,6 +65,7 @@ func (i *Inference) inferModule(mod ast.Module) (ModuleEnv, error) {
 			// TODO: cache constructor
 			decls[dcname] = DeclRef{Type: dcty, Visibility: dc.Visibility, IsInstance: false, Comment: nil}
 		}
+		env.ExtendCtors(typeName, ctorRefs)
 		types[d.Name.Val] = TypeDeclRef{Type: ty, Visibility: d.Visibility, Ctors: ctorNames, Comment: d.Comment}
 	}
 	for _, d := range datas {
@@ -122,6 +125,8 @@ func (i *Inference) inferModule(mod ast.Module) (ModuleEnv, error) {
 			}
 		}
 
+		i.checkMatches(decl.Exp)
+
 		// TODO: check implicits
 		genTy := i.generalize(-1, ty)
 		env.Extend(name, genTy)
@@ -698,6 +703,7 @@ func (i *Inference) inferPattern(env *Env, level ast.Level, pat ast.Pattern, ty
 		{
 			if p.Labels.IsEmpty() {
 				err := i.uni.Unify(ast.TRecord{Row: i.tc.NewVar(level)}, ty, p.Span)
+				pat.WithType(ty)
 				return []PatternVar{}, err
 			}
 
@@ -724,6 +730,7 @@ func (i *Inference) inferPattern(env *Env, level ast.Level, pat ast.Pattern, ty
 		{
 			if len(p.Elems) == 0 {
 				err := i.uni.Unify(ast.TApp{Type: ast.TConst{Name: PrimList}, Types: []ast.Type{i.tc.NewVar(level)}}, ty, p.Span)
+				pat.WithType(ty)
 				return []PatternVar{}, err
 			}
 
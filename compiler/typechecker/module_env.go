@@ -32,3 +32,24 @@ type FullModuleEnv struct {
 	Comment     *lexer.Comment
 	IsStdlib    bool
 }
+
+// Returns the constructors of a type declared in this module
+func (m ModuleEnv) TypeCtors(ref TypeDeclRef) []CtorRef {
+	ctors := make([]CtorRef, 0, len(ref.Ctors))
+	for _, name := range ref.Ctors {
+		ctors = append(ctors, CtorRef{Name: name, Arity: arrowArity(m.Decls[name].Type)})
+	}
+	return ctors
+}
+
+func arrowArity(typ ast.Type) int {
+	switch t := typ.(type) {
+	case ast.TArrow:
+		return len(t.Args) + arrowArity(t.Ret)
+	case ast.TVar:
+		if t.Tvar.Tag == ast.LINK {
+			return arrowArity(t.Tvar.Type)
+		}
+	}
+	return 0
+}
@@ -177,10 +177,11 @@ func (tc *Typechecker) checkWellFormed(typ ast.Type, span data.Span) error {
 	return nil
 }
 
-func (tc *Typechecker) makeError(msg string, span data.Span) data.CompilerProblem {
+func (tc *Typechecker) makeError(msg data.Message, span data.Span) data.CompilerProblem {
 	mod := tc.context.mod
 	return data.CompilerProblem{
-		Msg:           msg,
+		Msg:           msg.Text,
+		Code:          msg.Code,
 		Span:          span,
 		Filename:      mod.SourceName,
 		Module:        mod.Name.Val,
@@ -189,11 +190,17 @@ func (tc *Typechecker) makeError(msg string, span data.Span) data.CompilerProble
 	}
 }
 
-func (tc *Typechecker) makeErrorRef(msg string, span data.Span) *data.CompilerProblem {
+func (tc *Typechecker) makeErrorRef(msg data.Message, span data.Span) *data.CompilerProblem {
 	err := tc.makeError(msg, span)
 	return &err
 }
 
+func (tc *Typechecker) makeWarningRef(msg data.Message, span data.Span) *data.CompilerProblem {
+	warn := tc.makeError(msg, span)
+	warn.Severity = data.WARN
+	return &warn
+}
+
 type TypingContext struct {
 	mod   ast.Module
 	decl  *ast.ValDecl
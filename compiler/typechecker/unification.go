@@ -21,11 +21,11 @@ func (u *Unification) Unify(t1, t2 ast.Type, span data.Span) *data.CompilerProbl
 	if err == nil {
 		return nil
 	}
-	var reason string
+	var reason data.Message
 	switch e := err.(type) {
 	case noMatch:
 		if ast.RealType(t1).Equals(ast.RealType(e.t1)) && ast.RealType(t2).Equals(ast.RealType(e.t2)) {
-			reason = ""
+			reason = data.Message{}
 		} else {
 			reason = data.IncompatibleTypes(e.t1.String(), e.t2.String())
 		}
@@ -375,6 +375,9 @@ func headTail[T any](labels []data.Entry[[]T]) (string, []T, []data.Entry[[]T])
 // Concatenate and sort all values for a label
 func concatLabelMap[T any](lm data.LabelMap[T]) []data.Entry[[]T] {
 	res := make([]data.Entry[[]T], 0, lm.Size())
+	if lm.IsEmpty() {
+		return res
+	}
 	entries := lm.Copy().Entries()
 	slices.SortStableFunc(entries, func(a, b data.Entry[T]) bool { return a.Label < b.Label })
 	tmp := entries
@@ -434,5 +437,5 @@ func (e notRow) Error() string {
 }
 
 func (e recursiveRows) Error() string {
-	return data.RECURSIVE_ROWS
+	return data.RECURSIVE_ROWS.Text
 }
@@ -0,0 +1,183 @@
+package compiler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	goast "go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/data"
+	"github.com/stackoverflow/novah-go/novah"
+)
+
+// Generated files are parsed, formatted and typechecked before they are written,
+// so a bug in the code generation is reported as an internal compiler error
+// at the novah code that caused it instead of producing an invalid go file.
+// Positions in the generated code are mapped back to novah by the line directives.
+
+// A generated go file
+type goFile struct {
+	pack ast.GoPackage
+	path string
+	ast  *goast.File
+}
+
+// Parses and formats the go code generated for a package.
+// Returns the formatted code, which keeps the line directives of the declarations and statements,
+// or an internal error.
+func parseGo(fset *token.FileSet, pack ast.GoPackage, code string) (*goFile, string, *data.CompilerProblem) {
+	path := filepath.ToSlash(filepath.Join(packageDir(pack.Module), packageName(pack.Module)+".go"))
+	file, err := parser.ParseFile(fset, path, code, parser.ParseComments)
+	if err != nil {
+		var errs scanner.ErrorList
+		if errors.As(err, &errs) && len(errs) > 0 {
+			return nil, "", internalError(pack, errs[0].Pos, errs[0].Msg)
+		}
+		return nil, "", internalError(pack, token.Position{}, err.Error())
+	}
+	// the line comments inside expressions are only needed to map the errors,
+	// go/format would move them away from the code they refer to
+	file.Comments = data.FilterSlice(file.Comments, func(group *goast.CommentGroup) bool {
+		group.List = data.FilterSlice(group.List, func(c *goast.Comment) bool { return !strings.HasPrefix(c.Text, "/*line ") })
+		return len(group.List) > 0
+	})
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, "", internalError(pack, token.Position{}, err.Error())
+	}
+	return &goFile{pack: pack, path: importPath(pack.GoModule, pack.Module), ast: file}, buf.String(), nil
+}
+
+// Typechecks the generated packages against the runtime.
+// Only the first error of each package is reported, the others are usually caused by it.
+func typecheckGo(fset *token.FileSet, files []*goFile, goModule string) []data.CompilerProblem {
+	runtime, err := runtimeTypes()
+	return typecheckAgainst(fset, files, goModule, runtime, err)
+}
+
+// Typechecks the generated packages against the runtime loaded with err.
+// The generated code cannot be verified without the runtime,
+// so every package gets an internal error instead of being written unchecked.
+func typecheckAgainst(fset *token.FileSet, files []*goFile, goModule string, runtime *types.Package, err error) []data.CompilerProblem {
+	if err != nil {
+		problems := make([]data.CompilerProblem, 0, len(files))
+		for _, file := range files {
+			problems = append(problems, *internalError(file.pack, token.Position{}, "could not typecheck the runtime: "+err.Error()))
+		}
+		return problems
+	}
+	imp := &goImporter{
+		runtimePath: importPath(goModule, RUNTIME_PACKAGE),
+		runtime:     runtime,
+		files:       make(map[string]*goFile, len(files)),
+		checked:     make(map[string]*types.Package, len(files)),
+		fset:        fset,
+	}
+	for _, file := range files {
+		imp.files[file.path] = file
+	}
+	for _, file := range files {
+		imp.Import(file.path)
+	}
+	return imp.problems
+}
+
+type goImporter struct {
+	runtimePath string
+	runtime     *types.Package
+	files       map[string]*goFile
+	checked     map[string]*types.Package
+	fset        *token.FileSet
+	problems    []data.CompilerProblem
+}
+
+func (imp *goImporter) Import(path string) (*types.Package, error) {
+	if path == imp.runtimePath {
+		return imp.runtime, nil
+	}
+	if pkg, has := imp.checked[path]; has {
+		return pkg, nil
+	}
+	file, has := imp.files[path]
+	if !has {
+		return nil, fmt.Errorf("package %s was not generated", path)
+	}
+	var first *types.Error
+	conf := types.Config{
+		Importer: imp,
+		Error: func(err error) {
+			if terr, isType := err.(types.Error); isType && first == nil {
+				first = &terr
+			}
+		},
+	}
+	pkg, _ := conf.Check(path, imp.fset, []*goast.File{file.ast}, nil)
+	imp.checked[path] = pkg
+	if first != nil {
+		imp.problems = append(imp.problems, *internalError(file.pack, imp.fset.Position(first.Pos), first.Msg))
+	}
+	return pkg, nil
+}
+
+var runtimeCache struct {
+	once sync.Once
+	pkg  *types.Package
+	err  error
+}
+
+// The types of the runtime, checked once against the sources of the standard library
+func runtimeTypes() (*types.Package, error) {
+	runtimeCache.once.Do(func() {
+		fset := token.NewFileSet()
+		names, err := fs.Glob(novah.Sources, "*.go")
+		if err != nil {
+			runtimeCache.err = err
+			return
+		}
+		files := make([]*goast.File, 0, len(names))
+		for _, name := range names {
+			src, err := novah.Sources.ReadFile(name)
+			if err != nil {
+				runtimeCache.err = err
+				return
+			}
+			file, err := parser.ParseFile(fset, name, src, 0)
+			if err != nil {
+				runtimeCache.err = err
+				return
+			}
+			files = append(files, file)
+		}
+		conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+		runtimeCache.pkg, runtimeCache.err = conf.Check(RUNTIME_PACKAGE, fset, files, nil)
+	})
+	return runtimeCache.pkg, runtimeCache.err
+}
+
+// An error in the generated code at the novah position pos
+func internalError(pack ast.GoPackage, pos token.Position, msg string) *data.CompilerProblem {
+	err := data.InternalError(msg)
+	start := data.Pos{Line: pos.Line, Col: pos.Column}
+	if !pos.IsValid() {
+		start = data.Pos{Line: pack.Pos.Line, Col: pack.Pos.Col}
+	}
+	return &data.CompilerProblem{
+		Msg:      err.Text,
+		Code:     err.Code,
+		Span:     data.Span{Start: start, End: data.Pos{Line: start.Line, Col: start.Col + 1}},
+		Filename: pack.SourceName,
+		Module:   pack.Module,
+		Severity: data.ERROR,
+	}
+}
@@ -0,0 +1,71 @@
+package compiler
+
+import (
+	"errors"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormattedOutput(t *testing.T) {
+	root, output := t.TempDir(), t.TempDir()
+	writeFiles(t, root, map[string]string{"app.novah": `module app
+
+loop : Bool -> Unit
+loop b =
+  while b do
+    loop false
+  ()
+`})
+
+	c := NewCompiler([]string{filepath.Join(root, "app.novah")}, Options{})
+	assert.Empty(t, c.Run(output, false))
+
+	app, err := os.ReadFile(filepath.Join(output, "app", "app.go"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(app), "\nfunc loop(b bool) novah.Unit {\n")
+	assert.Contains(t, string(app), "\n\tfor b {\n")
+	assert.NotContains(t, string(app), "/*line")
+}
+
+var appPackage = ast.GoPackage{Name: "app", Module: "app", SourceName: "app.novah", GoModule: "app", Pos: data.Pos{Line: 1, Col: 8}}
+
+func TestSyntaxErrorsAreInternal(t *testing.T) {
+	code := "//line app.novah:1\npackage app\n\nfunc f() int {\n\t/*line :3:5*/ return (1\n}\n"
+	_, _, problem := parseGo(token.NewFileSet(), appPackage, code)
+
+	assert.Equal(t, "N0112", problem.Code)
+	assert.Equal(t, "app.novah", problem.Filename)
+	assert.Equal(t, "app", problem.Module)
+	assert.Equal(t, 3, problem.Span.Start.Line)
+}
+
+func TestTypeErrorsAreInternal(t *testing.T) {
+	code := "//line app.novah:1\npackage app\n\nfunc f() int {\n\treturn /*line :7:12*/\"1\"\n}\n"
+	fset := token.NewFileSet()
+	file, _, problem := parseGo(fset, appPackage, code)
+	assert.Nil(t, problem)
+
+	problems := typecheckGo(fset, []*goFile{file}, "app")
+	assert.Equal(t, 1, len(problems))
+	assert.Equal(t, "N0112", problems[0].Code)
+	assert.Equal(t, data.Pos{Line: 7, Col: 12}, problems[0].Span.Start)
+}
+
+func TestRuntimeErrorsAreInternal(t *testing.T) {
+	code := "//line app.novah:1\npackage app\n\nfunc f() int {\n\treturn 1\n}\n"
+	fset := token.NewFileSet()
+	file, _, problem := parseGo(fset, appPackage, code)
+	assert.Nil(t, problem)
+
+	problems := typecheckAgainst(fset, []*goFile{file}, "app", nil, errors.New("fmt not found"))
+	assert.Equal(t, 1, len(problems))
+	assert.Equal(t, "N0112", problems[0].Code)
+	assert.Equal(t, "app", problems[0].Module)
+	assert.Contains(t, problems[0].Msg, "could not typecheck the runtime: fmt not found")
+}
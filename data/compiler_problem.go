@@ -2,6 +2,8 @@ package data
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 type Severity = int
@@ -20,6 +22,7 @@ const (
 
 type CompilerProblem struct {
 	Msg           string
+	Code          string
 	Span          Span
 	Filename      string
 	Module        string
@@ -31,12 +34,146 @@ func (cp CompilerProblem) Error() string {
 	return fmt.Sprintf("%s at %s", cp.Msg, cp.Span.String())
 }
 
+// Options to render problems to the console.
+// Source, if present, is used to get the text of
+// a file so the offending lines can be shown.
+type ConsoleOptions struct {
+	Color  bool
+	Source func(filename string) (string, bool)
+}
+
 func (err CompilerProblem) FormatToConsole() string {
+	return err.Format(ConsoleOptions{Color: true})
+}
+
+func (err CompilerProblem) Format(opts ConsoleOptions) string {
 	var mod string
 	if err.Module != "" {
-		mod = fmt.Sprintf("module %s%s%s ", yellow, err.Module, reset)
+		mod = fmt.Sprintf("module %s ", colorize(err.Module, yellow, opts.Color))
+	}
+	at := fmt.Sprintf("at %s:%s", err.Filename, err.Span.String())
+	if err.Code != "" {
+		at += fmt.Sprintf(" [%s]", err.Code)
+	}
+	at += "\n\n"
+
+	var snippet string
+	if opts.Source != nil && !err.Span.IsEmpty() {
+		if src, has := opts.Source(err.Filename); has {
+			snippet = FormatSnippet(src, err.Span, severityColor(err.Severity), opts.Color)
+		}
+	}
+
+	return fmt.Sprintf("%s%s%s%s\n\n%s", mod, at, snippet, PrependIdent(err.Msg, "  "), err.TypingContext)
+}
+
+// max number of lines to show in a snippet
+// before eliding the middle ones
+const maxSnippetLines = 6
+
+// Renders the lines of source covered by span with line numbers
+// and underlines the exact columns covered by the span.
+func FormatSnippet(source string, span Span, color string, useColor bool) string {
+	lines := strings.Split(source, "\n")
+	start, end := span.Start.Line, span.End.Line
+	if start < 1 || start > len(lines) {
+		return ""
+	}
+	if end < start {
+		end = start
+	}
+	if end > len(lines) {
+		end = len(lines)
 	}
-	at := fmt.Sprintf("at %s:%s\n\n", err.Filename, err.Span.String())
 
-	return fmt.Sprintf("%s%s%s\n\n%s", mod, at, PrependIdent(err.Msg, "  "), err.TypingContext)
+	width := len(strconv.Itoa(end))
+	gutter := strings.Repeat(" ", width) + " | "
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat(" ", width) + " |\n")
+	for l := start; l <= end; l++ {
+		if end-start+1 > maxSnippetLines && l == start+maxSnippetLines/2 {
+			sb.WriteString(strings.Repeat(" ", width) + " | ...\n")
+			l = end - maxSnippetLines/2 + 1
+		}
+		line := []rune(strings.TrimRight(lines[l-1], "\r"))
+		fmt.Fprintf(&sb, "%*d | %s\n", width, l, string(line))
+
+		// spans count tabs as more than one column
+		from, to := 1, len(line)+1
+		if l == start {
+			from = RuneIndex(line, span.Start.Col) + 1
+		} else {
+			from = firstNonBlank(line)
+		}
+		if l == end {
+			to = RuneIndex(line, span.End.Col) + 1
+		}
+		if from < 1 {
+			from = 1
+		}
+		if from > len(line)+1 {
+			from = len(line) + 1
+		}
+		if to > len(line)+1 {
+			to = len(line) + 1
+		}
+		if to <= from {
+			to = from + 1
+		}
+		sb.WriteString(gutter)
+		sb.WriteString(underline(line, from, to, color, useColor))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// Returns the padding and carets from column `from` to column `to` (exclusive).
+// Tabs in the padding are kept so the carets line up with the source.
+func underline(line []rune, from, to int, color string, useColor bool) string {
+	var pad strings.Builder
+	for i := 0; i < from-1; i++ {
+		if line[i] == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteRune(' ')
+		}
+	}
+	return pad.String() + colorize(strings.Repeat("^", to-from), color, useColor)
+}
+
+func firstNonBlank(line []rune) int {
+	for i, r := range line {
+		if r != ' ' && r != '\t' {
+			return i + 1
+		}
+	}
+	return 1
+}
+
+func severityColor(sev Severity) string {
+	if sev == WARN {
+		return yellow
+	}
+	return red
+}
+
+func colorize(str, color string, useColor bool) string {
+	if !useColor {
+		return str
+	}
+	return color + str + reset
+}
+
+// Returns the number of errors (including fatal ones)
+// and warnings in the list of problems
+func CountProblems(problems []CompilerProblem) (errors int, warnings int) {
+	for _, p := range problems {
+		if p.Severity == WARN {
+			warnings++
+		} else {
+			errors++
+		}
+	}
+	return
 }
@@ -0,0 +1,70 @@
+package data
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSnippetSingleLine(t *testing.T) {
+	src := "module test\n\nfoo = bar 1\n"
+	res := FormatSnippet(src, NewSpan2(3, 7, 3, 10), red, false)
+	expected := "  |\n3 | foo = bar 1\n  |       ^^^\n\n"
+	if res != expected {
+		t.Errorf("Result was incorrect: expected\n%s\ngot:\n%s", expected, res)
+	}
+}
+
+func TestSnippetMultiLine(t *testing.T) {
+	src := "foo =\n  if true\n    then 1\n    else 2\n"
+	res := FormatSnippet(src, NewSpan2(2, 3, 4, 11), red, false)
+	expected := "  |\n2 |   if true\n  |   ^^^^^^^\n3 |     then 1\n  |     ^^^^^^\n4 |     else 2\n  |     ^^^^^^\n\n"
+	if res != expected {
+		t.Errorf("Result was incorrect: expected\n%s\ngot:\n%s", expected, res)
+	}
+}
+
+func TestSnippetElidesLongSpans(t *testing.T) {
+	lines := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		lines = append(lines, "x")
+	}
+	res := FormatSnippet(strings.Join(lines, "\n"), NewSpan2(1, 1, 20, 2), red, false)
+	if !strings.Contains(res, "   | ...\n") || strings.Contains(res, " 4 | x") || !strings.Contains(res, "20 | x") {
+		t.Errorf("Result was incorrect: got\n%s", res)
+	}
+}
+
+func TestSnippetKeepsTabs(t *testing.T) {
+	// the lexer counts a tab as 2 columns
+	res := FormatSnippet("\tfoo", NewSpan2(1, 3, 1, 6), red, false)
+	expected := "  |\n1 | \tfoo\n  | \t^^^\n\n"
+	if res != expected {
+		t.Errorf("Result was incorrect: expected\n%s\ngot:\n%s", expected, res)
+	}
+	res = FormatSnippet("x\t\tfoo bar", NewSpan2(1, 6, 1, 13), red, false)
+	expected = "  |\n1 | x\t\tfoo bar\n  |  \t\t^^^^^^^\n\n"
+	if res != expected {
+		t.Errorf("Result was incorrect: expected\n%s\ngot:\n%s", expected, res)
+	}
+}
+
+func TestRuneIndex(t *testing.T) {
+	line := []rune("\ta\tb")
+	for col, idx := range map[int]int{1: 0, 2: 1, 3: 1, 4: 2, 6: 3, 7: 4, 9: 6} {
+		if got := RuneIndex(line, col); got != idx {
+			t.Errorf("Result was incorrect for column %d: expected %d, got %d", col, idx, got)
+		}
+	}
+	for idx, col := range map[int]int{0: 1, 1: 3, 2: 4, 3: 6, 4: 7, 5: 8} {
+		if got := RuneColumn(line, idx); got != col {
+			t.Errorf("Result was incorrect for index %d: expected %d, got %d", idx, col, got)
+		}
+	}
+}
+
+func TestSnippetColor(t *testing.T) {
+	res := FormatSnippet("foo", NewSpan2(1, 1, 1, 4), yellow, true)
+	if !strings.Contains(res, yellow+"^^^"+reset) {
+		t.Errorf("Result was incorrect: got\n%s", res)
+	}
+}
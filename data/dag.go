@@ -19,52 +19,155 @@ func (d *Dag[T, D]) Size() int {
 }
 
 // Finds the first cycle in the DAG.
+// Nodes are visited in the order they were added.
 // The second return tells if there's a cycle
 func (d *Dag[T, D]) FindCycle() ([]DagNode[T, D], bool) {
-	whiteSet := make(map[T]*DagNode[T, D])
-	graySet := make(map[T]DagNode[T, D])
-	blackSet := make(map[T]DagNode[T, D])
+	visited := NewSet[T]()
+	graySet := NewSet[T]()
 	parentage := make(map[T]*DagNode[T, D])
 
-	for _, node := range d.nodes {
-		whiteSet[node.Val] = node
-	}
-
 	// depth first search
-	var dfs func(DagNode[T, D], *DagNode[T, D]) *DagNode[T, D]
-	dfs = func(current DagNode[T, D], parent *DagNode[T, D]) *DagNode[T, D] {
-		delete(whiteSet, current.Val)
-		graySet[current.Val] = current
+	// returns the last node of the cycle and the node that closes it
+	var dfs func(*DagNode[T, D], *DagNode[T, D]) (*DagNode[T, D], *DagNode[T, D])
+	dfs = func(current *DagNode[T, D], parent *DagNode[T, D]) (*DagNode[T, D], *DagNode[T, D]) {
+		visited.Add(current.Val)
+		graySet.Add(current.Val)
 		parentage[current.Val] = parent
 
 		for _, neighbor := range current.Neighbors {
-			if _, has := blackSet[neighbor.Val]; has {
+			// found cycle
+			if graySet.Contains(neighbor.Val) {
+				return current, neighbor
+			}
+			if visited.Contains(neighbor.Val) {
 				continue
 			}
+			if last, start := dfs(neighbor, current); last != nil {
+				return last, start
+			}
+		}
 
-			// found cycle
-			if _, has := graySet[neighbor.Val]; has {
-				return &current
+		graySet.Remove(current.Val)
+		return nil, nil
+	}
+
+	for _, node := range d.nodes {
+		if visited.Contains(node.Val) {
+			continue
+		}
+		if last, start := dfs(node, nil); last != nil {
+			return d.reportCycle(*last, *start, parentage), true
+		}
+	}
+	return []DagNode[T, D]{}, false
+}
+
+// Returns the strongly connected components of this graph
+// using Tarjan's algorithm.
+// Every node is in exactly one component, so a component with a
+// single node is only a cycle if the node links to itself.
+func (d *Dag[T, D]) StronglyConnectedComponents() [][]*DagNode[T, D] {
+	index := 0
+	indexes := make(map[T]int)
+	lowlinks := make(map[T]int)
+	onStack := NewSet[T]()
+	stack := make([]*DagNode[T, D], 0)
+	components := make([][]*DagNode[T, D], 0)
+
+	var connect func(*DagNode[T, D])
+	connect = func(node *DagNode[T, D]) {
+		indexes[node.Val] = index
+		lowlinks[node.Val] = index
+		index++
+		stack = append(stack, node)
+		onStack.Add(node.Val)
+
+		for _, neighbor := range node.Neighbors {
+			if _, has := indexes[neighbor.Val]; !has {
+				connect(neighbor)
+				if lowlinks[neighbor.Val] < lowlinks[node.Val] {
+					lowlinks[node.Val] = lowlinks[neighbor.Val]
+				}
+			} else if onStack.Contains(neighbor.Val) && indexes[neighbor.Val] < lowlinks[node.Val] {
+				lowlinks[node.Val] = indexes[neighbor.Val]
 			}
-			res := dfs(*neighbor, &current)
-			if res != nil {
-				return res
+		}
+
+		if lowlinks[node.Val] == indexes[node.Val] {
+			component := make([]*DagNode[T, D], 0, 1)
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack.Remove(top.Val)
+				component = append(component, top)
+				if top.Val == node.Val {
+					break
+				}
 			}
+			components = append(components, ReverseSlice(component))
 		}
+	}
 
-		delete(graySet, current.Val)
-		blackSet[current.Val] = current
-		return nil
+	for _, node := range d.nodes {
+		if _, has := indexes[node.Val]; !has {
+			connect(node)
+		}
+	}
+	return components
+}
+
+// Finds one cycle in every strongly connected component of the graph.
+// Each cycle starts at the first node of its component that was added to
+// the graph and is the shortest path from it back to itself.
+func (d *Dag[T, D]) FindCycles() [][]*DagNode[T, D] {
+	order := make(map[T]int)
+	for i, node := range d.nodes {
+		order[node.Val] = i
 	}
 
-	for len(whiteSet) > 0 {
-		current := FirstInMap(whiteSet)
-		cycled := dfs(*current, nil)
-		if cycled != nil {
-			return d.reportCycle(*cycled, parentage), true
+	cycles := make([][]*DagNode[T, D], 0)
+	for _, component := range d.StronglyConnectedComponents() {
+		members := NewSet[T]()
+		start := component[0]
+		for _, node := range component {
+			members.Add(node.Val)
+			if order[node.Val] < order[start.Val] {
+				start = node
+			}
+		}
+		if cycle := shortestCycle(start, members); cycle != nil {
+			cycles = append(cycles, cycle)
 		}
 	}
-	return []DagNode[T, D]{}, false
+	return cycles
+}
+
+// Breadth first search from start back to itself
+// without leaving the component.
+func shortestCycle[T comparable, D any](start *DagNode[T, D], members Set[T]) []*DagNode[T, D] {
+	parents := make(map[T]*DagNode[T, D])
+	queue := []*DagNode[T, D]{start}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, neighbor := range node.Neighbors {
+			if !members.Contains(neighbor.Val) {
+				continue
+			}
+			if neighbor.Val == start.Val {
+				cycle := []*DagNode[T, D]{node}
+				for cycle[0].Val != start.Val {
+					cycle = append([]*DagNode[T, D]{parents[cycle[0].Val]}, cycle...)
+				}
+				return cycle
+			}
+			if _, seen := parents[neighbor.Val]; !seen {
+				parents[neighbor.Val] = node
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	return nil
 }
 
 // Returns a topological sorted representation of this graph.
@@ -94,12 +197,13 @@ func (d *Dag[T, D]) Toposort() *Stack[DagNode[T, D]] {
 	return stack
 }
 
-func (d *Dag[T, D]) reportCycle(node DagNode[T, D], parentage map[T]*DagNode[T, D]) []DagNode[T, D] {
+func (d *Dag[T, D]) reportCycle(node DagNode[T, D], start DagNode[T, D], parentage map[T]*DagNode[T, D]) []DagNode[T, D] {
 	cycle := []DagNode[T, D]{node}
 
 	parent := parentage[node.Val]
-	for parent != nil {
+	for parent != nil && node.Val != start.Val {
 		cycle = append(cycle, *parent)
+		node = *parent
 		parent = parentage[parent.Val]
 	}
 	return cycle
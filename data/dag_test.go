@@ -122,3 +122,68 @@ func TestToposort(t *testing.T) {
 		t.Error("Sorted graph should be {4, 1, 2, 3, 5, 6}")
 	}
 }
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	dag := NewDag[int, int](6)
+
+	n1 := NewDagNode(1, 0)
+	n2 := NewDagNode(2, 0)
+	n3 := NewDagNode(3, 0)
+	n4 := NewDagNode(4, 0)
+	n5 := NewDagNode(5, 0)
+	n6 := NewDagNode(6, 0)
+
+	n1.Link(n2)
+	n2.Link(n1)
+	n2.Link(n3)
+	n3.Link(n4)
+	n4.Link(n5)
+	n5.Link(n3)
+	n5.Link(n6)
+
+	dag.AddNodes(n1, n2, n3, n4, n5, n6)
+
+	comps := MapSlice(dag.StronglyConnectedComponents(), func(c []*DagNode[int, int]) []int {
+		return MapSlice(c, func(n *DagNode[int, int]) int { return n.Val })
+	})
+	if len(comps) != 3 || !slices.Equal(comps[0], []int{6}) || !slices.Equal(comps[1], []int{3, 4, 5}) || !slices.Equal(comps[2], []int{1, 2}) {
+		t.Errorf("Expected components [[6] [3 4 5] [1 2]], got %v", comps)
+	}
+}
+
+func TestFindCycles(t *testing.T) {
+	dag := NewDag[int, int](6)
+
+	n1 := NewDagNode(1, 0)
+	n2 := NewDagNode(2, 0)
+	n3 := NewDagNode(3, 0)
+	n4 := NewDagNode(4, 0)
+	n5 := NewDagNode(5, 0)
+	n6 := NewDagNode(6, 0)
+
+	// two cycles connected by 3 -> 4
+	n1.Link(n2)
+	n2.Link(n3)
+	n3.Link(n1)
+	n2.Link(n1)
+	n3.Link(n4)
+	n4.Link(n5)
+	n5.Link(n6)
+	n6.Link(n4)
+	// self loop
+	n7 := NewDagNode(7, 0)
+	n7.Link(n7)
+
+	dag.AddNodes(n1, n2, n3, n4, n5, n6, n7)
+
+	cycles := MapSlice(dag.FindCycles(), func(c []*DagNode[int, int]) []int {
+		return MapSlice(c, func(n *DagNode[int, int]) int { return n.Val })
+	})
+	if len(cycles) != 3 || !slices.Equal(cycles[0], []int{4, 5, 6}) || !slices.Equal(cycles[1], []int{1, 2}) || !slices.Equal(cycles[2], []int{7}) {
+		t.Errorf("Expected cycles [[4 5 6] [1 2] [7]], got %v", cycles)
+	}
+
+	if cycles := NewDag[int, int](0).FindCycles(); len(cycles) != 0 {
+		t.Errorf("Expected no cycles, got %v", cycles)
+	}
+}
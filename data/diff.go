@@ -0,0 +1,169 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lines of context around each change in a diff
+const diffContext = 3
+
+type diffOp struct {
+	kind rune // ' ', '-' or '+'
+	line string
+}
+
+// Returns a unified diff between the two texts
+// or an empty string if they are the same.
+func UnifiedDiff(before, after, fromName, toName string) string {
+	if before == after {
+		return ""
+	}
+	ops := diffLines(splitLines(before), splitLines(after))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range diffHunks(ops) {
+		writeHunk(&sb, ops, hunk)
+	}
+	return sb.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Computes the edit script between a and b using the longest common subsequence.
+// Common prefixes and suffixes are skipped first as they are usually most of the file.
+func diffLines(a, b []string) []diffOp {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+
+	// lcs[i][j] is the size of the lcs of ma[i:] and mb[j:]
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, l := range a[:pre] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			ops = append(ops, diffOp{' ', ma[i]})
+			i++
+			j++
+		case j >= len(mb) || (i < len(ma) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', ma[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', mb[j]})
+			j++
+		}
+	}
+	for _, l := range a[len(a)-suf:] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
+
+// Returns the [start, end) ranges of ops that make up each hunk
+func diffHunks(ops []diffOp) [][2]int {
+	hunks := make([][2]int, 0)
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == ' ' {
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		// extend the hunk while changes are close enough to share context
+		end := i
+		for k := i; k < len(ops) && k <= end+2*diffContext; k++ {
+			if ops[k].kind != ' ' {
+				end = k
+			}
+		}
+		end += diffContext + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1][1] {
+			hunks[len(hunks)-1][1] = end
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+		i = end - 1
+	}
+	return hunks
+}
+
+func writeHunk(sb *strings.Builder, ops []diffOp, hunk [2]int) {
+	// line numbers of the start of the hunk in both files
+	aLine, bLine := 1, 1
+	for _, op := range ops[:hunk[0]] {
+		if op.kind != '+' {
+			aLine++
+		}
+		if op.kind != '-' {
+			bLine++
+		}
+	}
+	aCount, bCount := 0, 0
+	for _, op := range ops[hunk[0]:hunk[1]] {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aLine, aCount), hunkRange(bLine, bCount))
+	for _, op := range ops[hunk[0]:hunk[1]] {
+		sb.WriteRune(op.kind)
+		sb.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// empty ranges point to the line before
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"testing"
+)
+
+func TestDiffEqual(t *testing.T) {
+	if res := UnifiedDiff("a\nb\n", "a\nb\n", "a", "b"); res != "" {
+		t.Errorf("Expected empty diff, got:\n%s", res)
+	}
+}
+
+func TestDiffChange(t *testing.T) {
+	before := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	after := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n"
+	expected := `--- a
++++ b
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`
+	if res := UnifiedDiff(before, after, "a", "b"); res != expected {
+		t.Errorf("Result was incorrect: expected\n%s\ngot:\n%s", expected, res)
+	}
+}
+
+func TestDiffSeparateHunks(t *testing.T) {
+	before := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	after := "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n"
+	res := UnifiedDiff(before, after, "a", "b")
+	expected := `--- a
++++ b
+@@ -1,3 +1,4 @@
++0
+ 1
+ 2
+ 3
+@@ -9,4 +10,3 @@
+ 9
+ 10
+ 11
+-12
+`
+	if res != expected {
+		t.Errorf("Result was incorrect: expected\n%s\ngot:\n%s", expected, res)
+	}
+}
+
+func TestDiffMissingNewline(t *testing.T) {
+	expected := "--- a\n+++ b\n@@ -1 +1 @@\n-x\n\\ No newline at end of file\n+x\n"
+	if res := UnifiedDiff("x", "x\n", "a", "b"); res != expected {
+		t.Errorf("Result was incorrect: expected\n%s\ngot:\n%s", expected, res)
+	}
+}
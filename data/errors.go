@@ -1,67 +1,98 @@
 package data
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// A diagnostic message with its stable code.
+// Codes never change or get reused, so they can be
+// searched, suppressed and explained with `novah explain`.
+type Message struct {
+	Code string
+	Text string
+}
+
+func (m Message) String() string {
+	return m.Text
+}
+
+var (
+	// lexer
+	EMPTY_IDENTIFIER = Message{"N0001", "Identifiers cannot be empty."}
+
+	DOUBLE_UNDERSCORE = Message{"N0002", "Identifiers cannot have a double underscore (__)."}
+
+	UPPER_IDENT_END = Message{"N0003", "Upper case identifiers cannot end with `?` or `!`."}
 
-const (
-	MISMATCHED_INDENTATION = "Mismatched indentation."
+	BACKTICK_OPERATOR = Message{"N0004", "Invalid character in backtick operator."}
 
-	MODULE_NAME = `Module names should be composed of identifiers started with a lower case character and separated by dots.
-They also cannot contain special characters like '?' or '!'.`
+	STRING_NEWLINE = Message{"N0005", "Newline is not allowed inside strings."}
 
-	MODULE_DEFINITION = `Expected file to begin with a module declaration.
+	CHAR_QUOTE = Message{"N0006", "Expected ' after char literal."}
+
+	ESCAPE_CHARACTER = Message{"N0007", "Unexpected UTF-8 escape character."}
+
+	// parser, desugar and typechecker
+	MISMATCHED_INDENTATION = Message{"N0008", "Mismatched indentation."}
+
+	MODULE_NAME = Message{"N0009", `Module names should be composed of identifiers started with a lower case character and separated by dots.
+They also cannot contain special characters like '?' or '!'.`}
+
+	MODULE_DEFINITION = Message{"N0010", `Expected file to begin with a module declaration.
 Example:
 
-module some.package`
+module some.package`}
 
-	IMPORT_REFER = "Expected exposing definitions to be a comma-separated list of upper or lower case identifiers."
+	IMPORT_REFER = Message{"N0011", "Expected exposing definitions to be a comma-separated list of upper or lower case identifiers."}
 
-	DECLARATION_REF_ALL = `To import or export all constructor of a type use a (..) syntax.
+	DECLARATION_REF_ALL = Message{"N0012", `To import or export all constructor of a type use a (..) syntax.
 
-ex: import package (fun1, SomeType(..), fun2)`
+ex: import package (fun1, SomeType(..), fun2)`}
 
-	CTOR_NAME = "Expected constructor name (upper case identifier)."
+	CTOR_NAME = Message{"N0013", "Expected constructor name (upper case identifier)."}
 
-	IMPORT_ALIAS = `Expected module import alias to be capitalized:
-Example: import data.package as Mod`
+	IMPORT_ALIAS = Message{"N0014", `Expected module import alias to be capitalized:
+Example: import data.package as Mod`}
 
-	IMPORTED_DOT = "Expected identifier after imported variable reference."
+	IMPORTED_DOT = Message{"N0015", "Expected identifier after imported variable reference."}
 
-	TYPE_VAR = "Expected type variable (lower case identifier)."
+	TYPE_VAR = Message{"N0016", "Expected type variable (lower case identifier)."}
 
-	TYPE_DEF = "Expected a type definition."
+	TYPE_DEF = Message{"N0017", "Expected a type definition."}
 
-	TYPE_COLON = "Expected `:` before type definition."
+	TYPE_COLON = Message{"N0018", "Expected `:` before type definition."}
 
-	TYPEALIAS_DOT = "Expected type identifier after dot."
+	TYPEALIAS_DOT = Message{"N0019", "Expected type identifier after dot."}
 
-	TYPE_TEST_TYPE = "Expected type in type test."
+	TYPE_TEST_TYPE = Message{"N0020", "Expected type in type test."}
 
-	RECORD_LABEL = "A label of a record can only be a lower case identifier or a String."
+	RECORD_LABEL = Message{"N0021", "A label of a record can only be a lower case identifier or a String."}
 
-	RECORD_COLON = "Expected `:` after record label."
+	RECORD_COLON = Message{"N0022", "Expected `:` after record label."}
 
-	RECORD_EQUALS = "Expected `=` or `->` after record labels in set/update expression."
+	RECORD_EQUALS = Message{"N0023", "Expected `=` or `->` after record labels in set/update expression."}
 
-	INSTANCE_TYPE = "Instance types need to be enclosed in double brackets: {{ type }}."
+	INSTANCE_TYPE = Message{"N0024", "Instance types need to be enclosed in double brackets: {{ type }}."}
 
-	INSTANCE_VAR = "Instance variables need to be enclosed in double brackets: {{var}}."
+	INSTANCE_VAR = Message{"N0025", "Instance variables need to be enclosed in double brackets: {{var}}."}
 
-	INSTANCE_ERROR = "Type and type alias declarations cannot be instances, only values."
+	INSTANCE_ERROR = Message{"N0026", "Type and type alias declarations cannot be instances, only values."}
 
-	VARIABLE = "Expected variable name."
+	VARIABLE = Message{"N0027", "Expected variable name."}
 
-	OPERATOR = "Expected operator."
+	OPERATOR = Message{"N0028", "Expected operator."}
 
-	LAMBDA_BACKSLASH = "Expected lambda definition to start with backslash: `\\`."
+	LAMBDA_BACKSLASH = Message{"N0029", "Expected lambda definition to start with backslash: `\\`."}
 
-	LAMBDA_ARROW = "Expected `->` after lambda parameter definition."
+	LAMBDA_ARROW = Message{"N0030", "Expected `->` after lambda parameter definition."}
 
-	LAMBDA_VAR = `Expected identifier after start of lambda definition:
-Example: \x -> x + 3`
+	LAMBDA_VAR = Message{"N0031", `Expected identifier after start of lambda definition:
+Example: \x -> x + 3`}
 
-	TOPLEVEL_IDENT = "Expected variable definition or variable type at the top level."
+	TOPLEVEL_IDENT = Message{"N0032", "Expected variable definition or variable type at the top level."}
 
-	PATTERN = `Expected a pattern expression.
+	PATTERN = Message{"N0033", `Expected a pattern expression.
 |Patterns can be one of:
 |
 |Wildcard pattern: _
@@ -71,57 +102,57 @@ Example: \x -> x + 3`
 |Record pattern: { x, y: 3 }
 |List pattern: [], [x, y, _], [x :: xs]
 |Named pattern: 10 as number
-|Type test: :? Int as i`
+|Type test: :? Int as i`}
 
-	DO_WHILE = "Expected keyword `do` after while condition."
+	DO_WHILE = Message{"N0034", "Expected keyword `do` after while condition."}
 
-	EXP_SIMPLE = "Invalid expression for while condition."
+	EXP_SIMPLE = Message{"N0035", "Invalid expression for while condition."}
 
-	THEN = "Expected `then` after if condition."
+	THEN = Message{"N0036", "Expected `then` after if condition."}
 
-	ELSE = "Expected `else` after then condition."
+	ELSE = Message{"N0037", "Expected `else` after then condition."}
 
-	LET_DECL = "Expected variable name after `let`."
+	LET_DECL = Message{"N0038", "Expected variable name after `let`."}
 
-	LET_EQUALS = "Expected `=` after let name declaration."
+	LET_EQUALS = Message{"N0039", "Expected `=` after let name declaration."}
 
-	LET_IN = "Expected `in` after let definition."
+	LET_IN = Message{"N0040", "Expected `in` after let definition."}
 
-	FOR_IN = "Expected `in` after for pattern."
+	FOR_IN = Message{"N0041", "Expected `in` after for pattern."}
 
-	FOR_DO = "Expected `do` after for definition."
+	FOR_DO = Message{"N0042", "Expected `do` after for definition."}
 
-	CASE_ARROW = "Expected `->` after case pattern."
+	CASE_ARROW = Message{"N0043", "Expected `->` after case pattern."}
 
-	CASE_OF = "Expected `of` after a case expression."
+	CASE_OF = Message{"N0044", "Expected `of` after a case expression."}
 
-	ALIAS_DOT = "Expected dot (.) after aliased variable."
+	ALIAS_DOT = Message{"N0045", "Expected dot (.) after aliased variable."}
 
-	MALFORMED_EXPR = "Malformed expression."
+	MALFORMED_EXPR = Message{"N0046", "Malformed expression."}
 
-	APPLIED_DO_LET = "Cannot apply let statement as a function."
+	APPLIED_DO_LET = Message{"N0047", "Cannot apply let statement as a function."}
 
-	PUB_PLUS = "Visibility of value or typealias declaration can only be public (pub) not pub+."
+	PUB_PLUS = Message{"N0048", "Visibility of value or typealias declaration can only be public (pub) not pub+."}
 
-	TYPEALIAS_NAME = "Expected name for typealias."
+	TYPEALIAS_NAME = Message{"N0049", "Expected name for typealias."}
 
-	TYPEALIAS_EQUALS = "Expected `=` after typealias declaration."
+	TYPEALIAS_EQUALS = Message{"N0050", "Expected `=` after typealias declaration."}
 
-	DATA_NAME = "Expected new data type name to be a upper case identifier."
+	DATA_NAME = Message{"N0051", "Expected new data type name to be a upper case identifier."}
 
-	DATA_EQUALS = "Expected equals `=` after data name declaration."
+	DATA_EQUALS = Message{"N0052", "Expected equals `=` after data name declaration."}
 
-	INVALID_OPERATOR_DECL = "Operator declarations have to be defined between parentheses."
+	INVALID_OPERATOR_DECL = Message{"N0053", "Operator declarations have to be defined between parentheses."}
 
-	IMPLICIT_PATTERN = "Implicit patterns can only be used in function parameters before any destructuring happens."
+	IMPLICIT_PATTERN = Message{"N0054", "Implicit patterns can only be used in function parameters before any destructuring happens."}
 
-	ANNOTATION_PATTERN = "Type annotation patterns can only be used in function variables"
+	ANNOTATION_PATTERN = Message{"N0055", "Type annotation patterns can only be used in function variables"}
 
-	NOT_A_FIELD = "Operator `<-` expects a foreign field as first parameter and cannot be partially applied."
+	NOT_A_FIELD = Message{"N0056", "Operator `<-` expects a foreign field as first parameter and cannot be partially applied."}
 
-	LET_DO_LAST = "Do expression cannot end with a let statement."
+	LET_DO_LAST = Message{"N0057", "Do expression cannot end with a let statement."}
 
-	ANONYMOUS_FUNCTION_ARGUMENT = `Invalid context for anonymous function argument.
+	ANONYMOUS_FUNCTION_ARGUMENT = Message{"N0058", `Invalid context for anonymous function argument.
 
 Valid ones are:
 Operator sections: (_ + 1)
@@ -134,80 +165,84 @@ Option unwrap: _!!
 Ifs: if _ then 1 else 0, if check then _ else _
 Cases: case _ of ...
 Foreign fields: (_ : MyClass)#-field
-Foreign methods: (_ : String)#endsWith("."), Math#exp(_)`
+Foreign methods: (_ : String)#endsWith("."), Math#exp(_)`}
 
-	RETURN_EXPR = "return keyword can only be used inside a computation expression."
+	RETURN_EXPR = Message{"N0059", "return keyword can only be used inside a computation expression."}
 
-	YIELD_EXPR = "yield keyword can only be used inside a computation expression."
+	YIELD_EXPR = Message{"N0060", "yield keyword can only be used inside a computation expression."}
 
-	FOR_EXPR = "for expression can only be used inside a computation expression."
+	FOR_EXPR = Message{"N0061", "for expression can only be used inside a computation expression."}
 
-	LET_BANG = "`let!` syntax can only be used inside a computation expression."
+	LET_BANG = Message{"N0062", "`let!` syntax can only be used inside a computation expression."}
 
-	DO_BANG = "`do!` syntax can only be used inside a computation expression."
+	DO_BANG = Message{"N0063", "`do!` syntax can only be used inside a computation expression."}
 
-	RECURSIVE_ROWS = "Recursive row types"
+	RECURSIVE_ROWS = Message{"N0064", "Recursive row types"}
 
-	RECURSIVE_LET = "Let variables cannot be recursive."
+	RECURSIVE_LET = Message{"N0065", "Let variables cannot be recursive."}
 
-	NOT_A_FUNCTION = `Expected expression to be a function.
+	NOT_A_FUNCTION = Message{"N0066", `Expected expression to be a function.
 If you are trying to pass an instance argument to a function explicitily
-make sure to use the {{}} syntax.`
+make sure to use the {{}} syntax.`}
 
-	RECORD_MERGE = "Cannot merge records with unknown labels."
+	RECORD_MERGE = Message{"N0067", "Cannot merge records with unknown labels."}
+
+	REDUNDANT_MATCH = Message{"N0110", "This case is unreachable: all the values it matches are matched by the cases above."}
 )
 
-func UndefinedVarInCtor(name string, typeVars []string) string {
+func UndefinedVarInCtor(name string, typeVars []string) Message {
 	if len(typeVars) == 1 {
-		return fmt.Sprintf("The variable %s is undefined in constructor %s.", typeVars[0], name)
+		return Message{"N0068", fmt.Sprintf("The variable %s is undefined in constructor %s.", typeVars[0], name)}
 	}
 	vars := JoinToStringFunc(typeVars, ", ", func(x string) string { return x })
-	return fmt.Sprintf("The variables %s are undefined in constructor %s.", vars, name)
+	return Message{"N0068", fmt.Sprintf("The variables %s are undefined in constructor %s.", vars, name)}
 }
 
-func CannotFindInModule(name string, module string) string {
-	return fmt.Sprintf("Cannot find %s in module %s.", name, module)
+func CannotFindInModule(name string, module string) Message {
+	return Message{"N0069", fmt.Sprintf("Cannot find %s in module %s.", name, module)}
 }
 
-func CannotImportInModule(name string, module string) string {
-	return fmt.Sprintf("Cannot import private %s in module %s.", name, module)
+func CannotImportInModule(name string, module string) Message {
+	return Message{"N0070", fmt.Sprintf("Cannot import private %s in module %s.", name, module)}
 }
 
-func UndefinedVar(name string) string {
-	return fmt.Sprintf("Undefined variable %s.", name)
+func UndefinedVar(name string) Message {
+	return Message{"N0071", fmt.Sprintf("Undefined variable %s.", name)}
 }
 
-func UndefinedType(typ string) string {
-	return fmt.Sprintf(`Undefined type %s
+func UndefinedType(typ string) Message {
+	return Message{"N0072", fmt.Sprintf(`Undefined type %s
         
-	Make sure the type is imported: import some.module (MyType)`, typ)
+	Make sure the type is imported: import some.module (MyType)`, typ)}
 }
 
-func WrongKind(expected, got string) string {
-	return fmt.Sprintf(`Could not match kind
+func WrongKind(expected, got string) Message {
+	return Message{"N0073", fmt.Sprintf(`Could not match kind
         
 	%s
 	
 with kind
 
-	%s`, expected, got)
+	%s`, expected, got)}
 }
 
-func NotARow(typ string) string {
-	return fmt.Sprintf(`Type
+func NotARow(typ string) Message {
+	return Message{"N0074", fmt.Sprintf(`Type
         
 	%s
 
-is a not a row type.`, typ)
+is a not a row type.`, typ)}
 }
 
-func RecordMissingLabels(labels string) string {
-	return fmt.Sprintf(`Record is missing labels:
+func RecordMissingLabels(labels string) Message {
+	return Message{"N0075", fmt.Sprintf(`Record is missing labels:
     
-	  %s`, labels)
+	  %s`, labels)}
 }
 
-func TypesDontMatch(a, b, reason string) string {
+// A type mismatch is always N0076, so the same error has the same code,
+// the reason the types don't match, if there's one, is added to the text
+func TypesDontMatch(a, b string, reason Message) Message {
 	str := fmt.Sprintf(`Cannot match type
             
 	  %s
@@ -216,124 +251,163 @@ with type
 
 	  %s`, a, b)
 
-	if reason != "" {
-		return fmt.Sprintf("%s\n\n%s", str, reason)
+	if reason.Text != "" {
+		return Message{"N0076", fmt.Sprintf("%s\n\n%s", str, reason.Text)}
 	}
-	return str
+	return Message{"N0076", str}
 }
 
-func EscapeType(typ string) string {
-	return fmt.Sprintf(`Private type %s escaped its module.
+func EscapeType(typ string) Message {
+	return Message{"N0077", fmt.Sprintf(`Private type %s escaped its module.
         
-A public function cannot have a private type.`, typ)
+A public function cannot have a private type.`, typ)}
 }
 
-func IncompatibleTypes(t1, t2 string) string {
-	return fmt.Sprintf("Incompatible types %s and %s.", t1, t2)
+func IncompatibleTypes(t1, t2 string) Message {
+	return Message{"N0078", fmt.Sprintf("Incompatible types %s and %s.", t1, t2)}
 }
 
-func InfiniteType(name string) string {
-	return fmt.Sprintf("Occurs check failed: infinite type %s.", name)
+func InfiniteType(name string) Message {
+	return Message{"N0079", fmt.Sprintf("Occurs check failed: infinite type %s.", name)}
 }
 
-func DuplicateModule(name string) string {
-	return fmt.Sprintf(`Found duplicate module
+func DuplicateModule(name string) Message {
+	return Message{"N0080", fmt.Sprintf(`Found duplicate module
         
-	  %s`, name)
+	  %s`, name)}
+}
+
+// Modules are in import order: every module imports the next
+// and the last imports the first. locations[i] is where modules[i] imports the next.
+func CycleFound(modules []string, locations []string) Message {
+	chain := JoinToStringStr(modules, " -> ") + " -> " + modules[0]
+	var imports strings.Builder
+	for i, mod := range modules {
+		imports.WriteString(fmt.Sprintf("\n    %s imports %s at %s", mod, modules[(i+1)%len(modules)], locations[i]))
+	}
+	return Message{"N0081", fmt.Sprintf("Found cycle between modules:\n\n    %s\n%s", chain, imports.String())}
+}
+
+func ModuleNotFound(name string) Message {
+	return Message{"N0082", fmt.Sprintf("Could not find module %s.", name)}
+}
+
+func ExpectedDefinition(name string) Message {
+	return Message{"N0083", fmt.Sprintf("Expected definition to follow its type declaration for %s.", name)}
+}
+
+func ExpectedLetDefinition(name string) Message {
+	return Message{"N0084", fmt.Sprintf("Expected definition to follow its type declaration for %s in let clause.", name)}
+}
+
+func EmptyImport(ctx string) Message {
+	return Message{"N0085", fmt.Sprintf("%s list cannot be empty.", ctx)}
+}
+
+func WrongArityToCase(got int, expected int) Message {
+	return Message{"N0086", fmt.Sprintf("Case expression expected %d patterns but got %d.", got, expected)}
+}
+
+func WrongArityCtorPattern(name string, got, expected int) Message {
+	return Message{"N0087", fmt.Sprintf("Constructor pattern %s expected %d parameter(s) but got %d.", name, expected, got)}
+}
+
+func OpTooLong(op string) Message {
+	return Message{"N0088", fmt.Sprintf("Operator %s is too long. Operators cannot contain more than 3 characters.", op)}
 }
 
-func CycleFound(nodes []string) string {
-	return fmt.Sprintf("Found cycle between modules\n\n%s", JoinToStringFunc(nodes, "\n\n", func(s string) string { return "    " + s }))
+func ShadowedVariable(name string) Message {
+	return Message{"N0089", fmt.Sprintf("Value %s is shadowing another value with the same name.", name)}
 }
 
-func ModuleNotFound(name string) string {
-	return fmt.Sprintf("Could not find module %s.", name)
+func NoAliasFound(alias string) Message {
+	return Message{"N0090", fmt.Sprintf("Could not find import alias %s.", alias)}
 }
 
-func ExpectedDefinition(name string) string {
-	return fmt.Sprintf("Expected definition to follow its type declaration for %s.", name)
+func WrongConstructorName(typeName string) Message {
+	return Message{"N0091", fmt.Sprintf("Multi constructor type cannot have the same name as their type: %s.", typeName)}
 }
 
-func ExpectedLetDefinition(name string) string {
-	return fmt.Sprintf("Expected definition to follow its type declaration for %s in let clause.", name)
+func DuplicatedDecl(name string) Message {
+	return Message{"N0092", fmt.Sprintf("Declaration %s is already defined or imported.", name)}
 }
 
-func EmptyImport(ctx string) string {
-	return fmt.Sprintf("%s list cannot be empty.", ctx)
+func DuplicatedType(name string) Message {
+	return Message{"N0093", fmt.Sprintf("Type %s is already defined or imported.", name)}
 }
 
-func WrongArityToCase(got int, expected int) string {
-	return fmt.Sprintf("Case expression expected %d patterns but got %d.", got, expected)
+func UnusedVariable(varr string) Message {
+	return Message{"N0094", fmt.Sprintf("Variable %s is unused in declaration.", varr)}
 }
 
-func WrongArityCtorPattern(name string, got, expected int) string {
-	return fmt.Sprintf("Constructor pattern %s expected %d parameter(s) but got %d.", name, expected, got)
+func CycleInValues(nodes []string) Message {
+	return Message{"N0095", fmt.Sprintf("Found cycle between values %s.", JoinToStringStr(nodes, ", "))}
 }
 
-func OpTooLong(op string) string {
-	return fmt.Sprintf("Operator %s is too long. Operators cannot contain more than 3 characters.", op)
+func CycleInFunctions(nodes []string) Message {
+	return Message{"N0096", fmt.Sprintf("Mutually recursive functions %s need type annotations.", JoinToStringStr(nodes, ", "))}
 }
 
-func ShadowedVariable(name string) string {
-	return fmt.Sprintf("Value %s is shadowing another value with the same name.", name)
+func LiteralExpected(name string) Message {
+	return Message{"N0097", fmt.Sprintf("Expected %s literal.", name)}
 }
 
-func NoAliasFound(alias string) string {
-	return fmt.Sprintf("Could not find import alias %s.", alias)
+func LParensExpected(ctx string) Message {
+	return Message{"N0098", fmt.Sprintf("Expected `(` after %s", ctx)}
 }
 
-func WrongConstructorName(typeName string) string {
-	return fmt.Sprintf("Multi constructor type cannot have the same name as their type: %s.", typeName)
+func RParensExpected(ctx string) Message {
+	return Message{"N0099", fmt.Sprintf("Expected `)` after %s", ctx)}
 }
 
-func DuplicatedDecl(name string) string {
-	return fmt.Sprintf("Declaration %s is already defined or imported.", name)
+func RSBracketExpected(ctx string) Message {
+	return Message{"N0100", fmt.Sprintf("Expected `]` after %s", ctx)}
 }
 
-func DuplicatedType(name string) string {
-	return fmt.Sprintf("Type %s is already defined or imported.", name)
+func RBracketExpected(ctx string) Message {
+	return Message{"N0101", fmt.Sprintf("Expected `}` after %s", ctx)}
 }
 
-func UnusedVariable(varr string) string {
-	return fmt.Sprintf("Variable %s is unused in declaration.", varr)
+func PipeExpected(ctx string) Message {
+	return Message{"N0102", fmt.Sprintf("Expected `|` after %s.", ctx)}
 }
 
-func CycleInValues(nodes []string) string {
-	return fmt.Sprintf("Found cycle between values %s.", JoinToStringStr(nodes, ", "))
+func CommaExpected(ctx string) Message {
+	return Message{"N0103", fmt.Sprintf("Expected `,` after %s.", ctx)}
 }
 
-func CycleInFunctions(nodes []string) string {
-	return fmt.Sprintf("Mutually recursive functions %s need type annotations.", JoinToStringStr(nodes, ", "))
+func EqualsExpected(ctx string) Message {
+	return Message{"N0104", fmt.Sprintf("Expected `=` after %s.", ctx)}
 }
 
-func LiteralExpected(name string) string {
-	return fmt.Sprintf("Expected %s literal.", name)
+func UnexpectedCharacter(c string) Message {
+	return Message{"N0105", fmt.Sprintf("Unexpected character %s.", c)}
 }
 
-func LParensExpected(ctx string) string {
-	return fmt.Sprintf("Expected `(` after %s", ctx)
+func InvalidNumber(num string) Message {
+	return Message{"N0106", fmt.Sprintf("Invalid number %s.", num)}
 }
 
-func RParensExpected(ctx string) string {
-	return fmt.Sprintf("Expected `)` after %s", ctx)
+func UnexpectedToken(token string) Message {
+	return Message{"N0107", fmt.Sprintf("Unexpected token %s.", token)}
 }
 
-func RSBracketExpected(ctx string) string {
-	return fmt.Sprintf("Expected `]` after %s", ctx)
+func ModuleNameMismatch(name, expected string) Message {
+	return Message{"N0108", fmt.Sprintf("Module %s should be named %s to match its path in the source root.", name, expected)}
 }
 
-func RBracketExpected(ctx string) string {
-	return fmt.Sprintf("Expected `}` after %s", ctx)
+func NonExhaustiveMatch(missing []string) Message {
+	return Message{"N0109", fmt.Sprintf("Pattern match is not exhaustive. Cases not matched:\n\n    %s", strings.Join(missing, "\n    "))}
 }
 
-func PipeExpected(ctx string) string {
-	return fmt.Sprintf("Expected `|` after %s.", ctx)
+func MainFunctionExpected(module string) Message {
+	return Message{"N0111", fmt.Sprintf("The entry module %s should declare a main function of type List String -> Unit.", module)}
 }
 
-func CommaExpected(ctx string) string {
-	return fmt.Sprintf("Expected `,` after %s.", ctx)
+func InternalError(msg string) Message {
+	return Message{"N0112", fmt.Sprintf("Internal compiler error: the generated go code is invalid: %s. Please report this bug.", msg)}
 }
 
-func EqualsExpected(ctx string) string {
-	return fmt.Sprintf("Expected `=` after %s.", ctx)
+func FormatterError(msg string) Message {
+	return Message{"N0113", fmt.Sprintf("Internal formatter error: %s. The source was not changed. Please report this bug.", msg)}
 }
@@ -0,0 +1,44 @@
+package data
+
+import (
+	"embed"
+	"sort"
+	"strings"
+)
+
+// Long explanations for every diagnostic code.
+// Each file is named after its code and starts with a
+// `CODE: title` line followed by the explanation and examples.
+//
+//go:embed explain/*.txt
+var explanations embed.FS
+
+// Returns the full explanation of a diagnostic code.
+func Explain(code string) (string, bool) {
+	text, err := explanations.ReadFile("explain/" + code + ".txt")
+	if err != nil {
+		return "", false
+	}
+	return string(text), true
+}
+
+// Returns the short title of a diagnostic code.
+func ExplainTitle(code string) (string, bool) {
+	text, has := Explain(code)
+	if !has {
+		return "", false
+	}
+	title, _, _ := strings.Cut(text, "\n")
+	return strings.TrimPrefix(title, code+": "), true
+}
+
+// Returns all the codes with an explanation in order.
+func ExplainedCodes() []string {
+	entries, _ := explanations.ReadDir("explain")
+	codes := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		codes = append(codes, strings.TrimSuffix(entry.Name(), ".txt"))
+	}
+	sort.Strings(codes)
+	return codes
+}
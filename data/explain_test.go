@@ -0,0 +1,55 @@
+package data
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestEveryCodeIsExplained(t *testing.T) {
+	src, err := os.ReadFile("errors.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes := NewSet[string]()
+	for _, code := range regexp.MustCompile(`"(N\d{4})"`).FindAllStringSubmatch(string(src), -1) {
+		codes.Add(code[1])
+	}
+
+	for code := range codes.Inner() {
+		text, has := Explain(code)
+		if !has {
+			t.Errorf("Code %s has no explanation", code)
+			continue
+		}
+		if !strings.HasPrefix(text, code+": ") || !strings.Contains(text, "Bad:") || !strings.Contains(text, "Fixed:") {
+			t.Errorf("Explanation for %s is malformed", code)
+		}
+	}
+
+	explained := ExplainedCodes()
+	if len(explained) != codes.Size() {
+		t.Errorf("Expected %d explanations, got %d", codes.Size(), len(explained))
+	}
+}
+
+func TestExplainTitle(t *testing.T) {
+	title, has := ExplainTitle("N0071")
+	if !has || title != "Undefined variable" {
+		t.Errorf("Result was incorrect: got %s", title)
+	}
+	if _, has := Explain("N9999"); has {
+		t.Error("Expected no explanation for unknown code")
+	}
+}
+
+func TestTypeMismatchKeepsItsCode(t *testing.T) {
+	msg := TypesDontMatch("Int", "String", InfiniteType("t1"))
+	if msg.Code != "N0076" || !strings.HasSuffix(msg.Text, "\n\nOccurs check failed: infinite type t1.") {
+		t.Errorf("Result was incorrect: got %s %s", msg.Code, msg.Text)
+	}
+	if msg := TypesDontMatch("Int", "String", Message{}); msg.Code != "N0076" {
+		t.Errorf("Result was incorrect: got %s", msg.Code)
+	}
+}
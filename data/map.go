@@ -7,3 +7,11 @@ func MapValues[K comparable, V any](m map[K]V) []V {
 	}
 	return res
 }
+
+func MapKeys[K comparable, V any](m map[K]V) []K {
+	res := make([]K, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	return res
+}
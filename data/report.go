@@ -0,0 +1,309 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Output formats for reporting compiler problems
+const (
+	FORMAT_TEXT  = "text"
+	FORMAT_JSON  = "json"
+	FORMAT_SARIF = "sarif"
+)
+
+var ProblemFormats = []string{FORMAT_TEXT, FORMAT_JSON, FORMAT_SARIF}
+
+func IsProblemFormat(format string) bool {
+	return InSlice(ProblemFormats, format)
+}
+
+// The usage of the --no-color flag of the commands
+const NO_COLOR_USAGE = "disable colors in the output (also disabled by setting NO_COLOR)"
+
+// The console options of the commands.
+// Colors are disabled by the --no-color flag or by setting NO_COLOR.
+func CommandConsoleOptions(noColor bool, source func(filename string) (string, bool)) ConsoleOptions {
+	_, noColorEnv := os.LookupEnv("NO_COLOR")
+	return ConsoleOptions{Color: !noColor && !noColorEnv, Source: source}
+}
+
+// Writes all problems to w in the given format.
+// Text is meant for humans, json and sarif for tools.
+// The console options are only used by the text format.
+func WriteProblems(w io.Writer, problems []CompilerProblem, format string, opts ConsoleOptions) error {
+	switch format {
+	case FORMAT_TEXT:
+		return writeProblemsText(w, problems, opts)
+	case FORMAT_JSON:
+		return writeJSON(w, toJSONReport(problems))
+	case FORMAT_SARIF:
+		return writeJSON(w, toSarifReport(problems))
+	default:
+		return fmt.Errorf("unknown format %s, expected one of: %s", format, JoinToStringStr(ProblemFormats, ", "))
+	}
+}
+
+func writeProblemsText(w io.Writer, problems []CompilerProblem, opts ConsoleOptions) error {
+	for _, problem := range problems {
+		if _, err := fmt.Fprintln(w, problem.Format(opts)); err != nil {
+			return err
+		}
+	}
+	errors, warnings := CountProblems(problems)
+	_, err := fmt.Fprintf(w, "%s, %s\n", pluralize(errors, "error"), pluralize(warnings, "warning"))
+	return err
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func pluralize(count int, word string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, word)
+	}
+	return fmt.Sprintf("%d %ss", count, word)
+}
+
+func SeverityName(sev Severity) string {
+	switch sev {
+	case WARN:
+		return "warning"
+	case ERROR:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
+
+///////////////////////////////////////////
+// JSON
+///////////////////////////////////////////
+
+// The json schema is versioned and
+// fields should only be added, never changed.
+const jsonReportVersion = 1
+
+type jsonReport struct {
+	Version  int           `json:"version"`
+	Problems []jsonProblem `json:"problems"`
+}
+
+type jsonProblem struct {
+	Severity      string   `json:"severity"`
+	Code          string   `json:"code"`
+	Message       string   `json:"message"`
+	Module        string   `json:"module"`
+	Filename      string   `json:"filename"`
+	Span          jsonSpan `json:"span"`
+	TypingContext string   `json:"typingContext"`
+}
+
+type jsonSpan struct {
+	Start jsonPos `json:"start"`
+	End   jsonPos `json:"end"`
+}
+
+type jsonPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func toJSONReport(problems []CompilerProblem) jsonReport {
+	return jsonReport{
+		Version: jsonReportVersion,
+		Problems: MapSlice(problems, func(p CompilerProblem) jsonProblem {
+			return jsonProblem{
+				Severity: SeverityName(p.Severity),
+				Code:     p.Code,
+				Message:  p.Msg,
+				Module:   p.Module,
+				Filename: p.Filename,
+				Span: jsonSpan{
+					Start: jsonPos{Line: p.Span.Start.Line, Column: p.Span.Start.Col},
+					End:   jsonPos{Line: p.Span.End.Line, Column: p.Span.End.Col},
+				},
+				TypingContext: p.TypingContext,
+			}
+		}),
+	}
+}
+
+///////////////////////////////////////////
+// SARIF
+///////////////////////////////////////////
+
+// Static Analysis Results Interchange Format.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifReport struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalUriBaseIds map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	Help             sarifMessage `json:"help"`
+}
+
+type sarifResult struct {
+	RuleId     string          `json:"ruleId,omitempty"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties sarifProperties `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	Uri       string `json:"uri"`
+	UriBaseId string `json:"uriBaseId,omitempty"`
+}
+
+// The base of the relative source uris: the directory the compiler runs in
+const sarifSourceRoot = "%SRCROOT%"
+
+// SARIF lines and columns are 1-based and the end column is exclusive,
+// the same as novah spans.
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifProperties struct {
+	Module        string `json:"module,omitempty"`
+	TypingContext string `json:"typingContext,omitempty"`
+}
+
+func toSarifReport(problems []CompilerProblem) sarifReport {
+	results := MapSlice(problems, func(p CompilerProblem) sarifResult {
+		level := "error"
+		if p.Severity == WARN {
+			level = "warning"
+		}
+		return sarifResult{
+			RuleId:  p.Code,
+			Level:   level,
+			Message: sarifMessage{Text: p.Msg},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactFromPath(p.Filename),
+				Region:           sarifRegionFromSpan(p.Span),
+			}}},
+			Properties: sarifProperties{Module: p.Module, TypingContext: p.TypingContext},
+		}
+	})
+	driver := sarifDriver{Name: "novah", InformationUri: "https://github.com/stackoverflow/novah-go", Rules: sarifRules(problems)}
+	return sarifReport{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, OriginalUriBaseIds: sarifBaseIds(), Results: results}},
+	}
+}
+
+// SARIF locations are uri references: absolute paths become file uris
+// and relative paths are relative to the source root.
+func sarifArtifactFromPath(path string) sarifArtifactLocation {
+	if filepath.IsAbs(path) {
+		return sarifArtifactLocation{Uri: fileUri(path)}
+	}
+	return sarifArtifactLocation{Uri: (&url.URL{Path: filepath.ToSlash(path)}).String(), UriBaseId: sarifSourceRoot}
+}
+
+func sarifBaseIds() map[string]sarifArtifactLocation {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	uri := fileUri(dir)
+	if !strings.HasSuffix(uri, "/") {
+		uri += "/"
+	}
+	return map[string]sarifArtifactLocation{sarifSourceRoot: {Uri: uri}}
+}
+
+// The file uri of an absolute path, windows drive letters included (file:///C:/src)
+func fileUri(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// Returns one rule for every distinct code in the problems
+func sarifRules(problems []CompilerProblem) []sarifRule {
+	rules := make([]sarifRule, 0)
+	seen := NewSet[string]()
+	for _, p := range problems {
+		if p.Code == "" || seen.Contains(p.Code) {
+			continue
+		}
+		seen.Add(p.Code)
+		title, _ := ExplainTitle(p.Code)
+		help, _ := Explain(p.Code)
+		rules = append(rules, sarifRule{Id: p.Code, ShortDescription: sarifMessage{Text: title}, Help: sarifMessage{Text: help}})
+	}
+	return rules
+}
+
+func sarifRegionFromSpan(span Span) sarifRegion {
+	// regions cannot start at line or column 0
+	reg := sarifRegion{StartLine: span.Start.Line, StartColumn: span.Start.Col, EndLine: span.End.Line, EndColumn: span.End.Col}
+	if reg.StartLine < 1 {
+		reg.StartLine = 1
+	}
+	if reg.StartColumn < 1 {
+		reg.StartColumn = 1
+	}
+	if reg.EndLine < reg.StartLine {
+		reg.EndLine = reg.StartLine
+	}
+	if reg.EndLine == reg.StartLine && reg.EndColumn < reg.StartColumn {
+		reg.EndColumn = reg.StartColumn
+	}
+	return reg
+}
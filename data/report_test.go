@@ -0,0 +1,131 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var reportProblems = []CompilerProblem{
+	{
+		Msg:      "Variable y is unused in declaration.",
+		Code:     "N0094",
+		Span:     NewSpan2(3, 5, 3, 6),
+		Filename: "src/main.novah",
+		Module:   "main",
+		Severity: WARN,
+	},
+	{
+		Msg:           "Undefined variable z.",
+		Code:          "N0071",
+		Span:          NewSpan2(4, 3, 4, 4),
+		Filename:      "src/main.novah",
+		Module:        "main",
+		Severity:      ERROR,
+		TypingContext: "while checking z",
+	},
+}
+
+func TestJSONReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteProblems(&buf, reportProblems, FORMAT_JSON, ConsoleOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var report jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Version != jsonReportVersion || len(report.Problems) != 2 {
+		t.Fatalf("Result was incorrect: got %v", report)
+	}
+	p := report.Problems[1]
+	if p.Severity != "error" || p.Code != "N0071" || p.Message != "Undefined variable z." || p.Module != "main" || p.Filename != "src/main.novah" {
+		t.Errorf("Result was incorrect: got %v", p)
+	}
+	if p.Span.Start.Line != 4 || p.Span.Start.Column != 3 || p.Span.End.Line != 4 || p.Span.End.Column != 4 {
+		t.Errorf("Result was incorrect: got %v", p.Span)
+	}
+	if p.TypingContext != "while checking z" {
+		t.Errorf("Result was incorrect: got %s", p.TypingContext)
+	}
+}
+
+func TestSarifReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteProblems(&buf, reportProblems, FORMAT_SARIF, ConsoleOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var report sarifReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Version != "2.1.0" || len(report.Runs) != 1 || len(report.Runs[0].Results) != 2 {
+		t.Fatalf("Result was incorrect: got %v", report)
+	}
+	res := report.Runs[0].Results
+	if res[0].Level != "warning" || res[1].Level != "error" {
+		t.Errorf("Result was incorrect: got %s and %s", res[0].Level, res[1].Level)
+	}
+	if res[1].RuleId != "N0071" {
+		t.Errorf("Result was incorrect: expected rule N0071, got %s", res[1].RuleId)
+	}
+	rules := report.Runs[0].Tool.Driver.Rules
+	if len(rules) != 2 || rules[1].Id != "N0071" || rules[1].ShortDescription.Text != "Undefined variable" {
+		t.Errorf("Result was incorrect: got %v", rules)
+	}
+	loc := res[1].Locations[0].PhysicalLocation
+	if loc.ArtifactLocation != (sarifArtifactLocation{"src/main.novah", "%SRCROOT%"}) || loc.Region != (sarifRegion{4, 3, 4, 4}) {
+		t.Errorf("Result was incorrect: got %v", loc)
+	}
+	root := report.Runs[0].OriginalUriBaseIds["%SRCROOT%"].Uri
+	if !strings.HasPrefix(root, "file:///") || !strings.HasSuffix(root, "/") {
+		t.Errorf("Result was incorrect: got source root %s", root)
+	}
+}
+
+func TestSarifUris(t *testing.T) {
+	abs, err := filepath.Abs(filepath.Join("my src", "main#1.novah"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := sarifArtifactFromPath(abs)
+	if !strings.HasPrefix(loc.Uri, "file:///") || !strings.HasSuffix(loc.Uri, "/my%20src/main%231.novah") || loc.UriBaseId != "" {
+		t.Errorf("Result was incorrect: got %v", loc)
+	}
+	loc = sarifArtifactFromPath(filepath.Join("my src", "a:b.novah"))
+	if loc.Uri != "my%20src/a:b.novah" || loc.UriBaseId != "%SRCROOT%" {
+		t.Errorf("Result was incorrect: got %v", loc)
+	}
+	if uri := sarifArtifactFromPath("a:b.novah").Uri; uri != "./a:b.novah" {
+		t.Errorf("Result was incorrect: got %s", uri)
+	}
+}
+
+func TestInvalidFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteProblems(&buf, reportProblems, "xml", ConsoleOptions{}); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestCommandConsoleOptions(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	os.Unsetenv("NO_COLOR")
+	source := func(string) (string, bool) { return "module main", true }
+
+	if opts := CommandConsoleOptions(false, source); !opts.Color || opts.Source == nil {
+		t.Error("Expected colors and the source")
+	}
+	if CommandConsoleOptions(true, source).Color {
+		t.Error("Expected no colors with --no-color")
+	}
+	os.Setenv("NO_COLOR", "")
+	if CommandConsoleOptions(false, source).Color {
+		t.Error("Expected no colors with NO_COLOR")
+	}
+}
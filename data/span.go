@@ -37,3 +37,43 @@ func (s Span) SameLine(other Span) bool {
 func (s Span) IsEmpty() bool {
 	return s.Start.Line == 0 && s.Start.Col == 0 && s.End.Line == 0 && s.End.Col == 0
 }
+
+// The number of columns of a tab in source positions
+const TAB_COLUMNS = 2
+
+// Returns the index of the rune at column col of a source line,
+// a column inside a tab is the rune after it.
+// Columns past the end of the line count one per column after the last rune.
+func RuneIndex(line []rune, col int) int {
+	c := 1
+	for i, r := range line {
+		if c >= col {
+			return i
+		}
+		c += columnsOf(r)
+	}
+	if col < c {
+		return len(line)
+	}
+	return len(line) + col - c
+}
+
+// Returns the column of the rune at index i of a source line, the inverse of RuneIndex.
+func RuneColumn(line []rune, i int) int {
+	col := 1
+	for j := 0; j < i; j++ {
+		if j < len(line) {
+			col += columnsOf(line[j])
+		} else {
+			col++
+		}
+	}
+	return col
+}
+
+func columnsOf(r rune) int {
+	if r == '\t' {
+		return TAB_COLUMNS
+	}
+	return 1
+}
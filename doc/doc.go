@@ -0,0 +1,436 @@
+package doc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	"github.com/stackoverflow/novah-go/compiler/lexer"
+	tc "github.com/stackoverflow/novah-go/compiler/typechecker"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+const (
+	FORMAT_MARKDOWN = "markdown"
+	FORMAT_HTML     = "html"
+)
+
+type Options struct {
+	Format string
+	// the title of the index page
+	Title string
+}
+
+// A generated file, relative to the output directory.
+type Page struct {
+	Path    string
+	Content string
+}
+
+// Generates the documentation of the public declarations of all
+// the given modules: one page per module, an index page and a search index.
+// Standard library modules are skipped.
+func Generate(modules map[string]tc.FullModuleEnv, opts Options) ([]Page, error) {
+	var r renderer
+	switch opts.Format {
+	case FORMAT_MARKDOWN, "":
+		r = markdownRenderer{}
+	case FORMAT_HTML:
+		r = htmlRenderer{}
+	default:
+		return nil, fmt.Errorf("invalid format %s, expected markdown or html", opts.Format)
+	}
+	if opts.Title == "" {
+		opts.Title = "API documentation"
+	}
+
+	g := newGenerator(modules)
+	docs := data.MapSlice(g.modules, g.moduleDoc)
+
+	pages := make([]Page, 0, len(docs)+3)
+	for _, mod := range docs {
+		pages = append(pages, Page{Path: r.pageName(mod.Name), Content: r.module(mod)})
+	}
+	pages = append(pages, Page{Path: r.pageName(""), Content: r.index(opts.Title, docs)})
+
+	index := searchIndex(docs, r)
+	js, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	pages = append(pages, Page{Path: SEARCH_INDEX, Content: string(js) + "\n"})
+	if extra, has := r.(searchScript); has {
+		pages = append(pages, extra.searchScript(string(js)))
+	}
+	return pages, nil
+}
+
+const SEARCH_INDEX = "search-index.json"
+
+type moduleDoc struct {
+	Name    string
+	Comment string
+	Types   []typeDoc
+	Aliases []aliasDoc
+	Values  []valueDoc
+}
+
+type typeDoc struct {
+	Name    string
+	TyVars  []string
+	Comment string
+	Ctors   []valueDoc
+}
+
+type aliasDoc struct {
+	Name    string
+	TyVars  []string
+	Type    []token
+	Comment string
+}
+
+type valueDoc struct {
+	Name       string
+	Type       []token
+	Comment    string
+	IsInstance bool
+}
+
+// A piece of a pretty printed type.
+// Type names defined in a documented module link to their declaration.
+type token struct {
+	Text string
+	// the module and anchor of the linked type, if any
+	Module string
+	Anchor string
+}
+
+func (t token) isLink() bool {
+	return t.Module != ""
+}
+
+func plain(toks []token) string {
+	return data.JoinToStringFunc(toks, "", func(t token) string { return t.Text })
+}
+
+type generator struct {
+	modules []tc.FullModuleEnv
+	// module of every documented type by full name
+	types map[string]string
+	// full names of every documented type by simple name
+	simpleNames map[string][]string
+}
+
+func newGenerator(modules map[string]tc.FullModuleEnv) *generator {
+	g := &generator{types: make(map[string]string), simpleNames: make(map[string][]string)}
+	for _, mod := range modules {
+		if mod.IsStdlib {
+			continue
+		}
+		g.modules = append(g.modules, mod)
+	}
+	sort.Slice(g.modules, func(i, j int) bool { return g.modules[i].Ast.Name.Val < g.modules[j].Ast.Name.Val })
+
+	for _, mod := range g.modules {
+		name := mod.Ast.Name.Val
+		add := func(tname string) {
+			full := name + "." + tname
+			g.types[full] = name
+			g.simpleNames[tname] = append(g.simpleNames[tname], full)
+		}
+		for _, decl := range mod.Ast.Decls {
+			if td, isType := decl.(ast.TypeDecl); isType && td.IsPublic() {
+				add(td.Name.Val)
+			}
+		}
+		for _, alias := range mod.Aliases {
+			if alias.Visibility == ast.PUBLIC {
+				add(alias.Name)
+			}
+		}
+	}
+	return g
+}
+
+func (g *generator) moduleDoc(mod tc.FullModuleEnv) moduleDoc {
+	name := mod.Ast.Name.Val
+	doc := moduleDoc{Name: name, Comment: commentText(mod.Comment)}
+	showType := func(t ast.Type, names map[int]string) []token {
+		return g.link(ast.ShowTypeInner(t, true, typeVarNames(t, mod.TypeVarsMap, names)), name)
+	}
+
+	for _, decl := range mod.Ast.Decls {
+		if !decl.IsPublic() {
+			continue
+		}
+		switch d := decl.(type) {
+		case ast.TypeDecl:
+			td := typeDoc{Name: d.Name.Val, TyVars: d.TyVars, Comment: commentText(d.Comment)}
+			for _, ctor := range d.DataCtors {
+				ref, has := mod.Env.Decls[ctor.Name.Val]
+				if ctor.Visibility != ast.PUBLIC || !has {
+					continue
+				}
+				td.Ctors = append(td.Ctors, valueDoc{Name: ctor.Name.Val, Type: showType(ref.Type, ctorTypeVars(ref.Type, d.TyVars))})
+			}
+			doc.Types = append(doc.Types, td)
+		case ast.ValDecl:
+			ref, has := mod.Env.Decls[d.Name.Val]
+			if !has {
+				continue
+			}
+			doc.Values = append(doc.Values, valueDoc{Name: d.Name.Val, Type: showType(ref.Type, nil), Comment: commentText(d.Comment), IsInstance: d.IsInstance})
+		}
+	}
+
+	fmter := ast.NewFormatter()
+	for _, alias := range mod.Aliases {
+		if alias.Visibility != ast.PUBLIC {
+			continue
+		}
+		typ := g.link(fmter.ShowType(alias.Type), name)
+		doc.Aliases = append(doc.Aliases, aliasDoc{Name: alias.Name, TyVars: alias.TyVars, Type: typ, Comment: commentText(alias.Comment)})
+	}
+	return doc
+}
+
+// Names the type variables of a constructor type by the type variables of its type declaration:
+// the constructor returns the type applied to them.
+func ctorTypeVars(typ ast.Type, tyVars []string) map[int]string {
+	names := make(map[int]string)
+	ret := ast.RealType(typ)
+	for {
+		arr, isArrow := ret.(ast.TArrow)
+		if !isArrow {
+			break
+		}
+		ret = ast.RealType(arr.Ret)
+	}
+	if app, isApp := ret.(ast.TApp); isApp {
+		for i, ty := range app.Types {
+			if tv, isVar := ast.RealType(ty).(ast.TVar); isVar && i < len(tyVars) {
+				names[tv.Tvar.Id] = tyVars[i]
+			}
+		}
+	}
+	return names
+}
+
+// Names the type variables of a type for the docs: the ones in names or named by the checker
+// keep their name and the others, which only have an internal id, are named a, b, c... in order.
+func typeVarNames(typ ast.Type, checked map[int]string, names map[int]string) map[int]string {
+	res := make(map[int]string)
+	taken := data.NewSet[string]()
+	var unnamed []int
+	ast.EverywhereTypeUnit(typ, func(t ast.Type) {
+		tv, isVar := t.(ast.TVar)
+		if !isVar || tv.Tvar.Tag == ast.LINK {
+			return
+		}
+		if _, has := res[tv.Tvar.Id]; has || data.InSlice(unnamed, tv.Tvar.Id) {
+			return
+		}
+		if name, has := names[tv.Tvar.Id]; has {
+			res[tv.Tvar.Id] = name
+			taken.Add(name)
+		} else if name, has := checked[tv.Tvar.Id]; has {
+			res[tv.Tvar.Id] = name
+			taken.Add(name)
+		} else {
+			unnamed = append(unnamed, tv.Tvar.Id)
+		}
+	})
+	next := 0
+	for _, id := range unnamed {
+		name := typeVarName(next)
+		for taken.Contains(name) {
+			next++
+			name = typeVarName(next)
+		}
+		next++
+		res[id] = name
+	}
+	return res
+}
+
+// a, b, ..., z, a1, b1, ...
+func typeVarName(i int) string {
+	name := string(rune('a' + i%26))
+	if i >= 26 {
+		name += strconv.Itoa(i / 26)
+	}
+	return name
+}
+
+// Splits a pretty printed type in tokens linking the documented types.
+// Qualified names are shown without their module.
+func (g *generator) link(typ string, module string) []token {
+	toks := make([]token, 0, 4)
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			toks = append(toks, token{Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	runes := []rune(typ)
+	for i := 0; i < len(runes); {
+		if !isIdentStart(runes[i]) {
+			text.WriteRune(runes[i])
+			i++
+			continue
+		}
+		start := i
+		for i < len(runes) && isIdentPart(runes[i]) {
+			i++
+		}
+		ident := strings.TrimRight(string(runes[start:i]), ".")
+		i = start + len([]rune(ident))
+		simple := ident[strings.LastIndex(ident, ".")+1:]
+
+		if full, has := g.resolve(ident, simple, module); has {
+			flush()
+			toks = append(toks, token{Text: simple, Module: g.types[full], Anchor: anchor(KIND_TYPE, simple)})
+			continue
+		}
+		text.WriteString(simple)
+	}
+	flush()
+	return toks
+}
+
+// Finds the full name of a type name: first as is,
+// then in the current module and at last by its simple name if it's unique.
+func (g *generator) resolve(ident, simple, module string) (string, bool) {
+	if _, has := g.types[ident]; has {
+		return ident, true
+	}
+	if full := module + "." + ident; g.types[full] != "" {
+		return full, true
+	}
+	if fulls := g.simpleNames[simple]; len(fulls) == 1 {
+		return fulls[0], true
+	}
+	return "", false
+}
+
+func isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func isIdentPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '\'' || r == '.'
+}
+
+const (
+	KIND_MODULE = "module"
+	KIND_TYPE   = "type"
+	KIND_CTOR   = "constructor"
+	KIND_ALIAS  = "typealias"
+	KIND_VALUE  = "value"
+)
+
+// Returns the html id of a declaration.
+// Characters not allowed in urls (like in operators) are hex encoded.
+func anchor(kind, name string) string {
+	var sb strings.Builder
+	sb.WriteString(kind)
+	sb.WriteRune('-')
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteString(fmt.Sprintf(".%x", r))
+		}
+	}
+	return sb.String()
+}
+
+// Returns the text of a comment without the common indentation
+// and the surrounding blank lines.
+func commentText(comment *lexer.Comment) string {
+	if comment == nil {
+		return ""
+	}
+	lines := strings.Split(strings.ReplaceAll(comment.Text, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == -1 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		}
+		lines[i] = strings.TrimRight(lines[i], " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Returns the first paragraph of a comment.
+func summary(comment string) string {
+	par, _, _ := strings.Cut(comment, "\n\n")
+	return strings.Join(strings.Fields(par), " ")
+}
+
+type searchEntry struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Module    string `json:"module"`
+	Signature string `json:"signature,omitempty"`
+	Summary   string `json:"summary,omitempty"`
+	Url       string `json:"url"`
+}
+
+func searchIndex(docs []moduleDoc, r renderer) []searchEntry {
+	entries := make([]searchEntry, 0)
+	for _, mod := range docs {
+		page := r.pageName(mod.Name)
+		url := func(kind, name string) string { return page + "#" + anchor(kind, name) }
+		entries = append(entries, searchEntry{Name: mod.Name, Kind: KIND_MODULE, Module: mod.Name, Summary: summary(mod.Comment), Url: page})
+		for _, td := range mod.Types {
+			entries = append(entries, searchEntry{Name: td.Name, Kind: KIND_TYPE, Module: mod.Name, Signature: typeHeader(td), Summary: summary(td.Comment), Url: url(KIND_TYPE, td.Name)})
+			for _, ctor := range td.Ctors {
+				entries = append(entries, searchEntry{Name: ctor.Name, Kind: KIND_CTOR, Module: mod.Name, Signature: plain(ctor.Type), Url: url(KIND_CTOR, ctor.Name)})
+			}
+		}
+		for _, alias := range mod.Aliases {
+			entries = append(entries, searchEntry{Name: alias.Name, Kind: KIND_ALIAS, Module: mod.Name, Signature: plain(alias.Type), Summary: summary(alias.Comment), Url: url(KIND_ALIAS, alias.Name)})
+		}
+		for _, val := range mod.Values {
+			entries = append(entries, searchEntry{Name: val.Name, Kind: KIND_VALUE, Module: mod.Name, Signature: plain(val.Type), Summary: summary(val.Comment), Url: url(KIND_VALUE, val.Name)})
+		}
+	}
+	return entries
+}
+
+func typeHeader(td typeDoc) string {
+	if len(td.TyVars) == 0 {
+		return td.Name
+	}
+	return td.Name + " " + strings.Join(td.TyVars, " ")
+}
+
+func aliasHeader(alias aliasDoc) string {
+	if len(alias.TyVars) == 0 {
+		return alias.Name
+	}
+	return alias.Name + " " + strings.Join(alias.TyVars, " ")
+}
@@ -0,0 +1,171 @@
+package doc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stackoverflow/novah-go/compiler"
+	tc "github.com/stackoverflow/novah-go/compiler/typechecker"
+	"github.com/stretchr/testify/assert"
+)
+
+const shapesCode = `// Geometric shapes.
+//
+// Everything here is made of integers.
+module shapes
+
+/*
+ A shape with its dimensions.
+*/
+pub+
+type Shape = Circle Int | Square Int
+
+// A shape and a label.
+pub
+typealias Labeled = { shape : Shape, label : String }
+
+// Returns the *biggest* dimension
+// of the shape.
+pub
+size : Shape -> Int
+size s = case s of
+  Circle r -> r
+  Square x -> x
+
+hidden = 3
+`
+
+const drawCode = `module draw
+
+import shapes (Shape, size)
+
+pub
+draw : Shape -> Int
+draw s = size s
+`
+
+func compileModules(t *testing.T) map[string]tc.FullModuleEnv {
+	sources := []compiler.Source{{Path: "shapes.novah", Str: shapesCode}, {Path: "draw.novah", Str: drawCode}}
+	c := compiler.NewCompilerFromSources(sources, compiler.Options{})
+	mods, _ := c.Compile()
+	if errs := c.Errors(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	return mods
+}
+
+func pages(t *testing.T, format string) map[string]string {
+	ps, err := Generate(compileModules(t), Options{Format: format})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := make(map[string]string)
+	for _, p := range ps {
+		res[p.Path] = p.Content
+	}
+	return res
+}
+
+func TestMarkdownDocs(t *testing.T) {
+	ps := pages(t, FORMAT_MARKDOWN)
+	assert.Equal(t, 4, len(ps))
+
+	expected := `# Module draw
+
+[Index](index.md)
+
+## Values
+
+<a id="value-draw"></a>
+
+### draw
+
+<pre><code>draw : <a href="shapes.md#type-Shape">Shape</a> -&gt; Int</code></pre>
+`
+	assert.Equal(t, expected, ps["draw.md"])
+
+	shapes := ps["shapes.md"]
+	assert.Contains(t, shapes, "Geometric shapes.\n\nEverything here is made of integers.\n")
+	assert.Contains(t, shapes, "### type Shape\n\nA shape with its dimensions.\n")
+	assert.Contains(t, shapes, `<a id="constructor-Circle"></a>
+<pre><code>Circle : Int -&gt; <a href="shapes.md#type-Shape">Shape</a></code></pre>`)
+	assert.Contains(t, shapes, `<pre><code>typealias Labeled = { shape : <a href="shapes.md#type-Shape">Shape</a>, label : String }</code></pre>`)
+	assert.Contains(t, shapes, "Returns the *biggest* dimension\nof the shape.\n")
+	assert.NotContains(t, shapes, "hidden")
+
+	assert.Contains(t, ps["index.md"], "- [draw](draw.md)\n- [shapes](shapes.md): Geometric shapes.\n")
+}
+
+func TestHTMLDocs(t *testing.T) {
+	ps := pages(t, FORMAT_HTML)
+	assert.Equal(t, 5, len(ps))
+
+	shapes := ps["shapes.html"]
+	assert.Contains(t, shapes, `<h3 id="type-Shape">type Shape</h3>
+<p>A shape with its dimensions.</p>`)
+	assert.Contains(t, shapes, `<p>Returns the <em>biggest</em> dimension of the shape.</p>`)
+	assert.Contains(t, ps["draw.html"], `<a href="shapes.html#type-Shape">Shape</a>`)
+	assert.Contains(t, ps["index.html"], `<script src="search-index.js"></script>`)
+	assert.Contains(t, ps["search-index.js"], "var searchIndex = [")
+}
+
+func TestSearchIndex(t *testing.T) {
+	var entries []searchEntry
+	if err := json.Unmarshal([]byte(pages(t, FORMAT_MARKDOWN)[SEARCH_INDEX]), &entries); err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Module+"."+e.Name)
+	}
+	assert.Equal(t, []string{"draw.draw", "draw.draw", "shapes.shapes", "shapes.Shape", "shapes.Circle", "shapes.Square", "shapes.Labeled", "shapes.size"}, names)
+	assert.Equal(t, searchEntry{Name: "size", Kind: KIND_VALUE, Module: "shapes", Signature: "Shape -> Int", Summary: "Returns the *biggest* dimension of the shape.", Url: "shapes.md#value-size"}, entries[7])
+}
+
+func TestInvalidDocFormat(t *testing.T) {
+	_, err := Generate(compileModules(t), Options{Format: "pdf"})
+	assert.NotNil(t, err)
+}
+
+func TestAnchor(t *testing.T) {
+	assert.Equal(t, "value-map", anchor(KIND_VALUE, "map"))
+	assert.Equal(t, "value-.3c.7c", anchor(KIND_VALUE, "<|"))
+}
+
+func TestTypeVariableNames(t *testing.T) {
+	code := `module box
+
+pub+
+type Box a = Full a | Empty
+
+pub
+idf x = x
+
+pub
+pair : b -> c -> Box b
+pair x _ = Full x
+
+pub
+swap f x y = f y x
+`
+	c := compiler.NewCompilerFromSources([]compiler.Source{{Path: "box.novah", Str: code}}, compiler.Options{})
+	mods, _ := c.Compile()
+	if errs := c.Errors(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	ps, err := Generate(mods, Options{Format: FORMAT_MARKDOWN})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var box string
+	for _, p := range ps {
+		if p.Path == "box.md" {
+			box = p.Content
+		}
+	}
+	assert.Contains(t, box, `Full : a -&gt; <a href="box.md#type-Box">Box</a> a`)
+	assert.Contains(t, box, `Empty : <a href="box.md#type-Box">Box</a> a`)
+	assert.Contains(t, box, "idf : a -&gt; a")
+	assert.Contains(t, box, `pair : b -&gt; c -&gt; <a href="box.md#type-Box">Box</a> b`)
+	assert.Contains(t, box, "swap : (a -&gt; b -&gt; c) -&gt; b -&gt; a -&gt; c")
+}
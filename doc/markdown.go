@@ -0,0 +1,116 @@
+package doc
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Converts the markdown used in comments to html.
+// Supports paragraphs, headings, lists, fenced code blocks
+// and inline code, emphasis and links.
+func MarkdownToHTML(text string) string {
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	var sb strings.Builder
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	paragraph := make([]string, 0)
+	flush := func() {
+		if len(paragraph) > 0 {
+			fmt.Fprintf(&sb, "<p>%s</p>\n", inline(strings.Join(paragraph, " ")))
+			paragraph = paragraph[:0]
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			code := make([]string, 0)
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			fmt.Fprintf(&sb, "<pre><code>%s</code></pre>\n", html.EscapeString(strings.Join(code, "\n")))
+		case headingRegex.MatchString(trimmed):
+			flush()
+			m := headingRegex.FindStringSubmatch(trimmed)
+			// comments live inside pages that already use h1 to h3
+			level := len(m[1]) + 3
+			if level > 6 {
+				level = 6
+			}
+			fmt.Fprintf(&sb, "<h%d>%s</h%d>\n", level, inline(m[2]), level)
+		case listItem(trimmed, false) != "" || listItem(trimmed, true) != "":
+			flush()
+			ordered := listItem(trimmed, false) == ""
+			tag := "ul"
+			if ordered {
+				tag = "ol"
+			}
+			fmt.Fprintf(&sb, "<%s>\n", tag)
+			for ; i < len(lines); i++ {
+				item := listItem(strings.TrimSpace(lines[i]), ordered)
+				if item == "" {
+					i--
+					break
+				}
+				fmt.Fprintf(&sb, "<li>%s</li>\n", inline(item))
+			}
+			fmt.Fprintf(&sb, "</%s>\n", tag)
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+	return sb.String()
+}
+
+var headingRegex = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+var orderedRegex = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+
+// Returns the text of a list item or an empty string
+// if the line is not an item of this kind of list.
+func listItem(line string, ordered bool) string {
+	if ordered {
+		if m := orderedRegex.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+		return ""
+	}
+	if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
+		return strings.TrimSpace(line[2:])
+	}
+	return ""
+}
+
+var linkRegex = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+var strongRegex = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+var emRegex = regexp.MustCompile(`\*([^*]+)\*`)
+
+// Renders inline markdown. Code spans are not formatted.
+func inline(text string) string {
+	var sb strings.Builder
+	parts := strings.Split(text, "`")
+	for i, part := range parts {
+		// an unclosed backtick is just text
+		if i%2 == 1 && i < len(parts)-1 {
+			fmt.Fprintf(&sb, "<code>%s</code>", html.EscapeString(part))
+			continue
+		}
+		if i%2 == 1 {
+			sb.WriteString("`")
+		}
+		s := html.EscapeString(part)
+		s = linkRegex.ReplaceAllString(s, `<a href="$2">$1</a>`)
+		s = strongRegex.ReplaceAllString(s, "<strong>$1</strong>")
+		s = emRegex.ReplaceAllString(s, "<em>$1</em>")
+		sb.WriteString(s)
+	}
+	return sb.String()
+}
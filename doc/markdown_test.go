@@ -0,0 +1,46 @@
+package doc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownToHTML(t *testing.T) {
+	md := `Adds two numbers.
+
+Returns **the sum** of *both* numbers, see [the docs](http://novah.io).
+Use ` + "`add 1 2`" + ` to get 3.
+
+# Example
+
+` + "```" + `
+add 1 <2>
+` + "```" + `
+
+- first
+- second
+
+1. one
+2. two`
+
+	expected := `<p>Adds two numbers.</p>
+<p>Returns <strong>the sum</strong> of <em>both</em> numbers, see <a href="http://novah.io">the docs</a>. Use <code>add 1 2</code> to get 3.</p>
+<h4>Example</h4>
+<pre><code>add 1 &lt;2&gt;</code></pre>
+<ul>
+<li>first</li>
+<li>second</li>
+</ul>
+<ol>
+<li>one</li>
+<li>two</li>
+</ol>
+`
+	assert.Equal(t, expected, MarkdownToHTML(md))
+}
+
+func TestMarkdownEscapesHTML(t *testing.T) {
+	assert.Equal(t, "<p>a &lt; b &amp;&amp; <code>*x*</code> `c</p>\n", MarkdownToHTML("a < b && `*x*` `c"))
+	assert.Equal(t, "", MarkdownToHTML("  \n"))
+}
@@ -0,0 +1,235 @@
+package doc
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+type renderer interface {
+	// the file name of the page of a module, or of the index if module is empty
+	pageName(module string) string
+	module(mod moduleDoc) string
+	index(title string, docs []moduleDoc) string
+}
+
+// Renderers that need to expose the search index as a script.
+type searchScript interface {
+	searchScript(index string) Page
+}
+
+// Renders a type signature with links as html.
+// Markdown pages use it too as code spans can't have links.
+func signature(r renderer, prefix string, toks []token) string {
+	var sb strings.Builder
+	sb.WriteString("<pre><code>")
+	sb.WriteString(html.EscapeString(prefix))
+	for _, t := range toks {
+		if t.isLink() {
+			fmt.Fprintf(&sb, `<a href="%s#%s">%s</a>`, r.pageName(t.Module), t.Anchor, html.EscapeString(t.Text))
+		} else {
+			sb.WriteString(html.EscapeString(t.Text))
+		}
+	}
+	sb.WriteString("</code></pre>")
+	return sb.String()
+}
+
+type markdownRenderer struct{}
+
+func (markdownRenderer) pageName(module string) string {
+	if module == "" {
+		return "index.md"
+	}
+	return module + ".md"
+}
+
+func (r markdownRenderer) module(mod moduleDoc) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Module %s\n\n", mod.Name)
+	fmt.Fprintf(&sb, "[Index](%s)\n\n", r.pageName(""))
+	writeComment := func(comment string) {
+		if comment != "" {
+			sb.WriteString(comment)
+			sb.WriteString("\n\n")
+		}
+	}
+	writeComment(mod.Comment)
+
+	if len(mod.Types) > 0 {
+		sb.WriteString("## Types\n\n")
+		for _, td := range mod.Types {
+			fmt.Fprintf(&sb, "<a id=\"%s\"></a>\n\n### type %s\n\n", anchor(KIND_TYPE, td.Name), typeHeader(td))
+			writeComment(td.Comment)
+			for _, ctor := range td.Ctors {
+				fmt.Fprintf(&sb, "<a id=\"%s\"></a>\n%s\n\n", anchor(KIND_CTOR, ctor.Name), signature(r, ctor.Name+" : ", ctor.Type))
+			}
+		}
+	}
+
+	if len(mod.Aliases) > 0 {
+		sb.WriteString("## Type aliases\n\n")
+		for _, alias := range mod.Aliases {
+			fmt.Fprintf(&sb, "<a id=\"%s\"></a>\n\n### typealias %s\n\n", anchor(KIND_ALIAS, alias.Name), alias.Name)
+			fmt.Fprintf(&sb, "%s\n\n", signature(r, "typealias "+aliasHeader(alias)+" = ", alias.Type))
+			writeComment(alias.Comment)
+		}
+	}
+
+	if len(mod.Values) > 0 {
+		sb.WriteString("## Values\n\n")
+		for _, val := range mod.Values {
+			title := val.Name
+			if val.IsInstance {
+				title = "instance " + title
+			}
+			fmt.Fprintf(&sb, "<a id=\"%s\"></a>\n\n### %s\n\n", anchor(KIND_VALUE, val.Name), escapeMarkdown(title))
+			fmt.Fprintf(&sb, "%s\n\n", signature(r, val.Name+" : ", val.Type))
+			writeComment(val.Comment)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+func (r markdownRenderer) index(title string, docs []moduleDoc) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", title)
+	sb.WriteString("## Modules\n\n")
+	for _, mod := range docs {
+		fmt.Fprintf(&sb, "- [%s](%s)", mod.Name, r.pageName(mod.Name))
+		if sum := summary(mod.Comment); sum != "" {
+			fmt.Fprintf(&sb, ": %s", sum)
+		}
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "\nAll declarations are listed in the [search index](%s).\n", SEARCH_INDEX)
+	return sb.String()
+}
+
+// Escapes the characters that could be read as markdown in operator names.
+func escapeMarkdown(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\`*_{}[]()#+-.!|<>", r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+type htmlRenderer struct{}
+
+const SEARCH_SCRIPT = "search-index.js"
+
+func (htmlRenderer) pageName(module string) string {
+	if module == "" {
+		return "index.html"
+	}
+	return module + ".html"
+}
+
+const htmlStyle = `body { font-family: sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+pre { background: #f4f4f4; padding: .5em 1em; overflow-x: auto; }
+h3 { border-bottom: 1px solid #ddd; }
+a { color: #2a5db0; }`
+
+func htmlPage(title, body string) string {
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+%s
+</style>
+</head>
+<body>
+%s
+</body>
+</html>
+`, html.EscapeString(title), htmlStyle, body)
+}
+
+func (r htmlRenderer) module(mod moduleDoc) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<nav><a href=\"%s\">Index</a></nav>\n", r.pageName(""))
+	fmt.Fprintf(&sb, "<h1>Module %s</h1>\n", html.EscapeString(mod.Name))
+	sb.WriteString(MarkdownToHTML(mod.Comment))
+
+	if len(mod.Types) > 0 {
+		sb.WriteString("<h2>Types</h2>\n")
+		for _, td := range mod.Types {
+			fmt.Fprintf(&sb, "<h3 id=\"%s\">type %s</h3>\n", anchor(KIND_TYPE, td.Name), html.EscapeString(typeHeader(td)))
+			sb.WriteString(MarkdownToHTML(td.Comment))
+			for _, ctor := range td.Ctors {
+				fmt.Fprintf(&sb, "<div id=\"%s\">%s</div>\n", anchor(KIND_CTOR, ctor.Name), signature(r, ctor.Name+" : ", ctor.Type))
+			}
+		}
+	}
+
+	if len(mod.Aliases) > 0 {
+		sb.WriteString("<h2>Type aliases</h2>\n")
+		for _, alias := range mod.Aliases {
+			fmt.Fprintf(&sb, "<h3 id=\"%s\">typealias %s</h3>\n", anchor(KIND_ALIAS, alias.Name), html.EscapeString(alias.Name))
+			fmt.Fprintf(&sb, "%s\n", signature(r, "typealias "+aliasHeader(alias)+" = ", alias.Type))
+			sb.WriteString(MarkdownToHTML(alias.Comment))
+		}
+	}
+
+	if len(mod.Values) > 0 {
+		sb.WriteString("<h2>Values</h2>\n")
+		for _, val := range mod.Values {
+			title := val.Name
+			if val.IsInstance {
+				title = "instance " + title
+			}
+			fmt.Fprintf(&sb, "<h3 id=\"%s\">%s</h3>\n", anchor(KIND_VALUE, val.Name), html.EscapeString(title))
+			fmt.Fprintf(&sb, "%s\n", signature(r, val.Name+" : ", val.Type))
+			sb.WriteString(MarkdownToHTML(val.Comment))
+		}
+	}
+	return htmlPage("Module "+mod.Name, strings.TrimRight(sb.String(), "\n"))
+}
+
+const searchJs = `var input = document.getElementById("search");
+var results = document.getElementById("results");
+input.addEventListener("input", function () {
+  var query = input.value.trim().toLowerCase();
+  results.innerHTML = "";
+  if (query === "") return;
+  searchIndex.filter(function (e) {
+    return e.name.toLowerCase().indexOf(query) !== -1;
+  }).slice(0, 50).forEach(function (e) {
+    var li = document.createElement("li");
+    var a = document.createElement("a");
+    a.href = e.url;
+    a.textContent = e.module === e.name ? e.name : e.module + "." + e.name;
+    li.appendChild(a);
+    li.appendChild(document.createTextNode(" (" + e.kind + ")" + (e.signature ? " : " + e.signature : "")));
+    results.appendChild(li);
+  });
+});`
+
+func (r htmlRenderer) index(title string, docs []moduleDoc) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n", html.EscapeString(title))
+	sb.WriteString("<input id=\"search\" type=\"search\" placeholder=\"Search\">\n<ul id=\"results\"></ul>\n")
+	sb.WriteString("<h2>Modules</h2>\n<ul>\n")
+	for _, mod := range docs {
+		fmt.Fprintf(&sb, "<li><a href=\"%s\">%s</a>", r.pageName(mod.Name), html.EscapeString(mod.Name))
+		if sum := summary(mod.Comment); sum != "" {
+			fmt.Fprintf(&sb, ": %s", html.EscapeString(sum))
+		}
+		sb.WriteString("</li>\n")
+	}
+	sb.WriteString("</ul>\n")
+	fmt.Fprintf(&sb, "<script src=\"%s\"></script>\n<script>\n%s\n</script>", SEARCH_SCRIPT, searchJs)
+	return htmlPage(title, sb.String())
+}
+
+// Browsers can't fetch json from local files
+// so the index is also written as a script.
+func (htmlRenderer) searchScript(index string) Page {
+	return Page{Path: SEARCH_SCRIPT, Content: "var searchIndex = " + strings.TrimRight(index, "\n") + ";\n"}
+}
@@ -0,0 +1,132 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// JSON-RPC error codes used by the server.
+const (
+	PARSE_ERROR      = -32700
+	INVALID_REQUEST  = -32600
+	METHOD_NOT_FOUND = -32601
+	INVALID_PARAMS   = -32602
+	INTERNAL_ERROR   = -32603
+	SERVER_NOT_INIT  = -32002
+)
+
+// A JSON-RPC message: a request, a notification or a response.
+// Requests and responses have an id, notifications don't.
+type Message struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ResponseError  `json:"error,omitempty"`
+}
+
+func (m Message) IsNotification() bool {
+	return m.Method != "" && len(m.Id) == 0
+}
+
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// Reads and writes messages framed by a Content-Length header.
+type Conn struct {
+	reader *bufio.Reader
+	writer io.Writer
+	mu     sync.Mutex
+}
+
+func NewConn(in io.Reader, out io.Writer) *Conn {
+	return &Conn{reader: bufio.NewReader(in), writer: out}
+}
+
+// Reads the next message.
+// Returns io.EOF if the stream ended between messages.
+func (c *Conn) Read() (Message, error) {
+	var msg Message
+	headers, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(headers) == 0 {
+			return msg, io.EOF
+		}
+		return msg, fmt.Errorf("invalid message header: %w", err)
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return msg, fmt.Errorf("invalid Content-Length header %q", headers.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return msg, fmt.Errorf("could not read message body: %w", err)
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return msg, &ResponseError{Code: PARSE_ERROR, Message: err.Error()}
+	}
+	return msg, nil
+}
+
+// Writes a message. Safe to call from multiple goroutines.
+func (c *Conn) Write(msg Message) error {
+	msg.Jsonrpc = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.writer.Write(body)
+	return err
+}
+
+// Sends a notification with the given params.
+func (c *Conn) Notify(method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.Write(Message{Method: method, Params: raw})
+}
+
+// Sends a request with the given id and params.
+func (c *Conn) Request(id int, method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.Write(Message{Id: json.RawMessage(strconv.Itoa(id)), Method: method, Params: raw})
+}
+
+// Answers the request with the given id.
+// A nil result is sent as null.
+func (c *Conn) Reply(id json.RawMessage, result any) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return c.Write(Message{Id: id, Result: raw})
+}
+
+func (c *Conn) ReplyError(id json.RawMessage, err *ResponseError) error {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return c.Write(Message{Id: id, Error: err})
+}
@@ -0,0 +1,268 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/stackoverflow/novah-go/compiler/ast"
+	tc "github.com/stackoverflow/novah-go/compiler/typechecker"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+func (s *Server) hover(params TextDocumentPositionParams) *Hover {
+	mod, found := s.moduleAt(params.TextDocument.Uri)
+	if !found {
+		return nil
+	}
+	lines := s.lines(mod.Ast.SourceName)
+	pos := lines.fromPosition(params.Position)
+	show := func(t ast.Type) string {
+		return ast.ShowTypeInner(t, false, mod.TypeVarsMap)
+	}
+
+	for _, decl := range mod.Ast.Decls {
+		if !contains(decl.GetSpan(), pos) {
+			continue
+		}
+		switch d := decl.(type) {
+		case ast.TypeDecl:
+			if contains(d.Name.Span, pos) {
+				return lines.hover(fmt.Sprintf("type %s", d.Name.Val), d.Name.Span)
+			}
+			for _, ctor := range d.DataCtors {
+				if ref, has := mod.Env.Decls[ctor.Name.Val]; has && contains(ctor.Name.Span, pos) {
+					return lines.hover(fmt.Sprintf("%s : %s", ctor.Name.Val, show(ref.Type)), ctor.Name.Span)
+				}
+			}
+		case ast.ValDecl:
+			if contains(d.Name.Span, pos) {
+				if ref, has := mod.Env.Decls[d.Name.Val]; has {
+					return lines.hover(fmt.Sprintf("%s : %s", d.Name.Val, show(ref.Type)), d.Name.Span)
+				}
+				return nil
+			}
+			if binder, typ, has := binderAt(d.Exp, pos); has {
+				return lines.hover(fmt.Sprintf("%s : %s", binder.Name, show(typ)), binder.Span)
+			}
+			exp, has := exprAt(d.Exp, pos, func(e ast.Expr) bool { return typeOf(e) != nil })
+			if !has {
+				return nil
+			}
+			typ := show(typeOf(exp))
+			switch e := exp.(type) {
+			case ast.Var:
+				return lines.hover(fmt.Sprintf("%s : %s", e.Name, typ), e.Span)
+			case ast.Ctor:
+				return lines.hover(fmt.Sprintf("%s : %s", e.Name, typ), e.Span)
+			case ast.ImplicitVar:
+				return lines.hover(fmt.Sprintf("{{%s}} : %s", e.Name, typ), e.Span)
+			default:
+				return lines.hover(typ, exp.GetSpan())
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) definition(params TextDocumentPositionParams) *Location {
+	mod, found := s.moduleAt(params.TextDocument.Uri)
+	if !found {
+		return nil
+	}
+	pos := s.lines(mod.Ast.SourceName).fromPosition(params.Position)
+
+	for _, imp := range mod.Ast.Imports {
+		if !contains(imp.Span, pos) {
+			continue
+		}
+		other, has := s.modules[imp.Module.Val]
+		if !has {
+			return nil
+		}
+		for _, def := range imp.Defs {
+			if contains(def.Name.Span, pos) {
+				return s.topLevel(other, def.Name.Val)
+			}
+			for _, ctor := range def.Ctors {
+				if contains(ctor.Span, pos) {
+					return s.topLevel(other, ctor.Val)
+				}
+			}
+		}
+		return s.location(other, other.Ast.Name.Span)
+	}
+
+	for _, decl := range mod.Ast.Decls {
+		d, isVal := decl.(ast.ValDecl)
+		if !isVal || !contains(d.Span, pos) {
+			continue
+		}
+		exp, has := exprAt(d.Exp, pos, func(e ast.Expr) bool {
+			switch e.(type) {
+			case ast.Var, ast.Ctor:
+				return true
+			}
+			return false
+		})
+		if !has {
+			return nil
+		}
+		switch e := exp.(type) {
+		case ast.Var:
+			if e.ModuleName == "" {
+				if binder, has := localBinder(d.Exp, e); has {
+					return s.location(mod, binder.Span)
+				}
+			}
+			return s.resolve(mod, e.ModuleName, e.Name)
+		case ast.Ctor:
+			return s.resolve(mod, e.ModuleName, e.Name)
+		}
+	}
+	return nil
+}
+
+// Finds the declaration of a name in the given module
+// or in the current one if no module is given.
+func (s *Server) resolve(current tc.FullModuleEnv, module, name string) *Location {
+	if module == "" || module == current.Ast.Name.Val {
+		return s.topLevel(current, name)
+	}
+	if other, has := s.modules[module]; has {
+		return s.topLevel(other, name)
+	}
+	return nil
+}
+
+func (s *Server) topLevel(mod tc.FullModuleEnv, name string) *Location {
+	for _, decl := range mod.Ast.Decls {
+		switch d := decl.(type) {
+		case ast.ValDecl:
+			if d.Name.Val == name {
+				return s.location(mod, d.Name.Span)
+			}
+		case ast.TypeDecl:
+			if d.Name.Val == name {
+				return s.location(mod, d.Name.Span)
+			}
+			for _, ctor := range d.DataCtors {
+				if ctor.Name.Val == name {
+					return s.location(mod, ctor.Name.Span)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) location(mod tc.FullModuleEnv, span data.Span) *Location {
+	return &Location{Uri: s.uri(mod.Ast.SourceName), Range: s.lines(mod.Ast.SourceName).toRange(span)}
+}
+
+// Returns the module compiled from the document with this uri.
+func (s *Server) moduleAt(uri string) (tc.FullModuleEnv, bool) {
+	path := uriToPath(uri)
+	for _, mod := range s.modules {
+		if filepath.Clean(mod.Ast.SourceName) == path {
+			return mod, true
+		}
+	}
+	return tc.FullModuleEnv{}, false
+}
+
+// Returns the innermost expression containing pos that satisfies pred.
+func exprAt(exp ast.Expr, pos data.Pos, pred func(ast.Expr) bool) (ast.Expr, bool) {
+	var found ast.Expr
+	ast.EverywhereExprUnit(exp, func(e ast.Expr) {
+		span := e.GetSpan()
+		if !contains(span, pos) || !pred(e) {
+			return
+		}
+		if found == nil || within(span, found.GetSpan()) {
+			found = e
+		}
+	})
+	return found, found != nil
+}
+
+// Returns the lambda or let binder under pos and its type.
+// Binders don't keep their types after inference
+// so they come from the lambda or the let definition.
+func binderAt(exp ast.Expr, pos data.Pos) (ast.Binder, ast.Type, bool) {
+	var found ast.Binder
+	var typ ast.Type
+	ast.EverywhereExprUnit(exp, func(e ast.Expr) {
+		switch ex := e.(type) {
+		case ast.Lambda:
+			if contains(ex.Binder.Span, pos) {
+				if arr, isArrow := typeOf(ex).(ast.TArrow); isArrow && len(arr.Args) == 1 {
+					found, typ = ex.Binder, arr.Args[0]
+				}
+			}
+		case ast.Let:
+			if contains(ex.Def.Binder.Span, pos) {
+				found, typ = ex.Def.Binder, typeOf(ex.Def.Expr)
+			}
+		}
+	})
+	return found, typ, typ != nil
+}
+
+// Returns the innermost lambda or let binder in scope of this variable.
+func localBinder(exp ast.Expr, v ast.Var) (ast.Binder, bool) {
+	var found ast.Binder
+	var scope data.Span
+	has := false
+	check := func(binder ast.Binder, span data.Span) {
+		if binder.Name == v.Name && contains(span, v.Span.Start) && (!has || within(span, scope)) {
+			found = binder
+			scope = span
+			has = true
+		}
+	}
+	ast.EverywhereExprUnit(exp, func(e ast.Expr) {
+		switch ex := e.(type) {
+		case ast.Lambda:
+			check(ex.Binder, ex.Span)
+		case ast.Let:
+			// a let binder is only in scope of its own definition if it's recursive
+			if ex.Def.Recursive || !contains(ex.Def.Expr.GetSpan(), v.Span.Start) {
+				check(ex.Def.Binder, ex.Span)
+			}
+		}
+	})
+	return found, has
+}
+
+// Returns the inferred type of this expression or nil if it has none.
+func typeOf(e ast.Expr) (typ ast.Type) {
+	defer func() {
+		if recover() != nil {
+			typ = nil
+		}
+	}()
+	return e.GetType()
+}
+
+func (l lines) hover(text string, span data.Span) *Hover {
+	r := l.toRange(span)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: "```novah\n" + text + "\n```"}, Range: &r}
+}
+
+// Returns true if pos is inside the span.
+// The end of a span is exclusive.
+func contains(span data.Span, pos data.Pos) bool {
+	if pos.Line < span.Start.Line || (pos.Line == span.Start.Line && pos.Col < span.Start.Col) {
+		return false
+	}
+	return pos.Line < span.End.Line || (pos.Line == span.End.Line && pos.Col < span.End.Col)
+}
+
+// Returns true if inner is inside outer.
+func within(inner, outer data.Span) bool {
+	return !before(inner.Start, outer.Start) && !before(outer.End, inner.End)
+}
+
+func before(a, b data.Pos) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+}
@@ -0,0 +1,397 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/stackoverflow/novah-go/compiler"
+	tc "github.com/stackoverflow/novah-go/compiler/typechecker"
+	"github.com/stackoverflow/novah-go/data"
+)
+
+// A language server for novah.
+// Every change to an open document recompiles the workspace
+// (the open documents plus every novah file under the root)
+// and publishes the resulting diagnostics.
+type Server struct {
+	conn *Conn
+	root string
+	// text of the open documents by path
+	docs map[string]string
+	// uri of the open documents by path, as sent by the client
+	uris map[string]string
+	// uris that got diagnostics on the last publish
+	published map[string]bool
+	// modules from the last compilation
+	modules     map[string]tc.FullModuleEnv
+	initialized bool
+	shutdown    bool
+	// logs errors that can't be sent to the client
+	Log io.Writer
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		conn:      NewConn(in, out),
+		docs:      make(map[string]string),
+		uris:      make(map[string]string),
+		published: make(map[string]bool),
+		modules:   make(map[string]tc.FullModuleEnv),
+		Log:       io.Discard,
+	}
+}
+
+// Serves requests until the client sends exit or closes the input.
+// Returns an error if the client exits without a shutdown request.
+func (s *Server) Run() error {
+	for {
+		msg, err := s.conn.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if rerr, ok := err.(*ResponseError); ok {
+				s.conn.ReplyError(nil, rerr)
+				continue
+			}
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit received before shutdown")
+			}
+			return nil
+		}
+		if msg.Method == "" {
+			// a response to a request we never make
+			continue
+		}
+		result, rerr := s.handle(msg)
+		if msg.IsNotification() {
+			if rerr != nil {
+				fmt.Fprintf(s.Log, "%s: %s\n", msg.Method, rerr.Message)
+			}
+			continue
+		}
+		if rerr != nil {
+			err = s.conn.ReplyError(msg.Id, rerr)
+		} else {
+			err = s.conn.Reply(msg.Id, result)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg Message) (result any, rerr *ResponseError) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			rerr = &ResponseError{Code: INTERNAL_ERROR, Message: fmt.Sprint(r)}
+		}
+	}()
+
+	if !s.initialized && msg.Method != "initialize" {
+		return nil, &ResponseError{Code: SERVER_NOT_INIT, Message: "server not initialized"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		var params InitializeParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.initialize(params), nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		s.setDocument(params.TextDocument.Uri, params.TextDocument.Text)
+		s.compile()
+		return nil, nil
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// we only support full syncs so the last change has the whole text
+		s.setDocument(params.TextDocument.Uri, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		s.compile()
+		return nil, nil
+	case "textDocument/didSave":
+		var params DidSaveTextDocumentParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if params.Text != nil {
+			s.setDocument(params.TextDocument.Uri, *params.Text)
+		}
+		s.compile()
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		path := uriToPath(params.TextDocument.Uri)
+		delete(s.docs, path)
+		s.compile()
+		delete(s.uris, path)
+		return nil, nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	default:
+		if strings.HasPrefix(msg.Method, "$/") {
+			// optional notifications can be ignored
+			return nil, nil
+		}
+		return nil, &ResponseError{Code: METHOD_NOT_FOUND, Message: "method not found: " + msg.Method}
+	}
+}
+
+func (s *Server) initialize(params InitializeParams) InitializeResult {
+	if params.RootUri != "" {
+		s.root = uriToPath(params.RootUri)
+	} else {
+		s.root = params.RootPath
+	}
+	s.initialized = true
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:   TextDocumentSyncOptions{OpenClose: true, Change: SYNC_FULL, Save: true},
+			HoverProvider:      true,
+			DefinitionProvider: true,
+		},
+		ServerInfo: ServerInfo{Name: "novah"},
+	}
+}
+
+func (s *Server) setDocument(uri, text string) {
+	path := uriToPath(uri)
+	s.docs[path] = text
+	s.uris[path] = uri
+}
+
+// Compiles the workspace and publishes the diagnostics.
+func (s *Server) compile() {
+	problems := s.runCompiler()
+
+	byUri := make(map[string][]Diagnostic)
+	for path := range s.docs {
+		byUri[s.uri(path)] = []Diagnostic{}
+	}
+	for _, p := range problems {
+		if p.Filename == "" {
+			continue
+		}
+		uri := s.uri(p.Filename)
+		byUri[uri] = append(byUri[uri], s.lines(p.Filename).toDiagnostic(p))
+	}
+	// clear the diagnostics of files that have none now
+	for uri := range s.published {
+		if _, has := byUri[uri]; !has {
+			byUri[uri] = []Diagnostic{}
+		}
+	}
+
+	uris := data.MapKeys(byUri)
+	sort.Strings(uris)
+	s.published = make(map[string]bool)
+	for _, uri := range uris {
+		diags := byUri[uri]
+		if len(diags) > 0 {
+			s.published[uri] = true
+		}
+		s.conn.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{Uri: uri, Diagnostics: diags})
+	}
+}
+
+func (s *Server) runCompiler() (problems []data.CompilerProblem) {
+	sources := s.sources()
+	c := compiler.NewCompilerFromSources(sources, compiler.Options{})
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(s.Log, "compiler crashed: %v\n", r)
+			problems = c.Errors()
+		}
+		s.modules = c.Modules()
+	}()
+	c.Compile()
+	return c.Errors()
+}
+
+// The open documents plus every novah file in the workspace.
+func (s *Server) sources() []compiler.Source {
+	paths := data.NewSet[string]()
+	for path := range s.docs {
+		paths.Add(path)
+	}
+	if s.root != "" {
+		filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() && path != s.root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && strings.HasSuffix(path, ".novah") {
+				paths.Add(path)
+			}
+			return nil
+		})
+	}
+
+	sorted := data.MapKeys(paths.Inner())
+	sort.Strings(sorted)
+	sources := make([]compiler.Source, 0, len(sorted))
+	for _, path := range sorted {
+		if text, has := s.docs[path]; has {
+			sources = append(sources, compiler.Source{Path: path, Str: text})
+		} else if _, err := os.Stat(path); err == nil {
+			sources = append(sources, compiler.Source{Path: path})
+		}
+	}
+	return sources
+}
+
+func (s *Server) uri(path string) string {
+	if uri, has := s.uris[path]; has {
+		return uri
+	}
+	return pathToUri(path)
+}
+
+func (l lines) toDiagnostic(p data.CompilerProblem) Diagnostic {
+	severity := SEVERITY_ERROR
+	if p.Severity == data.WARN {
+		severity = SEVERITY_WARNING
+	}
+	msg := p.Msg
+	if p.TypingContext != "" {
+		msg += "\n\n" + p.TypingContext
+	}
+	return Diagnostic{Range: l.toRange(p.Span), Severity: severity, Code: p.Code, Source: "novah", Message: msg}
+}
+
+// The lines of a source, to convert between novah and LSP positions.
+// Novah positions are one-based and count a tab as more than one column,
+// LSP positions are zero-based and count the UTF-16 code units of the line.
+type lines []string
+
+// The lines of the open document or of the file in this path
+func (s *Server) lines(path string) lines {
+	text, has := s.docs[filepath.Clean(path)]
+	if !has {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		text = string(bytes)
+	}
+	return strings.Split(text, "\n")
+}
+
+func (l lines) line(n int) []rune {
+	if n < 1 || n > len(l) {
+		return nil
+	}
+	return []rune(strings.TrimSuffix(l[n-1], "\r"))
+}
+
+func (l lines) toRange(span data.Span) Range {
+	return Range{Start: l.toPosition(span.Start), End: l.toPosition(span.End)}
+}
+
+func (l lines) toPosition(pos data.Pos) Position {
+	line := l.line(pos.Line)
+	char := 0
+	for i := 0; i < data.RuneIndex(line, max0(pos.Col-1)+1); i++ {
+		if i < len(line) {
+			char += utf16Len(line[i])
+		} else {
+			char++
+		}
+	}
+	return Position{Line: max0(pos.Line - 1), Character: char}
+}
+
+// Runes outside the basic multilingual plane take two UTF-16 code units
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+func (l lines) fromPosition(pos Position) data.Pos {
+	line := l.line(pos.Line + 1)
+	i, char := 0, 0
+	for ; char < pos.Character; i++ {
+		if i < len(line) {
+			char += utf16Len(line[i])
+		} else {
+			char++
+		}
+	}
+	return data.Pos{Line: pos.Line + 1, Col: data.RuneColumn(line, i)}
+}
+
+func max0(x int) int {
+	if x < 0 {
+		return 0
+	}
+	return x
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.Clean(filepath.FromSlash(u.Path))
+}
+
+func pathToUri(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
+
+func unmarshal(raw json.RawMessage, v any) *ResponseError {
+	if len(raw) == 0 {
+		return &ResponseError{Code: INVALID_PARAMS, Message: "missing params"}
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &ResponseError{Code: INVALID_PARAMS, Message: err.Error()}
+	}
+	return nil
+}
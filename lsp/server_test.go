@@ -0,0 +1,298 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A scripted client talking to an in-process server.
+type client struct {
+	t        *testing.T
+	conn     *Conn
+	id       int
+	messages chan Message
+	done     chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	server := NewServer(serverIn, serverOut)
+	done := make(chan error, 1)
+	go func() {
+		err := server.Run()
+		serverOut.Close()
+		done <- err
+	}()
+	t.Cleanup(func() { clientOut.Close() })
+
+	// keep reading like a real client would so the server never blocks
+	conn := NewConn(clientIn, clientOut)
+	messages := make(chan Message, 100)
+	go func() {
+		defer close(messages)
+		for {
+			msg, err := conn.Read()
+			if err != nil {
+				return
+			}
+			messages <- msg
+		}
+	}()
+	return &client{t: t, conn: conn, messages: messages, done: done}
+}
+
+func (c *client) notify(method string, params any) {
+	if err := c.conn.Notify(method, params); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// Sends a request and waits for its response.
+// Notifications received while waiting are dropped.
+func (c *client) request(method string, params any, result any) *ResponseError {
+	c.id++
+	if err := c.conn.Request(c.id, method, params); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		msg := c.read()
+		if msg.Method != "" {
+			continue
+		}
+		var id int
+		json.Unmarshal(msg.Id, &id)
+		if id != c.id {
+			c.t.Fatalf("expected response to %d, got %s", c.id, string(msg.Id))
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return nil
+	}
+}
+
+// Reads publishDiagnostics notifications until one for the given uri arrives.
+func (c *client) diagnostics(uri string) []Diagnostic {
+	for {
+		msg := c.read()
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var params PublishDiagnosticsParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			c.t.Fatal(err)
+		}
+		if params.Uri == uri {
+			return params.Diagnostics
+		}
+	}
+}
+
+func (c *client) read() Message {
+	msg, ok := <-c.messages
+	if !ok {
+		c.t.Fatal("server closed the connection")
+	}
+	return msg
+}
+
+func (c *client) initialize(root string) {
+	var res InitializeResult
+	if err := c.request("initialize", InitializeParams{RootUri: pathToUri(root)}, &res); err != nil {
+		c.t.Fatal(err)
+	}
+	assert.True(c.t, res.Capabilities.HoverProvider)
+	assert.True(c.t, res.Capabilities.DefinitionProvider)
+	assert.Equal(c.t, SYNC_FULL, res.Capabilities.TextDocumentSync.Change)
+	c.notify("initialized", struct{}{})
+}
+
+func (c *client) open(uri, text string) {
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{Uri: uri, LanguageId: "novah", Version: 1, Text: text},
+	})
+}
+
+func (c *client) change(uri, text string) {
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{Uri: uri},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: text}},
+	})
+}
+
+func (c *client) hover(uri string, line, char int) *Hover {
+	var res *Hover
+	params := TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{Uri: uri}, Position: Position{line, char}}
+	if err := c.request("textDocument/hover", params, &res); err != nil {
+		c.t.Fatal(err)
+	}
+	return res
+}
+
+func (c *client) definition(uri string, line, char int) *Location {
+	var res *Location
+	params := TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{Uri: uri}, Position: Position{line, char}}
+	if err := c.request("textDocument/definition", params, &res); err != nil {
+		c.t.Fatal(err)
+	}
+	return res
+}
+
+func (c *client) exit() {
+	if err := c.request("shutdown", nil, nil); err != nil {
+		c.t.Fatal(err)
+	}
+	c.notify("exit", nil)
+	assert.Nil(c.t, <-c.done)
+}
+
+const libCode = `module lib
+
+pub
+ident : Int -> Int
+ident x = x
+`
+
+const appCode = `module app
+
+import lib (ident)
+
+quad x = ident (ident x)
+
+eight = quad 2
+`
+
+func workspace(t *testing.T) string {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "lib.novah"), []byte(libCode), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestDiagnostics(t *testing.T) {
+	root := workspace(t)
+	uri := pathToUri(filepath.Join(root, "app.novah"))
+	c := newClient(t)
+	c.initialize(root)
+
+	c.open(uri, appCode)
+	assert.Empty(t, c.diagnostics(uri))
+
+	c.change(uri, strings.Replace(appCode, "quad 2", `quad "2"`, 1))
+	diags := c.diagnostics(uri)
+	assert.Equal(t, 1, len(diags))
+	assert.Equal(t, SEVERITY_ERROR, diags[0].Severity)
+	assert.Equal(t, "N0076", diags[0].Code)
+	assert.Equal(t, Range{Position{6, 13}, Position{6, 16}}, diags[0].Range)
+
+	// fixing the error clears the diagnostics
+	c.change(uri, appCode)
+	assert.Empty(t, c.diagnostics(uri))
+	c.exit()
+}
+
+func TestHover(t *testing.T) {
+	root := workspace(t)
+	uri := pathToUri(filepath.Join(root, "app.novah"))
+	c := newClient(t)
+	c.initialize(root)
+	c.open(uri, appCode)
+	c.diagnostics(uri)
+
+	// top level declaration
+	h := c.hover(uri, 4, 1)
+	assert.Equal(t, "```novah\nquad : Int -> Int\n```", h.Contents.Value)
+	assert.Equal(t, &Range{Position{4, 0}, Position{4, 4}}, h.Range)
+
+	// imported variable
+	h = c.hover(uri, 4, 10)
+	assert.Equal(t, "```novah\nident : Int -> Int\n```", h.Contents.Value)
+
+	// lambda binder
+	h = c.hover(uri, 4, 5)
+	assert.Equal(t, "```novah\nx : Int\n```", h.Contents.Value)
+
+	// literal
+	h = c.hover(uri, 6, 13)
+	assert.Equal(t, "```novah\nInt\n```", h.Contents.Value)
+
+	// nothing
+	assert.Nil(t, c.hover(uri, 1, 0))
+	c.exit()
+}
+
+func TestDefinition(t *testing.T) {
+	root := workspace(t)
+	libUri := pathToUri(filepath.Join(root, "lib.novah"))
+	uri := pathToUri(filepath.Join(root, "app.novah"))
+	c := newClient(t)
+	c.initialize(root)
+	c.open(uri, appCode)
+	c.diagnostics(uri)
+
+	// declaration in another module
+	loc := c.definition(uri, 4, 10)
+	assert.Equal(t, &Location{libUri, Range{Position{4, 0}, Position{4, 5}}}, loc)
+
+	// declaration in the same module
+	loc = c.definition(uri, 6, 8)
+	assert.Equal(t, &Location{uri, Range{Position{4, 0}, Position{4, 4}}}, loc)
+
+	// local variable
+	loc = c.definition(uri, 4, 22)
+	assert.Equal(t, &Location{uri, Range{Position{4, 5}, Position{4, 6}}}, loc)
+
+	// imported declaration
+	loc = c.definition(uri, 2, 12)
+	assert.Equal(t, &Location{libUri, Range{Position{4, 0}, Position{4, 5}}}, loc)
+
+	// imported module
+	loc = c.definition(uri, 2, 8)
+	assert.Equal(t, &Location{libUri, Range{Position{0, 7}, Position{0, 10}}}, loc)
+	c.exit()
+}
+
+func TestNotInitialized(t *testing.T) {
+	c := newClient(t)
+	err := c.request("textDocument/hover", TextDocumentPositionParams{}, nil)
+	assert.Equal(t, SERVER_NOT_INIT, err.Code)
+	c.initialize(t.TempDir())
+	err = c.request("unknown/method", struct{}{}, nil)
+	assert.Equal(t, METHOD_NOT_FOUND, err.Code)
+	c.exit()
+}
+
+func TestTabsAndWideRunes(t *testing.T) {
+	root := workspace(t)
+	uri := pathToUri(filepath.Join(root, "app.novah"))
+	c := newClient(t)
+	c.initialize(root)
+	// the lexer counts the tab as 2 columns, LSP as 1 character
+	// and the emoji as 2 UTF-16 code units
+	code := "module app\n\nfoo =\n\tlet n = [\"😀\", 1]\n\tn\n"
+	c.open(uri, code)
+	diags := c.diagnostics(uri)
+	assert.Equal(t, 1, len(diags))
+	assert.Equal(t, Range{Position{3, 16}, Position{3, 17}}, diags[0].Range)
+
+	h := c.hover(uri, 3, 12)
+	assert.Equal(t, "```novah\nString\n```", h.Contents.Value)
+	assert.Equal(t, &Range{Position{3, 10}, Position{3, 14}}, h.Range)
+
+	loc := c.definition(uri, 4, 1)
+	assert.Equal(t, &Location{uri, Range{Position{3, 5}, Position{3, 6}}}, loc)
+	c.exit()
+}
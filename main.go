@@ -2,11 +2,27 @@ package main
 
 import (
 	"github.com/spf13/cobra"
+	build "github.com/stackoverflow/novah-go/cmd/build_cmd"
+	check "github.com/stackoverflow/novah-go/cmd/check_cmd"
 	compile "github.com/stackoverflow/novah-go/cmd/compile_cmd"
+	doccmd "github.com/stackoverflow/novah-go/cmd/doc_cmd"
+	explain "github.com/stackoverflow/novah-go/cmd/explain_cmd"
+	fmtcmd "github.com/stackoverflow/novah-go/cmd/fmt_cmd"
+	lsp "github.com/stackoverflow/novah-go/cmd/lsp_cmd"
+	run "github.com/stackoverflow/novah-go/cmd/run_cmd"
+	trace "github.com/stackoverflow/novah-go/cmd/trace_cmd"
 )
 
 func main() {
 	rootCmd := &cobra.Command{Use: "novah", Version: "0.1"}
 	rootCmd.AddCommand(compile.CompileCmd)
+	rootCmd.AddCommand(run.RunCmd)
+	rootCmd.AddCommand(build.BuildCmd)
+	rootCmd.AddCommand(check.CheckCmd)
+	rootCmd.AddCommand(explain.ExplainCmd)
+	rootCmd.AddCommand(fmtcmd.FmtCmd)
+	rootCmd.AddCommand(lsp.LspCmd)
+	rootCmd.AddCommand(doccmd.DocCmd)
+	rootCmd.AddCommand(trace.TraceCmd)
 	rootCmd.Execute()
 }
@@ -0,0 +1,9 @@
+package novah
+
+import "os"
+
+// The command line arguments passed to the main function,
+// without the name of the program
+func Args() Vector[string] {
+	return NewVector(os.Args[1:]...)
+}
@@ -0,0 +1,10 @@
+package novah
+
+import "embed"
+
+// The sources of the runtime.
+// They are copied to the output of programs
+// that need the runtime to be built.
+//
+//go:embed args.go hash.go record.go set.go unit.go vector.go
+var Sources embed.FS
@@ -0,0 +1,216 @@
+package novah
+
+import (
+	"math"
+	"reflect"
+)
+
+// Runtime types that are compared structurally
+type hashable interface {
+	hash() uint64
+	equals(other any) bool
+}
+
+// Hashes any value. Equal values always have the same hash.
+// Hashes don't depend on the process, so iteration order is stable between runs.
+func hashOf(v any) uint64 {
+	switch x := v.(type) {
+	case hashable:
+		return x.hash()
+	case string:
+		return hashString(x)
+	case int:
+		return mix(uint64(x))
+	case int8:
+		return mix(uint64(x))
+	case int16:
+		return mix(uint64(x))
+	case int32:
+		return mix(uint64(x))
+	case int64:
+		return mix(uint64(x))
+	case uint:
+		return mix(uint64(x))
+	case uint8:
+		return mix(uint64(x))
+	case uint16:
+		return mix(uint64(x))
+	case uint32:
+		return mix(uint64(x))
+	case uint64:
+		return mix(x)
+	case uintptr:
+		return mix(uint64(x))
+	case float32:
+		return hashFloat(float64(x))
+	case float64:
+		return hashFloat(x)
+	case bool:
+		if x {
+			return mix(1)
+		}
+		return mix(0)
+	default:
+		return hashValue(reflect.ValueOf(v))
+	}
+}
+
+// Hashes the structure of values of other types, like the structs
+// of constructors and closed records. Runtime types inside them
+// are hashed by their elements, not by their internal nodes.
+func hashValue(v reflect.Value) uint64 {
+	if !v.IsValid() {
+		return mix(0)
+	}
+	if v.CanInterface() {
+		if h, isHashable := v.Interface().(hashable); isHashable {
+			return h.hash()
+		}
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		h := hashString(v.Type().String())
+		for i := 0; i < v.NumField(); i++ {
+			h = h*31 + hashValue(v.Field(i))
+		}
+		return h
+	case reflect.Array, reflect.Slice:
+		h := uint64(v.Len())
+		for i := 0; i < v.Len(); i++ {
+			h = h*31 + hashValue(v.Index(i))
+		}
+		return h
+	case reflect.Map:
+		// the order of the entries doesn't matter
+		var h uint64
+		iter := v.MapRange()
+		for iter.Next() {
+			h += mix(hashValue(iter.Key())*31 + hashValue(iter.Value()))
+		}
+		return h
+	case reflect.Interface:
+		return hashValue(v.Elem())
+	case reflect.Ptr, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return mix(uint64(v.Pointer()))
+	case reflect.Bool:
+		return hashOf(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return mix(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return mix(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return hashFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return hashFloat(real(c))*31 + hashFloat(imag(c))
+	default:
+		return hashString(v.String())
+	}
+}
+
+// Compares two values using structural equality.
+// Values of the same type are equal if their fields or elements are equal,
+// runtime types are compared by their elements.
+// Pointers and channels are equal if they are the same and functions are never equal.
+func equal(a, b any) bool {
+	if ha, isHashable := a.(hashable); isHashable {
+		return ha.equals(b)
+	}
+	switch a.(type) {
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64, bool:
+		return a == b
+	}
+	return equalValues(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func equalValues(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	if a.CanInterface() && b.CanInterface() {
+		if ha, isHashable := a.Interface().(hashable); isHashable {
+			return ha.equals(b.Interface())
+		}
+	}
+	switch a.Kind() {
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !equalValues(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array, reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalValues(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		iter := a.MapRange()
+		for iter.Next() {
+			bval := b.MapIndex(iter.Key())
+			if !bval.IsValid() || !equalValues(iter.Value(), bval) {
+				return false
+			}
+		}
+		return true
+	case reflect.Interface:
+		return equalValues(a.Elem(), b.Elem())
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	case reflect.Func:
+		return false
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	default:
+		return a.String() == b.String()
+	}
+}
+
+// 64 bit FNV-1a
+func hashString(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return mix(h)
+}
+
+func hashFloat(f float64) uint64 {
+	// 0.0 == -0.0
+	if f == 0 {
+		return mix(0)
+	}
+	return mix(math.Float64bits(f))
+}
+
+// The splitmix64 finalizer, spreads the bits so
+// all levels of the hash trie are used
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,149 @@
+// Package novah is the runtime library used by the go code
+// generated by the compiler.
+package novah
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A labeled value of a record
+type Field struct {
+	Label string
+	Val   any
+}
+
+// Record is the representation of records whose labels are
+// not all known at compile time (records with an open row).
+// Records with a closed row are compiled to go structs instead.
+//
+// Records are immutable, every operation returns a new record.
+// The fields are kept sorted by label and labels can be duplicated
+// (scoped labels): only the first field of a label is visible,
+// the others are uncovered when it's restricted.
+type Record struct {
+	fields []Field
+}
+
+var EmptyRecord = Record{}
+
+// Creates a record from the fields.
+// If a label is repeated the first field is the visible one.
+func NewRecord(fields ...Field) Record {
+	fs := make([]Field, len(fields))
+	copy(fs, fields)
+	sort.SliceStable(fs, func(i, j int) bool { return fs[i].Label < fs[j].Label })
+	return Record{fields: fs}
+}
+
+// The number of fields in this record, including the shadowed ones
+func (r Record) Len() int {
+	return len(r.fields)
+}
+
+// Returns the value of the label.
+// Panics if the label is not in the record.
+func (r Record) Select(label string) any {
+	i, found := r.find(label)
+	if !found {
+		panic(fmt.Sprintf("label %s not found in record", label))
+	}
+	return r.fields[i].Val
+}
+
+// Adds the fields to this record shadowing any existing labels
+func (r Record) Extend(fields ...Field) Record {
+	return r.Merge(NewRecord(fields...))
+}
+
+// Removes the visible field of the label
+func (r Record) Restrict(label string) Record {
+	i, found := r.find(label)
+	if !found {
+		panic(fmt.Sprintf("label %s not found in record", label))
+	}
+	fs := make([]Field, 0, len(r.fields)-1)
+	fs = append(fs, r.fields[:i]...)
+	fs = append(fs, r.fields[i+1:]...)
+	return Record{fields: fs}
+}
+
+// Changes the value of the visible field of the label
+func (r Record) Update(label string, val any) Record {
+	i, found := r.find(label)
+	if !found {
+		panic(fmt.Sprintf("label %s not found in record", label))
+	}
+	fs := make([]Field, len(r.fields))
+	copy(fs, r.fields)
+	fs[i] = Field{Label: label, Val: val}
+	return Record{fields: fs}
+}
+
+// Merges the two records together.
+// The fields of other shadow the fields of this record.
+func (r Record) Merge(other Record) Record {
+	if len(other.fields) == 0 {
+		return r
+	}
+	if len(r.fields) == 0 {
+		return other
+	}
+	fs := make([]Field, 0, len(r.fields)+len(other.fields))
+	i, j := 0, 0
+	for i < len(r.fields) && j < len(other.fields) {
+		if other.fields[j].Label <= r.fields[i].Label {
+			fs = append(fs, other.fields[j])
+			j++
+		} else {
+			fs = append(fs, r.fields[i])
+			i++
+		}
+	}
+	fs = append(fs, r.fields[i:]...)
+	fs = append(fs, other.fields[j:]...)
+	return Record{fields: fs}
+}
+
+func (r Record) hash() uint64 {
+	h := uint64(len(r.fields))
+	for _, f := range r.fields {
+		h = h*31 + hashString(f.Label)
+		h = h*31 + hashOf(f.Val)
+	}
+	return h
+}
+
+// Records are equal if they have the same fields, including the shadowed ones
+func (r Record) equals(other any) bool {
+	o, isRec := other.(Record)
+	if !isRec || len(r.fields) != len(o.fields) {
+		return false
+	}
+	for i, f := range r.fields {
+		if f.Label != o.fields[i].Label || !equal(f.Val, o.fields[i].Val) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Record) String() string {
+	var sb strings.Builder
+	sb.WriteRune('{')
+	for i, f := range r.fields {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(fmt.Sprintf("%s: %v", f.Label, f.Val))
+	}
+	sb.WriteRune('}')
+	return sb.String()
+}
+
+// Returns the index of the first field with this label
+func (r Record) find(label string) (int, bool) {
+	i := sort.Search(len(r.fields), func(i int) bool { return r.fields[i].Label >= label })
+	return i, i < len(r.fields) && r.fields[i].Label == label
+}
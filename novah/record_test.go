@@ -0,0 +1,67 @@
+package novah
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordSelect(t *testing.T) {
+	rec := NewRecord(Field{"name", "novah"}, Field{"age", 3})
+
+	assert.Equal(t, "novah", rec.Select("name"))
+	assert.Equal(t, 3, rec.Select("age"))
+	assert.Equal(t, 2, rec.Len())
+}
+
+func TestRecordScopedLabels(t *testing.T) {
+	rec := NewRecord(Field{"x", 1}).Extend(Field{"x", "one"}, Field{"y", 2})
+
+	assert.Equal(t, "one", rec.Select("x"))
+	assert.Equal(t, 3, rec.Len())
+
+	rest := rec.Restrict("x")
+	assert.Equal(t, 1, rest.Select("x"))
+	assert.Equal(t, "{x: one, x: 1, y: 2}", rec.String())
+	assert.Equal(t, "{x: 1, y: 2}", rest.String())
+}
+
+func TestRecordUpdate(t *testing.T) {
+	rec := NewRecord(Field{"x", 1}, Field{"y", 2})
+	up := rec.Update("y", 3)
+
+	assert.Equal(t, 3, up.Select("y"))
+	// the original record is not changed
+	assert.Equal(t, 2, rec.Select("y"))
+}
+
+func TestRecordMerge(t *testing.T) {
+	r1 := NewRecord(Field{"a", 1}, Field{"c", 3})
+	r2 := NewRecord(Field{"b", 2}, Field{"c", "three"})
+
+	merged := r1.Merge(r2)
+	assert.Equal(t, "{a: 1, b: 2, c: three, c: 3}", merged.String())
+	assert.Equal(t, "three", merged.Select("c"))
+	assert.Equal(t, r1, r1.Merge(EmptyRecord))
+}
+
+func TestRecordMissingLabel(t *testing.T) {
+	defer func() {
+		assert.Equal(t, "label z not found in record", recover())
+	}()
+	EmptyRecord.Select("z")
+}
+
+func BenchmarkRecordSelect(b *testing.B) {
+	rec := NewRecord(Field{"a", 1}, Field{"b", 2}, Field{"c", 3}, Field{"d", 4}, Field{"e", 5})
+	for i := 0; i < b.N; i++ {
+		rec.Select("d")
+	}
+}
+
+func BenchmarkRecordExtend(b *testing.B) {
+	rec := NewRecord(Field{"a", 1}, Field{"b", 2}, Field{"c", 3}, Field{"d", 4}, Field{"e", 5})
+	for i := 0; i < b.N; i++ {
+		rec.Extend(Field{"c", 10})
+	}
+}
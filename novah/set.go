@@ -0,0 +1,263 @@
+package novah
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Set is the representation of novah sets.
+// It's an immutable hash array mapped trie (HAMT):
+// every node has up to 32 children indexed by 5 bits of the hash
+// and adding or removing an element copies only the path to it.
+//
+// The zero value is an empty set.
+type Set[T any] struct {
+	root *hnode[T]
+	size int
+}
+
+type hnode[T any] struct {
+	// which of the 32 slots have a child
+	bitmap uint32
+	kids   []hkid[T]
+}
+
+// A child is either a sub node or a leaf with the values of a hash.
+// Leaves only have more than one value if the hashes collide.
+type hkid[T any] struct {
+	sub  *hnode[T]
+	hash uint64
+	vals []T
+}
+
+// Creates a set with the elements
+func NewSet[T any](elems ...T) Set[T] {
+	var s Set[T]
+	for _, e := range elems {
+		s = s.Add(e)
+	}
+	return s
+}
+
+// The number of elements in the set
+func (s Set[T]) Len() int {
+	return s.size
+}
+
+// Returns true if elem is in the set
+func (s Set[T]) Contains(elem T) bool {
+	hash := hashOf(elem)
+	node := s.root
+	for shift := uint(0); node != nil; shift += trieBits {
+		bit, pos := slot(node, hash, shift)
+		if node.bitmap&bit == 0 {
+			return false
+		}
+		kid := node.kids[pos]
+		if kid.sub == nil {
+			return kid.hash == hash && indexOf(kid.vals, elem) != -1
+		}
+		node = kid.sub
+	}
+	return false
+}
+
+// Returns a new set with elem added
+func (s Set[T]) Add(elem T) Set[T] {
+	root := s.root
+	if root == nil {
+		root = &hnode[T]{}
+	}
+	newRoot, added := add(root, 0, hashOf(elem), elem)
+	if !added {
+		return s
+	}
+	return Set[T]{root: newRoot, size: s.size + 1}
+}
+
+// Returns a new set without elem
+func (s Set[T]) Remove(elem T) Set[T] {
+	if s.root == nil {
+		return s
+	}
+	newRoot, removed := remove(s.root, 0, hashOf(elem), elem)
+	if !removed {
+		return s
+	}
+	return Set[T]{root: newRoot, size: s.size - 1}
+}
+
+// Calls f with every element of the set.
+// The order is the same for sets with the same elements.
+func (s Set[T]) Each(f func(T)) {
+	if s.root != nil {
+		each(s.root, f)
+	}
+}
+
+// Returns the elements of the set as a new slice
+func (s Set[T]) ToSlice() []T {
+	res := make([]T, 0, s.size)
+	s.Each(func(e T) {
+		res = append(res, e)
+	})
+	return res
+}
+
+func (s Set[T]) String() string {
+	var sb strings.Builder
+	sb.WriteString("#{")
+	first := true
+	s.Each(func(e T) {
+		if !first {
+			sb.WriteString(", ")
+		}
+		first = false
+		sb.WriteString(fmt.Sprint(e))
+	})
+	sb.WriteRune('}')
+	return sb.String()
+}
+
+func (s Set[T]) hash() uint64 {
+	// the order of the elements doesn't matter
+	var h uint64
+	s.Each(func(e T) {
+		h += hashOf(e)
+	})
+	return h
+}
+
+func (s Set[T]) equals(other any) bool {
+	o, isSet := other.(Set[T])
+	if !isSet || s.size != o.size {
+		return false
+	}
+	eq := true
+	s.Each(func(e T) {
+		eq = eq && o.Contains(e)
+	})
+	return eq
+}
+
+// Returns the bit of the hash in this level and the index of the child
+func slot[T any](node *hnode[T], hash uint64, shift uint) (uint32, int) {
+	bit := uint32(1) << ((hash >> shift) & trieMask)
+	return bit, bits.OnesCount32(node.bitmap & (bit - 1))
+}
+
+func add[T any](node *hnode[T], shift uint, hash uint64, elem T) (*hnode[T], bool) {
+	bit, pos := slot(node, hash, shift)
+	if node.bitmap&bit == 0 {
+		res := &hnode[T]{bitmap: node.bitmap | bit, kids: make([]hkid[T], 0, len(node.kids)+1)}
+		res.kids = append(res.kids, node.kids[:pos]...)
+		res.kids = append(res.kids, hkid[T]{hash: hash, vals: []T{elem}})
+		res.kids = append(res.kids, node.kids[pos:]...)
+		return res, true
+	}
+
+	kid := node.kids[pos]
+	var newKid hkid[T]
+	switch {
+	case kid.sub != nil:
+		sub, added := add(kid.sub, shift+trieBits, hash, elem)
+		if !added {
+			return node, false
+		}
+		newKid = hkid[T]{sub: sub}
+	case kid.hash == hash:
+		if indexOf(kid.vals, elem) != -1 {
+			return node, false
+		}
+		vals := make([]T, len(kid.vals), len(kid.vals)+1)
+		copy(vals, kid.vals)
+		newKid = hkid[T]{hash: hash, vals: append(vals, elem)}
+	default:
+		// different hashes in the same slot: push both down a level
+		sub := newLeafNode(shift+trieBits, kid)
+		sub, _ = add(sub, shift+trieBits, hash, elem)
+		newKid = hkid[T]{sub: sub}
+	}
+	return node.withKid(pos, newKid), true
+}
+
+// Creates a node with a single leaf
+func newLeafNode[T any](shift uint, leaf hkid[T]) *hnode[T] {
+	bit := uint32(1) << ((leaf.hash >> shift) & trieMask)
+	return &hnode[T]{bitmap: bit, kids: []hkid[T]{leaf}}
+}
+
+func remove[T any](node *hnode[T], shift uint, hash uint64, elem T) (*hnode[T], bool) {
+	bit, pos := slot(node, hash, shift)
+	if node.bitmap&bit == 0 {
+		return node, false
+	}
+
+	kid := node.kids[pos]
+	if kid.sub != nil {
+		sub, removed := remove(kid.sub, shift+trieBits, hash, elem)
+		if !removed {
+			return node, false
+		}
+		switch {
+		case len(sub.kids) == 0:
+			return node.withoutKid(pos, bit), true
+		case len(sub.kids) == 1 && sub.kids[0].sub == nil:
+			// a single leaf doesn't need its own node
+			return node.withKid(pos, sub.kids[0]), true
+		default:
+			return node.withKid(pos, hkid[T]{sub: sub}), true
+		}
+	}
+
+	if kid.hash != hash {
+		return node, false
+	}
+	i := indexOf(kid.vals, elem)
+	if i == -1 {
+		return node, false
+	}
+	if len(kid.vals) == 1 {
+		return node.withoutKid(pos, bit), true
+	}
+	vals := make([]T, 0, len(kid.vals)-1)
+	vals = append(vals, kid.vals[:i]...)
+	vals = append(vals, kid.vals[i+1:]...)
+	return node.withKid(pos, hkid[T]{hash: hash, vals: vals}), true
+}
+
+func each[T any](node *hnode[T], f func(T)) {
+	for _, kid := range node.kids {
+		if kid.sub != nil {
+			each(kid.sub, f)
+			continue
+		}
+		for _, v := range kid.vals {
+			f(v)
+		}
+	}
+}
+
+func (n *hnode[T]) withKid(pos int, kid hkid[T]) *hnode[T] {
+	kids := make([]hkid[T], len(n.kids))
+	copy(kids, n.kids)
+	kids[pos] = kid
+	return &hnode[T]{bitmap: n.bitmap, kids: kids}
+}
+
+func (n *hnode[T]) withoutKid(pos int, bit uint32) *hnode[T] {
+	kids := make([]hkid[T], 0, len(n.kids)-1)
+	kids = append(kids, n.kids[:pos]...)
+	kids = append(kids, n.kids[pos+1:]...)
+	return &hnode[T]{bitmap: n.bitmap &^ bit, kids: kids}
+}
+
+func indexOf[T any](vals []T, elem T) int {
+	for i, v := range vals {
+		if equal(v, elem) {
+			return i
+		}
+	}
+	return -1
+}
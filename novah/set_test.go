@@ -0,0 +1,185 @@
+package novah
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAddAndContains(t *testing.T) {
+	n := 10000
+	var s Set[int]
+	for i := 0; i < n; i++ {
+		s = s.Add(i)
+	}
+	s = s.Add(0)
+
+	assert.Equal(t, n, s.Len())
+	for i := 0; i < n; i++ {
+		if !s.Contains(i) {
+			t.Fatalf("set should contain %d", i)
+		}
+	}
+	assert.False(t, s.Contains(n))
+}
+
+func TestSetIsPersistent(t *testing.T) {
+	s1 := NewSet("a", "b")
+	s2 := s1.Add("c")
+	s3 := s2.Remove("a")
+
+	assert.Equal(t, 2, s1.Len())
+	assert.False(t, s1.Contains("c"))
+	assert.True(t, s2.Contains("a"))
+	assert.False(t, s3.Contains("a"))
+	assert.Equal(t, 2, s3.Len())
+}
+
+func TestSetRemove(t *testing.T) {
+	n := 5000
+	s := NewSet[int]()
+	for i := 0; i < n; i++ {
+		s = s.Add(i)
+	}
+	for i := 0; i < n; i += 2 {
+		s = s.Remove(i)
+	}
+	s = s.Remove(n + 1)
+
+	assert.Equal(t, n/2, s.Len())
+	for i := 0; i < n; i++ {
+		assert.Equal(t, i%2 == 1, s.Contains(i))
+	}
+	for i := 1; i < n; i += 2 {
+		s = s.Remove(i)
+	}
+	assert.Equal(t, 0, s.Len())
+	assert.Equal(t, "#{}", s.String())
+}
+
+func TestSetCollisions(t *testing.T) {
+	// 0.0 and -0.0 are equal so they must be the same element
+	s := NewSet(0.0, negZero(), 1.5)
+	assert.Equal(t, 2, s.Len())
+
+	// values with the same hash are kept in the same leaf
+	k1 := collider{1}
+	k2 := collider{2}
+	cs := NewSet(k1, k2)
+	assert.Equal(t, 2, cs.Len())
+	assert.True(t, cs.Contains(k2))
+	assert.Equal(t, 1, cs.Remove(k1).Len())
+	assert.True(t, cs.Remove(k1).Contains(k2))
+}
+
+func TestSetOfVectors(t *testing.T) {
+	s := NewSet(NewVector(1, 2), NewVector(0, 1, 2).Drop(1), NewVector(3))
+
+	assert.Equal(t, 2, s.Len())
+	assert.True(t, s.Contains(NewVector(3)))
+	assert.True(t, equal(NewSet(1, 2, 3), NewSet(3, 2, 1)))
+	assert.Equal(t, NewSet(1, 2, 3).String(), NewSet(3, 2, 1).String())
+}
+
+// The structs generated for constructors and closed records
+type some[A any] struct {
+	V0 A
+}
+
+type none[A any] struct{}
+
+type point struct {
+	F_x    int
+	F_tags Vector[string]
+}
+
+func TestSetOfConstructors(t *testing.T) {
+	// vectors with the same elements and a different structure
+	pushed := NewVector[int]()
+	for i := 0; i < 100; i++ {
+		pushed = pushed.Push(i)
+	}
+	built := NewVector(make([]int, 100)...)
+	for i := 0; i < 100; i++ {
+		built = built.Set(i, i)
+	}
+
+	s := NewSet[any](some[Vector[int]]{pushed}, some[Vector[int]]{built}, none[Vector[int]]{}, none[Vector[int]]{})
+	assert.Equal(t, 2, s.Len())
+	assert.Equal(t, hashOf(some[Vector[int]]{pushed}), hashOf(some[Vector[int]]{built}))
+	assert.True(t, s.Contains(some[Vector[int]]{built}))
+	assert.False(t, s.Contains(some[Vector[int]]{NewVector(1)}))
+
+	sets := NewSet(some[Set[int]]{NewSet(1, 2)}, some[Set[int]]{NewSet(2, 1)})
+	assert.Equal(t, 1, sets.Len())
+
+	// constructors of different types with the same fields are different
+	assert.False(t, equal(some[int]{1}, some[int64]{1}))
+}
+
+func TestSetOfRecords(t *testing.T) {
+	closed := NewSet(point{1, NewVector("a")}, point{1, NewVector("a")}, point{1, NewVector("b")})
+	assert.Equal(t, 2, closed.Len())
+	assert.True(t, closed.Contains(point{1, NewVector("b")}))
+
+	open := NewSet(
+		NewRecord(Field{"tags", NewVector(1, 2)}, Field{"x", 1}),
+		NewRecord(Field{"x", 1}).Extend(Field{"tags", NewVector(1, 2)}),
+		NewRecord(Field{"x", 1}),
+	)
+	assert.Equal(t, 2, open.Len())
+	assert.True(t, open.Contains(NewRecord(Field{"x", 1})))
+	assert.False(t, equal(NewRecord(Field{"x", 1}), NewRecord(Field{"x", 1}, Field{"x", 2})))
+}
+
+type collider struct {
+	v int
+}
+
+func (c collider) hash() uint64 {
+	return 42
+}
+
+func (c collider) equals(other any) bool {
+	return other == c
+}
+
+func negZero() float64 {
+	z := 0.0
+	return -z
+}
+
+// Adding to an immutable map needs a full copy
+func BenchmarkMapCopyAdd(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		m := map[int]struct{}{}
+		for j := 0; j < 1000; j++ {
+			c := make(map[int]struct{}, len(m)+1)
+			for k := range m {
+				c[k] = struct{}{}
+			}
+			c[j] = struct{}{}
+			m = c
+		}
+	}
+}
+
+func BenchmarkSetAdd(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var s Set[int]
+		for j := 0; j < 1000; j++ {
+			s = s.Add(j)
+		}
+	}
+}
+
+func BenchmarkSetContains(b *testing.B) {
+	s := NewSet[int]()
+	for j := 0; j < 10000; j++ {
+		s = s.Add(j)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Contains(i % 10000)
+	}
+}
@@ -0,0 +1,5 @@
+package novah
+
+// Unit is the type of expressions without a meaningful value,
+// its only value is nil.
+type Unit = any
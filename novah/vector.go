@@ -0,0 +1,255 @@
+package novah
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	trieBits  = 5
+	trieWidth = 1 << trieBits
+	trieMask  = trieWidth - 1
+)
+
+// Vector is the representation of novah lists.
+// It's an immutable persistent vector: a 32-way trie
+// where every operation copies only the path to the changed leaf,
+// sharing the rest of the structure with the old vector.
+// The last (up to 32) elements are kept in a separate tail,
+// so pushing to the end is amortized constant time.
+//
+// The zero value is an empty vector.
+type Vector[T any] struct {
+	root  *vnode[T]
+	tail  []T
+	shift uint
+	// the number of elements in the trie and tail
+	cnt int
+	// the index of the first element of this vector,
+	// elements before start were dropped
+	start int
+}
+
+type vnode[T any] struct {
+	children []*vnode[T]
+	// only leaves have values
+	vals []T
+}
+
+// Creates a vector with the elements in order
+func NewVector[T any](elems ...T) Vector[T] {
+	var v Vector[T]
+	for _, e := range elems {
+		v = v.Push(e)
+	}
+	return v
+}
+
+// The number of elements in the vector
+func (v Vector[T]) Len() int {
+	return v.cnt - v.start
+}
+
+// Returns the element at index i.
+// Panics if the index is out of bounds.
+func (v Vector[T]) Get(i int) T {
+	v.checkIndex(i)
+	i += v.start
+	if i >= v.tailOffset() {
+		return v.tail[i-v.tailOffset()]
+	}
+	node := v.root
+	for level := v.shift; level > 0; level -= trieBits {
+		node = node.children[(i>>level)&trieMask]
+	}
+	return node.vals[i&trieMask]
+}
+
+// Returns a new vector with elem added to the end
+func (v Vector[T]) Push(elem T) Vector[T] {
+	if v.shift == 0 {
+		v.shift = trieBits
+	}
+	if v.cnt-v.tailOffset() < trieWidth {
+		tail := make([]T, len(v.tail), len(v.tail)+1)
+		copy(tail, v.tail)
+		v.tail = append(tail, elem)
+		v.cnt++
+		return v
+	}
+
+	// the tail is full, push it into the trie
+	tailNode := &vnode[T]{vals: v.tail}
+	if (v.cnt >> trieBits) > (1 << v.shift) {
+		// the root overflowed
+		v.root = &vnode[T]{children: []*vnode[T]{v.root, newPath(v.shift, tailNode)}}
+		v.shift += trieBits
+	} else {
+		v.root = v.pushTail(v.shift, v.root, tailNode)
+	}
+	v.tail = []T{elem}
+	v.cnt++
+	return v
+}
+
+// Returns a new vector with the element at index i replaced by elem.
+// Panics if the index is out of bounds.
+func (v Vector[T]) Set(i int, elem T) Vector[T] {
+	v.checkIndex(i)
+	i += v.start
+	if i >= v.tailOffset() {
+		tail := make([]T, len(v.tail))
+		copy(tail, v.tail)
+		tail[i-v.tailOffset()] = elem
+		v.tail = tail
+		return v
+	}
+	v.root = doSet(v.shift, v.root, i, elem)
+	return v
+}
+
+// Returns a vector without the first n elements.
+// This is a constant time operation that shares all the elements.
+func (v Vector[T]) Drop(n int) Vector[T] {
+	if n < 0 || n > v.Len() {
+		panic(fmt.Sprintf("cannot drop %d elements from a vector of length %d", n, v.Len()))
+	}
+	if n == v.Len() {
+		return Vector[T]{}
+	}
+	v.start += n
+	return v
+}
+
+// Returns the elements of the vector as a new slice
+func (v Vector[T]) ToSlice() []T {
+	res := make([]T, 0, v.Len())
+	v.Each(func(e T) {
+		res = append(res, e)
+	})
+	return res
+}
+
+// Calls f with every element in order
+func (v Vector[T]) Each(f func(T)) {
+	tailOff := v.tailOffset()
+	for i := v.start; i < tailOff; i += trieWidth - i&trieMask {
+		leaf := v.leafFor(i)
+		for _, e := range leaf.vals[i&trieMask:] {
+			f(e)
+		}
+	}
+	from := v.start - tailOff
+	if from < 0 {
+		from = 0
+	}
+	for _, e := range v.tail[from:] {
+		f(e)
+	}
+}
+
+func (v Vector[T]) String() string {
+	var sb strings.Builder
+	sb.WriteRune('[')
+	first := true
+	v.Each(func(e T) {
+		if !first {
+			sb.WriteString(", ")
+		}
+		first = false
+		sb.WriteString(fmt.Sprint(e))
+	})
+	sb.WriteRune(']')
+	return sb.String()
+}
+
+func (v Vector[T]) hash() uint64 {
+	h := uint64(v.Len())
+	v.Each(func(e T) {
+		h = h*31 + hashOf(e)
+	})
+	return h
+}
+
+func (v Vector[T]) equals(other any) bool {
+	o, isVec := other.(Vector[T])
+	if !isVec || v.Len() != o.Len() {
+		return false
+	}
+	for i := 0; i < v.Len(); i++ {
+		if !equal(v.Get(i), o.Get(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (v Vector[T]) checkIndex(i int) {
+	if i < 0 || i >= v.Len() {
+		panic(fmt.Sprintf("index %d out of bounds for vector of length %d", i, v.Len()))
+	}
+}
+
+// The index of the first element in the tail
+func (v Vector[T]) tailOffset() int {
+	if v.cnt < trieWidth {
+		return 0
+	}
+	return ((v.cnt - 1) >> trieBits) << trieBits
+}
+
+// The leaf of the trie that holds the index i
+func (v Vector[T]) leafFor(i int) *vnode[T] {
+	node := v.root
+	for level := v.shift; level > 0; level -= trieBits {
+		node = node.children[(i>>level)&trieMask]
+	}
+	return node
+}
+
+func (v Vector[T]) pushTail(level uint, parent *vnode[T], tailNode *vnode[T]) *vnode[T] {
+	subidx := ((v.cnt - 1) >> level) & trieMask
+	var children []*vnode[T]
+	if parent != nil {
+		children = parent.children
+	}
+	res := &vnode[T]{children: make([]*vnode[T], len(children), subidx+1)}
+	copy(res.children, children)
+
+	var insert *vnode[T]
+	if level == trieBits {
+		insert = tailNode
+	} else if subidx < len(children) {
+		insert = v.pushTail(level-trieBits, children[subidx], tailNode)
+	} else {
+		insert = newPath(level-trieBits, tailNode)
+	}
+	if subidx < len(res.children) {
+		res.children[subidx] = insert
+	} else {
+		res.children = append(res.children, insert)
+	}
+	return res
+}
+
+func newPath[T any](level uint, node *vnode[T]) *vnode[T] {
+	if level == 0 {
+		return node
+	}
+	return &vnode[T]{children: []*vnode[T]{newPath(level-trieBits, node)}}
+}
+
+func doSet[T any](level uint, node *vnode[T], i int, elem T) *vnode[T] {
+	res := &vnode[T]{}
+	if level == 0 {
+		res.vals = make([]T, len(node.vals))
+		copy(res.vals, node.vals)
+		res.vals[i&trieMask] = elem
+		return res
+	}
+	res.children = make([]*vnode[T], len(node.children))
+	copy(res.children, node.children)
+	subidx := (i >> level) & trieMask
+	res.children[subidx] = doSet(level-trieBits, node.children[subidx], i, elem)
+	return res
+}
@@ -0,0 +1,140 @@
+package novah
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVectorPushAndGet(t *testing.T) {
+	// enough elements for a trie with 3 levels
+	n := 40000
+	var v Vector[int]
+	for i := 0; i < n; i++ {
+		v = v.Push(i)
+	}
+
+	assert.Equal(t, n, v.Len())
+	for i := 0; i < n; i++ {
+		if v.Get(i) != i {
+			t.Fatalf("expected %d at index %d, got %d", i, i, v.Get(i))
+		}
+	}
+	assert.Equal(t, n, len(v.ToSlice()))
+}
+
+func TestVectorIsPersistent(t *testing.T) {
+	v1 := NewVector(1, 2, 3)
+	v2 := v1.Push(4)
+	v3 := v1.Push(5)
+	v4 := v2.Set(0, 10)
+
+	assert.Equal(t, "[1, 2, 3]", v1.String())
+	assert.Equal(t, "[1, 2, 3, 4]", v2.String())
+	assert.Equal(t, "[1, 2, 3, 5]", v3.String())
+	assert.Equal(t, "[10, 2, 3, 4]", v4.String())
+}
+
+func TestVectorSetInTrie(t *testing.T) {
+	v := NewVector(make([]int, 2000)...)
+	v2 := v.Set(1000, 1).Set(0, 2).Set(1999, 3)
+
+	assert.Equal(t, 1, v2.Get(1000))
+	assert.Equal(t, 2, v2.Get(0))
+	assert.Equal(t, 3, v2.Get(1999))
+	assert.Equal(t, 0, v.Get(1000))
+}
+
+func TestVectorDrop(t *testing.T) {
+	elems := make([]int, 100)
+	for i := range elems {
+		elems[i] = i
+	}
+	v := NewVector(elems...)
+
+	rest := v.Drop(40)
+	assert.Equal(t, 60, rest.Len())
+	assert.Equal(t, 40, rest.Get(0))
+	assert.Equal(t, elems[40:], rest.ToSlice())
+
+	// pushing to a dropped vector doesn't change the original
+	pushed := rest.Push(100)
+	assert.Equal(t, 100, pushed.Get(60))
+	assert.Equal(t, 100, v.Len())
+	assert.Equal(t, 0, v.Drop(100).Len())
+
+	var empty Vector[int]
+	assert.Equal(t, "[]", empty.String())
+	assert.Equal(t, 0, len(empty.ToSlice()))
+}
+
+func TestVectorEquality(t *testing.T) {
+	v1 := NewVector(0, 1, 2, 3).Drop(1)
+	v2 := NewVector(1, 2, 3)
+
+	assert.True(t, equal(v1, v2))
+	assert.Equal(t, hashOf(v1), hashOf(v2))
+	assert.False(t, equal(v2, NewVector(1, 2)))
+}
+
+func TestVectorOutOfBounds(t *testing.T) {
+	defer func() {
+		assert.Equal(t, "index 3 out of bounds for vector of length 3", recover())
+	}()
+	NewVector(1, 2, 3).Get(3)
+}
+
+// Pushing to an immutable slice needs a full copy
+func BenchmarkSliceCopyPush(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var s []int
+		for j := 0; j < 1000; j++ {
+			c := make([]int, len(s), len(s)+1)
+			copy(c, s)
+			s = append(c, j)
+		}
+	}
+}
+
+func BenchmarkVectorPush(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var v Vector[int]
+		for j := 0; j < 1000; j++ {
+			v = v.Push(j)
+		}
+	}
+}
+
+func BenchmarkSliceCopySet(b *testing.B) {
+	s := make([]int, 10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := make([]int, len(s))
+		copy(c, s)
+		c[i%len(c)] = i
+	}
+}
+
+func BenchmarkVectorSet(b *testing.B) {
+	v := NewVector(make([]int, 10000)...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.Set(i%v.Len(), i)
+	}
+}
+
+func BenchmarkSliceGet(b *testing.B) {
+	s := make([]int, 10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = s[i%len(s)]
+	}
+}
+
+func BenchmarkVectorGet(b *testing.B) {
+	v := NewVector(make([]int, 10000)...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.Get(i % v.Len())
+	}
+}